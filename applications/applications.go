@@ -0,0 +1,124 @@
+package applications
+
+import (
+	"APIServerExercise/core"
+	"sort"
+)
+
+// Version alias resolved to the latest release of an application
+const LatestVersion = "latest"
+
+// The metadata sharing an application slug, each metadata being a release of the application
+type Application struct {
+	Slug string `yaml:"slug"`
+	// Title of the latest release
+	Title  string `yaml:"title"`
+	Latest string `yaml:"latest"`
+	// Versions of the releases, newest first
+	Versions []string `yaml:"versions"`
+}
+
+// Returns the releases of the application, newest first by semantic version
+// Releases with the same version keep the default ordering.
+func Releases(database *core.Database, slug string) []*core.Metadata {
+	var releases []*core.Metadata
+	for _, id := range database.Ordering {
+		if metadata, ok := database.Metadatas[id]; ok && metadata.Application == slug {
+			releases = append(releases, metadata)
+		}
+	}
+	sortReleases(releases)
+	return releases
+}
+
+func sortReleases(releases []*core.Metadata) {
+	sort.SliceStable(releases, func(i, j int) bool {
+		return core.CompareVersions(releases[i].Version, releases[j].Version) > 0
+	})
+}
+
+// Returns the newest release that is not a pre-release, or the newest release when all of them are
+// The releases must be sorted newest first, returns nil when there are none.
+func Latest(releases []*core.Metadata) *core.Metadata {
+	for _, release := range releases {
+		if version, ok := core.ParseSemver(release.Version); ok && !version.IsPrerelease() {
+			return release
+		}
+	}
+	if len(releases) == 0 {
+		return nil
+	}
+	return releases[0]
+}
+
+// Returns the release of the application with the version, or its latest release for the latest alias
+// Versions are matched by semantic version precedence, IE: v1.0.0 matches 1.0.0+build.1.
+func Resolve(database *core.Database, slug string, version string) (*core.Metadata, bool) {
+	releases := Releases(database, slug)
+	if version == LatestVersion {
+		latest := Latest(releases)
+		return latest, latest != nil
+	}
+	for _, release := range releases {
+		if core.CompareVersions(release.Version, version) == 0 {
+			return release, true
+		}
+	}
+	return nil, false
+}
+
+// Returns the application, false when it has no release
+func Get(database *core.Database, slug string) (*Application, bool) {
+	releases := Releases(database, slug)
+	if len(releases) == 0 {
+		return nil, false
+	}
+	return newApplication(slug, releases), true
+}
+
+// Returns every application, sorted by slug
+func List(database *core.Database) []*Application {
+	bySlug := map[string][]*core.Metadata{}
+	for _, id := range database.Ordering {
+		if metadata, ok := database.Metadatas[id]; ok {
+			bySlug[metadata.Application] = append(bySlug[metadata.Application], metadata)
+		}
+	}
+	list := make([]*Application, 0, len(bySlug))
+	for slug, releases := range bySlug {
+		sortReleases(releases)
+		list = append(list, newApplication(slug, releases))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Slug < list[j].Slug })
+	return list
+}
+
+// The releases must be sorted newest first
+func newApplication(slug string, releases []*core.Metadata) *Application {
+	latest := Latest(releases)
+	application := &Application{Slug: slug, Title: latest.Title, Latest: latest.Version, Versions: make([]string, len(releases))}
+	for i, release := range releases {
+		application.Versions[i] = release.Version
+	}
+	return application
+}
+
+// Keeps the latest of the results of each application, at the position of the first result of the application
+// IE: search results for 1.0.0 and 2.0.0 of an application collapse to 2.0.0.
+func Collapse(results []*core.Metadata) []*core.Metadata {
+	bySlug := map[string][]*core.Metadata{}
+	var slugs []string
+	for _, metadata := range results {
+		if _, ok := bySlug[metadata.Application]; !ok {
+			slugs = append(slugs, metadata.Application)
+		}
+		bySlug[metadata.Application] = append(bySlug[metadata.Application], metadata)
+	}
+	collapsed := make([]*core.Metadata, 0, len(slugs))
+	for _, slug := range slugs {
+		releases := bySlug[slug]
+		sortReleases(releases)
+		collapsed = append(collapsed, Latest(releases))
+	}
+	return collapsed
+}
@@ -0,0 +1,93 @@
+package applications
+
+import (
+	"APIServerExercise/core"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// Adds a release per version, IE: "valid-app-1", "0.0.1"
+func newTestDatabase(releases ...[2]string) *core.Database {
+	database := &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}}
+	for _, release := range releases {
+		metadata := &core.Metadata{Id: uuid.New(), Title: release[0] + " " + release[1], Application: release[0], Version: release[1]}
+		database.Metadatas[metadata.Id] = metadata
+		database.Ordering = append(database.Ordering, metadata.Id)
+	}
+	return database
+}
+
+func versions(releases []*core.Metadata) []string {
+	var versions []string
+	for _, release := range releases {
+		versions = append(versions, release.Version)
+	}
+	return versions
+}
+
+func TestReleases(t *testing.T) {
+	database := newTestDatabase(
+		[2]string{"app", "0.0.2"}, [2]string{"other", "3.0.0"}, [2]string{"app", "0.10.0"},
+		[2]string{"app", "1.0.0-rc.1"}, [2]string{"app", "nightly"}, [2]string{"app", "0.0.10"})
+
+	assert.Equal(t, []string{"1.0.0-rc.1", "0.10.0", "0.0.10", "0.0.2", "nightly"}, versions(Releases(database, "app")))
+	assert.Empty(t, Releases(database, "unknown"))
+}
+
+func TestLatest(t *testing.T) {
+	database := newTestDatabase([2]string{"app", "1.0.0"}, [2]string{"app", "1.1.0-beta"}, [2]string{"prerelease", "0.1.0-alpha"})
+
+	assert.Equal(t, "1.0.0", Latest(Releases(database, "app")).Version)
+	assert.Equal(t, "0.1.0-alpha", Latest(Releases(database, "prerelease")).Version)
+	assert.Nil(t, Latest(nil))
+}
+
+func TestResolve(t *testing.T) {
+	database := newTestDatabase([2]string{"app", "1.0.0"}, [2]string{"app", "2.0.0"}, [2]string{"app", "nightly"})
+
+	release, ok := Resolve(database, "app", LatestVersion)
+	assert.True(t, ok)
+	assert.Equal(t, "2.0.0", release.Version)
+
+	release, ok = Resolve(database, "app", "v1.0.0")
+	assert.True(t, ok)
+	assert.Equal(t, "1.0.0", release.Version)
+
+	release, ok = Resolve(database, "app", "nightly")
+	assert.True(t, ok)
+	assert.Equal(t, "nightly", release.Version)
+
+	_, ok = Resolve(database, "app", "3.0.0")
+	assert.False(t, ok)
+	_, ok = Resolve(database, "unknown", LatestVersion)
+	assert.False(t, ok)
+}
+
+func TestList(t *testing.T) {
+	database := newTestDatabase([2]string{"other", "1.0.0"}, [2]string{"app", "0.0.1"}, [2]string{"app", "0.0.2"})
+
+	assert.Equal(t, []*Application{
+		{Slug: "app", Title: "app 0.0.2", Latest: "0.0.2", Versions: []string{"0.0.2", "0.0.1"}},
+		{Slug: "other", Title: "other 1.0.0", Latest: "1.0.0", Versions: []string{"1.0.0"}},
+	}, List(database))
+
+	application, ok := Get(database, "app")
+	assert.True(t, ok)
+	assert.Equal(t, "0.0.2", application.Latest)
+	_, ok = Get(database, "unknown")
+	assert.False(t, ok)
+}
+
+func TestCollapse(t *testing.T) {
+	database := newTestDatabase([2]string{"app", "0.0.1"}, [2]string{"other", "1.0.0"}, [2]string{"app", "0.0.2"})
+	var results []*core.Metadata
+	for _, id := range database.Ordering {
+		results = append(results, database.Metadatas[id])
+	}
+
+	collapsed := Collapse(results)
+	assert.Equal(t, []string{"0.0.2", "1.0.0"}, versions(collapsed))
+	assert.Equal(t, []string{"0.0.1", "1.0.0", "0.0.2"}, versions(results), "the results are not reordered")
+	assert.Empty(t, Collapse(nil))
+}
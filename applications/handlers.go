@@ -0,0 +1,65 @@
+package applications
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/handlerutil"
+	"APIServerExercise/lifecycle"
+	"APIServerExercise/storage"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// HTTP handlers of the applications, read only as applications are made of the metadata
+type Handler struct {
+	Store *storage.MetadataStore
+}
+
+// GET /applications
+func (h *Handler) HandleApplicationsGet(w http.ResponseWriter, req *http.Request) {
+	var applications []*Application
+	h.Store.Read(func(database *core.Database) { applications = List(database) })
+	handlerutil.WriteYaml(w, req, http.StatusOK, applications)
+}
+
+// GET /applications/{slug}
+func (h *Handler) HandleApplicationGet(w http.ResponseWriter, req *http.Request) {
+	slug := mux.Vars(req)["slug"]
+	var application *Application
+	ok := false
+	h.Store.Read(func(database *core.Database) { application, ok = Get(database, slug) })
+	if !ok {
+		handlerutil.WriteError(w, req, http.StatusNotFound, fmt.Sprintf("Unknown application %q", slug))
+		return
+	}
+	handlerutil.WriteYaml(w, req, http.StatusOK, application)
+}
+
+// GET /applications/{slug}/versions
+// Returns the metadata of every release, newest first.
+func (h *Handler) HandleVersionsGet(w http.ResponseWriter, req *http.Request) {
+	slug := mux.Vars(req)["slug"]
+	var releases []*core.Metadata
+	h.Store.Read(func(database *core.Database) { releases = Releases(database, slug) })
+	if len(releases) == 0 {
+		handlerutil.WriteError(w, req, http.StatusNotFound, fmt.Sprintf("Unknown application %q", slug))
+		return
+	}
+	handlerutil.WriteYaml(w, req, http.StatusOK, releases)
+}
+
+// GET /applications/{slug}/versions/{version}
+// The latest version resolves to the newest release that is not a pre-release. A deprecated release is sent with the
+// same headers as GET /metadata/{id}.
+func (h *Handler) HandleVersionGet(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	var release *core.Metadata
+	ok := false
+	h.Store.Read(func(database *core.Database) { release, ok = Resolve(database, vars["slug"], vars["version"]) })
+	if !ok {
+		handlerutil.WriteError(w, req, http.StatusNotFound, fmt.Sprintf("Unknown version %q of application %q", vars["version"], vars["slug"]))
+		return
+	}
+	lifecycle.SetHeaders(w.Header(), release)
+	handlerutil.WriteYaml(w, req, http.StatusOK, release)
+}
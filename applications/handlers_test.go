@@ -0,0 +1,62 @@
+package applications
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/storage"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(handle http.HandlerFunc, vars map[string]string) *httptest.ResponseRecorder {
+	request := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/applications", nil), vars)
+	responseRecorder := httptest.NewRecorder()
+	handle(responseRecorder, request)
+	return responseRecorder
+}
+
+func TestHandler(t *testing.T) {
+	handler := &Handler{Store: &storage.MetadataStore{Database: newTestDatabase([2]string{"app", "0.0.1"}, [2]string{"app", "0.1.0"})}}
+
+	responseRecorder := serve(handler.HandleApplicationsGet, nil)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var list []*Application
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), &list))
+	assert.Equal(t, List(handler.Store.Database), list)
+
+	responseRecorder = serve(handler.HandleApplicationGet, map[string]string{"slug": "app"})
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var application Application
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), &application))
+	assert.Equal(t, "0.1.0", application.Latest)
+
+	responseRecorder = serve(handler.HandleVersionsGet, map[string]string{"slug": "app"})
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var releases []*core.Metadata
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), &releases))
+	assert.Equal(t, []string{"0.1.0", "0.0.1"}, versions(releases))
+
+	responseRecorder = serve(handler.HandleVersionGet, map[string]string{"slug": "app", "version": "latest"})
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var release core.Metadata
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), &release))
+	assert.Equal(t, "0.1.0", release.Version)
+}
+
+func TestHandler_NotFound(t *testing.T) {
+	handler := &Handler{Store: &storage.MetadataStore{Database: newTestDatabase([2]string{"app", "0.0.1"})}}
+
+	responseRecorder := serve(handler.HandleApplicationGet, map[string]string{"slug": "unknown"})
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+	assert.Equal(t, "Unknown application \"unknown\"\n", responseRecorder.Body.String())
+
+	responseRecorder = serve(handler.HandleVersionsGet, map[string]string{"slug": "unknown"})
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+
+	responseRecorder = serve(handler.HandleVersionGet, map[string]string{"slug": "app", "version": "1.0.0"})
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+	assert.Equal(t, "Unknown version \"1.0.0\" of application \"app\"\n", responseRecorder.Body.String())
+}
@@ -0,0 +1,244 @@
+package client
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/ratelimit"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	yamlContentType = "application/x-yaml"
+
+	DefaultMaxRetries = 3
+	DefaultMinBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff = 5 * time.Second
+)
+
+// Client of the metadata API
+type Client struct {
+	// Address of the server, IE: http://localhost:8080
+	BaseURL string
+	// Sent in the X-API-Key header when set
+	ApiKey     string
+	HTTPClient *http.Client
+	// Number of retries after the first attempt
+	// Requests are retried when rate limited, when the server is not ready and, for idempotent requests,
+	// on network errors and 5xx responses.
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		MaxRetries: DefaultMaxRetries,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+	}
+}
+
+// Filters and paging of List
+type ListOptions struct {
+	// Field path, optionally followed by an operator, to value, IE: license[ne] -> MIT
+	Filters map[string]string
+	// Kubernetes style label selector, IE: tier=critical,env in (prod,staging)
+	LabelSelector string
+	// Size of every page, the server default when zero
+	PageSize int
+	Offset   int
+}
+
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+	for key, value := range o.Filters {
+		query.Set(key, value)
+	}
+	if o.LabelSelector != "" {
+		query.Set("labelSelector", o.LabelSelector)
+	}
+	if o.PageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(o.PageSize))
+	}
+	if o.Offset > 0 {
+		query.Set("offset", strconv.Itoa(o.Offset))
+	}
+	return query
+}
+
+// GET /metadata/{id}
+func (c *Client) Get(ctx context.Context, id uuid.UUID) (*core.Metadata, error) {
+	var metadata *core.Metadata
+	if err := c.do(ctx, http.MethodGet, "/metadata/"+id.String(), nil, &metadata); err != nil {
+		return nil, err
+	}
+	// The server answers null for unknown ids
+	if metadata == nil {
+		return nil, &Error{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("metadata %s not found", id)}
+	}
+	return metadata, nil
+}
+
+// GET /metadata
+// Returns a single page, use List to iterate over every page
+func (c *Client) ListPage(ctx context.Context, options ListOptions) (*core.ResultPage, error) {
+	return c.listPage(ctx, "/metadata?"+options.query().Encode())
+}
+
+func (c *Client) listPage(ctx context.Context, path string) (*core.ResultPage, error) {
+	page := &core.ResultPage{}
+	if err := c.do(ctx, http.MethodGet, path, nil, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Returns an iterator over every metadata matching the filters, following nextLink page by page
+func (c *Client) List(options ListOptions) *Iterator {
+	return &Iterator{client: c, next: "/metadata?" + options.query().Encode()}
+}
+
+// PUT /metadata or PUT /metadata/{id}
+// Creates the metadata, or replaces it when it has an id. Returns the saved metadata.
+func (c *Client) Put(ctx context.Context, metadata *core.Metadata) (*core.Metadata, error) {
+	body, err := yaml.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	path := "/metadata"
+	if metadata.Id != (uuid.UUID{}) {
+		path = "/metadata/" + metadata.Id.String()
+	}
+	saved := &core.Metadata{}
+	if err := c.do(ctx, http.MethodPut, path, body, saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// DELETE /metadata/{id}
+func (c *Client) Delete(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, http.MethodDelete, "/metadata/"+id.String(), nil, nil)
+}
+
+// Sends the request, retrying with exponential backoff, and decodes the YAML response into out
+func (c *Client) do(ctx context.Context, method string, path string, body []byte, out interface{}) error {
+	// Creating without an id generates a new one, retrying it after a network error could create a duplicate
+	idempotent := !(method == http.MethodPut && strings.TrimSuffix(path, "/") == "/metadata")
+
+	for attempt := 0; ; attempt++ {
+		responseBody, err := c.send(ctx, method, path, body)
+		if err == nil {
+			if out == nil {
+				return nil
+			}
+			return yaml.Unmarshal(responseBody, out)
+		}
+		if attempt >= c.MaxRetries || !retryable(err, idempotent) {
+			return err
+		}
+
+		wait := c.backoff(attempt)
+		if e, ok := err.(*Error); ok && e.RetryAfter > wait {
+			wait = e.RetryAfter
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method string, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", yamlContentType)
+	if body != nil {
+		req.Header.Set("Content-Type", yamlContentType)
+	}
+	if c.ApiKey != "" {
+		req.Header.Set(ratelimit.ApiKeyHeader, c.ApiKey)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		return nil, newError(response, responseBody)
+	}
+	return responseBody, nil
+}
+
+// Rate limited and not ready responses were not processed so they are always safe to retry
+func retryable(err error, idempotent bool) bool {
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return false
+	}
+	e, ok := err.(*Error)
+	if !ok {
+		// Network error, the request may or may not have been processed
+		return idempotent
+	}
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests, e.StatusCode == http.StatusServiceUnavailable:
+		return true
+	case e.StatusCode >= http.StatusInternalServerError:
+		return idempotent
+	default:
+		return false
+	}
+}
+
+// Exponential backoff with full jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	max := c.MinBackoff << uint(attempt)
+	if max > c.MaxBackoff || max <= 0 {
+		max = c.MaxBackoff
+	}
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// Retry-After is either a number of seconds or an HTTP date
+func retryAfter(response *http.Response) time.Duration {
+	value := response.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
@@ -0,0 +1,279 @@
+package client
+
+import (
+	"APIServerExercise/config"
+	"APIServerExercise/core"
+	"APIServerExercise/logging"
+	"APIServerExercise/server"
+	"APIServerExercise/util"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// Serves the real router, with request ids like in main
+func setupTest(t *testing.T) (*Client, *server.Server) {
+	srv := server.New(config.Defaults())
+	r := srv.NewApiRouter()
+	r.Use((&logging.AccessLog{Logger: logging.New(ioutil.Discard)}).Middleware)
+	testServer := httptest.NewServer(r)
+	t.Cleanup(testServer.Close)
+
+	c := New(testServer.URL)
+	c.MinBackoff = time.Millisecond
+	c.MaxBackoff = time.Millisecond
+	return c, srv
+}
+
+func newMetadata(title string) *core.Metadata {
+	website, _ := url.Parse("https://website.com")
+	source, _ := url.Parse("https://github.com/random/repo")
+	return &core.Metadata{
+		Title:       title,
+		Version:     "0.0.1",
+		Maintainers: []*core.Maintainer{{Name: "first last", Email: "first@hotmail.com"}},
+		Company:     "Random Inc.",
+		Website:     util.Yamlurl{URL: website},
+		Source:      util.Yamlurl{URL: source},
+		License:     "Apache-2.0",
+		Description: "Some application content",
+	}
+}
+
+// region CRUD
+
+func TestClient_PutGetDelete(t *testing.T) {
+	c, srv := setupTest(t)
+	ctx := context.Background()
+
+	created, err := c.Put(ctx, newMetadata("Valid App 1"))
+	assert.Nil(t, err)
+	assert.NotEqual(t, uuid.UUID{}, created.Id)
+	assert.Equal(t, "https://website.com", created.Website.String())
+	assert.Len(t, srv.Database.Metadatas, 1)
+
+	created.Version = "0.0.2"
+	updated, err := c.Put(ctx, created)
+	assert.Nil(t, err)
+	assert.Equal(t, created.Id, updated.Id)
+
+	fetched, err := c.Get(ctx, created.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "0.0.2", fetched.Version)
+	assert.Equal(t, "first@hotmail.com", fetched.Maintainers[0].Email)
+
+	assert.Nil(t, c.Delete(ctx, created.Id))
+	assert.Empty(t, srv.Database.Metadatas)
+
+	_, err = c.Get(ctx, created.Id)
+	assert.True(t, IsNotFound(err))
+	assert.True(t, IsNotFound(c.Delete(ctx, created.Id)))
+}
+
+func TestClient_Put_Conflict(t *testing.T) {
+	c, srv := setupTest(t)
+	ctx := context.Background()
+
+	existing, err := c.Put(ctx, newMetadata("Valid App 1"))
+	assert.Nil(t, err)
+
+	_, err = c.Put(ctx, newMetadata("valid app 1"))
+	assert.True(t, IsConflict(err))
+	assert.Contains(t, err.(*Error).Message, fmt.Sprintf(
+		`title and version must be unique, metadata %s already has title "Valid App 1" and version "0.0.1"`, existing.Id))
+	assert.Len(t, srv.Database.Metadatas, 1)
+
+	// The metadata itself can be saved again, and other metadata once their values differ
+	_, err = c.Put(ctx, existing)
+	assert.Nil(t, err)
+	other := newMetadata("valid app 1")
+	other.Version = "0.0.2"
+	_, err = c.Put(ctx, other)
+	assert.Nil(t, err)
+}
+
+func TestClient_Put_ValidationFailed(t *testing.T) {
+	c, _ := setupTest(t)
+
+	metadata := newMetadata("Invalid App")
+	metadata.Maintainers[0].Email = "apptwohotmail.com"
+	_, err := c.Put(context.Background(), metadata)
+
+	assert.True(t, IsBadRequest(err))
+	e := err.(*Error)
+	assert.Contains(t, e.Message, "Validation failed")
+	assert.NotContains(t, e.Message, "Request ID")
+	assert.NotEmpty(t, e.RequestId)
+}
+
+// endregion
+
+// region List
+
+func TestClient_List(t *testing.T) {
+	c, _ := setupTest(t)
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		metadata := newMetadata(fmt.Sprintf("App %d", i))
+		if i%2 == 0 {
+			metadata.License = "MIT"
+		}
+		if i < 2 {
+			metadata.Labels = map[string]string{"tier": "critical"}
+		}
+		_, err := c.Put(ctx, metadata)
+		assert.Nil(t, err)
+	}
+
+	all, err := c.List(ListOptions{PageSize: 2}).All(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, all, 5)
+	assert.Equal(t, "App 0", all[0].Title)
+	assert.Equal(t, "App 4", all[4].Title)
+
+	it := c.List(ListOptions{PageSize: 1, Filters: map[string]string{"license": "MIT"}})
+	var titles []string
+	for {
+		metadata, err := it.Next(ctx)
+		if err == Done {
+			break
+		}
+		assert.Nil(t, err)
+		titles = append(titles, metadata.Title)
+	}
+	assert.Equal(t, []string{"App 0", "App 2", "App 4"}, titles)
+
+	all, err = c.List(ListOptions{LabelSelector: "tier in (critical)", Filters: map[string]string{"license": "MIT"}}).All(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, all, 1)
+	assert.Equal(t, "App 0", all[0].Title)
+
+	page, err := c.ListPage(ctx, ListOptions{PageSize: 2, Offset: 4})
+	assert.Nil(t, err)
+	assert.Len(t, page.Resources, 1)
+	assert.Empty(t, page.NextLink)
+}
+
+func TestClient_List_UnknownFilter(t *testing.T) {
+	c, _ := setupTest(t)
+
+	_, err := c.List(ListOptions{Filters: map[string]string{"licence": "MIT"}}).All(context.Background())
+	assert.True(t, IsBadRequest(err))
+	assert.Equal(t, `unknown field "licence", did you mean "license"?`, err.(*Error).Message)
+}
+
+// endregion
+
+// region Retries
+
+func TestClient_Retry(t *testing.T) {
+	attempts := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("resources: []\nnextLink: \"\"\n"))
+	}))
+	defer testServer.Close()
+	c := New(testServer.URL)
+	c.MinBackoff = time.Millisecond
+
+	page, err := c.ListPage(context.Background(), ListOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, page.Resources)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestClient_Retry_GivesUp(t *testing.T) {
+	attempts := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("index rebuild has not completed\n"))
+	}))
+	defer testServer.Close()
+	c := New(testServer.URL)
+	c.MinBackoff = time.Millisecond
+	c.MaxRetries = 2
+
+	err := c.Delete(context.Background(), uuid.New())
+	assert.True(t, IsUnavailable(err))
+	assert.Equal(t, "503 index rebuild has not completed", err.Error())
+	assert.Equal(t, 3, attempts)
+}
+
+// Creating without an id is not idempotent, a server error may have saved it
+func TestClient_Retry_NotIdempotent(t *testing.T) {
+	attempts := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer testServer.Close()
+	c := New(testServer.URL)
+	c.MinBackoff = time.Millisecond
+
+	_, err := c.Put(context.Background(), newMetadata("App"))
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestClient_Retry_ContextCanceled(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer testServer.Close()
+	c := New(testServer.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := c.Get(ctx, uuid.New())
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+// endregion
+
+// region Watch
+
+func TestClient_Watch(t *testing.T) {
+	c, _ := setupTest(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	existing, err := c.Put(ctx, newMetadata("Existing"))
+	assert.Nil(t, err)
+	events := c.Watch(ctx, WatchOptions{Interval: 5 * time.Millisecond})
+
+	event := <-events
+	assert.Equal(t, Added, event.Type)
+	assert.Equal(t, existing.Id, event.Metadata.Id)
+
+	existing.Version = "2.0.0"
+	_, err = c.Put(ctx, existing)
+	assert.Nil(t, err)
+	event = <-events
+	assert.Equal(t, Modified, event.Type)
+	assert.Equal(t, "2.0.0", event.Metadata.Version)
+
+	assert.Nil(t, c.Delete(ctx, existing.Id))
+	event = <-events
+	assert.Equal(t, Deleted, event.Type)
+	assert.Equal(t, existing.Id, event.Metadata.Id)
+
+	cancel()
+	for range events {
+	}
+}
+
+// endregion
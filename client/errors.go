@@ -0,0 +1,83 @@
+package client
+
+import (
+	"APIServerExercise/logging"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const requestIdPrefix = "Request ID: "
+
+// Error returned when the server responds with an error status code
+// The server writes the error message as plain text, followed by the request id on its own line.
+type Error struct {
+	StatusCode int
+	Message    string
+	// Id of the failed request in the server access logs, empty if the server did not report one
+	RequestId string
+	// How long the server asked to wait before retrying, zero if it did not ask
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	message := e.Message
+	if message == "" {
+		message = http.StatusText(e.StatusCode)
+	}
+	if e.RequestId != "" {
+		return fmt.Sprintf("%d %s (request id %s)", e.StatusCode, message, e.RequestId)
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, message)
+}
+
+// Builds the error from an error response
+func newError(response *http.Response, body []byte) *Error {
+	e := &Error{
+		StatusCode: response.StatusCode,
+		RequestId:  response.Header.Get(logging.RequestIdHeader),
+		RetryAfter: retryAfter(response),
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+		if strings.HasPrefix(line, requestIdPrefix) {
+			e.RequestId = strings.TrimPrefix(line, requestIdPrefix)
+			continue
+		}
+		lines = append(lines, line)
+	}
+	e.Message = strings.Join(lines, "\n")
+	return e
+}
+
+func hasStatus(err error, status int) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == status
+}
+
+// The metadata does not exist
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// The request was rejected, IE: the metadata failed validation or a filter is unknown
+func IsBadRequest(err error) bool {
+	return hasStatus(err, http.StatusBadRequest)
+}
+
+// Another metadata has the same values for the fields of a unique constraint, IE: the same title and version
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// The rate limit was exceeded, even after retrying
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+// The server is not ready, even after retrying
+func IsUnavailable(err error) bool {
+	return hasStatus(err, http.StatusServiceUnavailable)
+}
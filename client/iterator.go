@@ -0,0 +1,70 @@
+package client
+
+import (
+	"APIServerExercise/core"
+	"context"
+	"errors"
+	"net/url"
+)
+
+// Returned by Iterator.Next when every metadata has been returned
+var Done = errors.New("no more metadata")
+
+// Iterates over the pages of GET /metadata
+type Iterator struct {
+	client *Client
+	// Path and query of the next page, empty after the last page
+	next  string
+	page  []*core.Metadata
+	index int
+}
+
+// Returns the next metadata, fetching the next page when needed
+// Returns Done when there are no more results.
+func (it *Iterator) Next(ctx context.Context) (*core.Metadata, error) {
+	for it.index >= len(it.page) {
+		if it.next == "" {
+			return nil, Done
+		}
+		page, err := it.client.listPage(ctx, it.next)
+		if err != nil {
+			return nil, err
+		}
+		it.page, it.index = page.Resources, 0
+		it.next, err = relativeLink(page.NextLink)
+		if err != nil {
+			return nil, err
+		}
+	}
+	metadata := it.page[it.index]
+	it.index++
+	return metadata, nil
+}
+
+// Returns every remaining metadata
+func (it *Iterator) All(ctx context.Context) ([]*core.Metadata, error) {
+	var all []*core.Metadata
+	for {
+		metadata, err := it.Next(ctx)
+		if err == Done {
+			return all, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, metadata)
+	}
+}
+
+// nextLink is built from the Host header the server received and always uses http
+// Only its path and query are kept so the next page goes through the same base URL, IE: behind a proxy.
+func relativeLink(link string) (string, error) {
+	if link == "" {
+		return "", nil
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	return u.RequestURI(), nil
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"APIServerExercise/core"
+	"context"
+	"github.com/google/uuid"
+	"reflect"
+	"time"
+)
+
+const DefaultWatchInterval = 5 * time.Second
+
+type EventType string
+
+const (
+	Added    EventType = "ADDED"
+	Modified EventType = "MODIFIED"
+	Deleted  EventType = "DELETED"
+	// The server could not be listed, Err is set
+	Failed EventType = "ERROR"
+)
+
+type Event struct {
+	Type     EventType
+	Metadata *core.Metadata
+	Err      error
+}
+
+type WatchOptions struct {
+	// Filters of the watched metadata, offset is ignored
+	ListOptions
+	// Time between two polls, DefaultWatchInterval when zero
+	Interval time.Duration
+}
+
+// Sends an event for every metadata matching the filters that is added, modified or deleted
+// The server has no change feed so the metadata is listed every interval and compared with the previous list.
+// The first list sends Added for every existing metadata. The channel is closed when ctx is done.
+func (c *Client) Watch(ctx context.Context, options WatchOptions) <-chan Event {
+	interval := options.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	options.Offset = 0
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		send := func(event Event) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var previous []*core.Metadata
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			current, err := c.List(options.ListOptions).All(ctx)
+			if err != nil {
+				if ctx.Err() != nil || !send(Event{Type: Failed, Err: err}) {
+					return
+				}
+			} else {
+				for _, event := range diff(previous, current) {
+					if !send(event) {
+						return
+					}
+				}
+				previous = current
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return events
+}
+
+// Returns the events turning previous into current, in the order of the lists
+func diff(previous []*core.Metadata, current []*core.Metadata) []Event {
+	previousById := make(map[uuid.UUID]*core.Metadata, len(previous))
+	for _, metadata := range previous {
+		previousById[metadata.Id] = metadata
+	}
+	currentById := make(map[uuid.UUID]bool, len(current))
+
+	var events []Event
+	for _, metadata := range current {
+		currentById[metadata.Id] = true
+		old, ok := previousById[metadata.Id]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: Added, Metadata: metadata})
+		case !reflect.DeepEqual(old, metadata):
+			events = append(events, Event{Type: Modified, Metadata: metadata})
+		}
+	}
+	for _, metadata := range previous {
+		if !currentById[metadata.Id] {
+			events = append(events, Event{Type: Deleted, Metadata: metadata})
+		}
+	}
+	return events
+}
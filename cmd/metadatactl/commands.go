@@ -0,0 +1,332 @@
+package main
+
+import (
+	"APIServerExercise/client"
+	"APIServerExercise/core"
+	"APIServerExercise/lifecycle"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// metadatactl get [-o format] ID...
+func (c *cli) get(args []string) error {
+	flags := c.newFlagSet("get")
+	output := flags.String("o", tableOutput, "Output format: table, yaml or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("get requires at least one id")
+	}
+	cl, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	var metadatas []*core.Metadata
+	for _, arg := range flags.Args() {
+		id, err := uuid.Parse(arg)
+		if err != nil {
+			return fmt.Errorf("invalid id %s: %v", arg, err)
+		}
+		metadata, err := cl.Get(context.Background(), id)
+		if err != nil {
+			return err
+		}
+		metadatas = append(metadatas, metadata)
+	}
+	return printMetadata(c.stdout, *output, metadatas)
+}
+
+// metadatactl list [-filter field=value]... [-l selector] [-pageSize n] [-o format]
+func (c *cli) list(args []string) error {
+	flags := c.newFlagSet("list")
+	filters := filterFlag{}
+	flags.Var(filters, "filter", "Filter as field=value, IE: license=MIT or maintainers.email[ne]=a@b.com. Repeatable")
+	selector := flags.String("l", "", "Label selector, IE: tier=critical,env in (prod,staging)")
+	pageSize := flags.Int("pageSize", 0, "Number of metadata fetched per request, the server default when 0")
+	output := flags.String("o", tableOutput, "Output format: table, yaml or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	cl, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	metadatas, err := cl.List(client.ListOptions{Filters: filters, LabelSelector: *selector, PageSize: *pageSize}).All(context.Background())
+	if err != nil {
+		return err
+	}
+	return printMetadata(c.stdout, *output, metadatas)
+}
+
+// metadatactl export [-o yaml|json]
+func (c *cli) export(args []string) error {
+	flags := c.newFlagSet("export")
+	output := flags.String("o", yamlOutput, "Output format: yaml or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *output == tableOutput {
+		return fmt.Errorf("export does not support the %s output", tableOutput)
+	}
+	cl, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	metadatas, err := cl.List(client.ListOptions{}).All(context.Background())
+	if err != nil {
+		return err
+	}
+	return printMetadata(c.stdout, *output, metadatas)
+}
+
+// metadatactl delete ID...
+func (c *cli) delete(args []string) error {
+	flags := c.newFlagSet("delete")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("delete requires at least one id")
+	}
+	cl, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	for _, arg := range flags.Args() {
+		id, err := uuid.Parse(arg)
+		if err != nil {
+			return fmt.Errorf("invalid id %s: %v", arg, err)
+		}
+		if err := cl.Delete(context.Background(), id); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "metadata/%s deleted\n", id)
+	}
+	return nil
+}
+
+// metadatactl apply -f PATH
+func (c *cli) apply(args []string) error {
+	flags := c.newFlagSet("apply")
+	path := flags.String("f", "", "File or directory of YAML metadata, - for stdin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	documents, err := c.readDocuments(*path)
+	if err != nil {
+		return err
+	}
+	cl, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	failed := false
+	for _, document := range documents {
+		existing, err := findExisting(ctx, cl, document.metadata)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "%s: %v\n", document.source, err)
+			failed = true
+			continue
+		}
+
+		action := "created"
+		if existing != nil {
+			document.metadata.Id = existing.Id
+			keepServerDefaults(document.metadata, existing)
+			if toYaml(existing) == toYaml(document.metadata) {
+				fmt.Fprintf(c.stdout, "metadata/%s unchanged\n", existing.Id)
+				continue
+			}
+			action = "configured"
+		}
+
+		saved, err := cl.Put(ctx, document.metadata)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "%s: %v\n", document.source, err)
+			failed = true
+			continue
+		}
+		fmt.Fprintf(c.stdout, "metadata/%s %s\n", saved.Id, action)
+	}
+	if failed {
+		return errSilentFailure
+	}
+	return nil
+}
+
+// metadatactl diff -f PATH
+// Exits with 1 when there are differences, like diff
+func (c *cli) diff(args []string) error {
+	flags := c.newFlagSet("diff")
+	path := flags.String("f", "", "File or directory of YAML metadata, - for stdin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	documents, err := c.readDocuments(*path)
+	if err != nil {
+		return err
+	}
+	cl, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	changed := false
+	for _, document := range documents {
+		existing, err := findExisting(ctx, cl, document.metadata)
+		if err != nil {
+			return fmt.Errorf("%s: %v", document.source, err)
+		}
+
+		serverName, serverYaml := "/dev/null", ""
+		if existing != nil {
+			document.metadata.Id = existing.Id
+			keepServerDefaults(document.metadata, existing)
+			serverName, serverYaml = fmt.Sprintf("server/metadata/%s", existing.Id), toYaml(existing)
+		}
+		if writeDiff(c.stdout, serverName, serverYaml, document.source, toYaml(document.metadata)) {
+			changed = true
+		}
+	}
+	if changed {
+		return errSilentFailure
+	}
+	return nil
+}
+
+// Returns the server copy of the metadata, nil if there is none
+// Metadata with an id is matched by id, otherwise by title.
+func findExisting(ctx context.Context, cl *client.Client, metadata *core.Metadata) (*core.Metadata, error) {
+	if metadata.Id != (uuid.UUID{}) {
+		existing, err := cl.Get(ctx, metadata.Id)
+		if client.IsNotFound(err) {
+			return nil, nil
+		}
+		return existing, err
+	}
+
+	// The title filter also matches single words, only keep exact matches. Draft and retired metadata are matched too.
+	filters := map[string]string{"title": metadata.Title, "lifecycle": lifecycle.Any}
+	candidates, err := cl.List(client.ListOptions{Filters: filters}).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var matches []*core.Metadata
+	for _, candidate := range candidates {
+		if candidate.Title == metadata.Title {
+			matches = append(matches, candidate)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%d metadata are titled %q, add the id to the file", len(matches), metadata.Title)
+	}
+}
+
+// Metadata without an application, a lifecycle or a deprecation keeps those of the metadata it replaces, the same as
+// on the server, and so does the deprecation date which the server sets
+func keepServerDefaults(metadata *core.Metadata, existing *core.Metadata) {
+	if metadata.Application == "" {
+		metadata.Application = existing.Application
+	}
+	if metadata.Lifecycle == "" {
+		metadata.Lifecycle = existing.Lifecycle
+	}
+	if existing.Deprecation == nil || (metadata.Lifecycle != lifecycle.Deprecated && metadata.Lifecycle != lifecycle.Retired) {
+		return
+	}
+	if metadata.Deprecation == nil {
+		kept := *existing.Deprecation
+		metadata.Deprecation = &kept
+	}
+	metadata.Deprecation.DeprecatedAt = existing.Deprecation.DeprecatedAt
+}
+
+// The link status and the merge history are set by the server and left out, so they never show as a change
+func toYaml(metadata *core.Metadata) string {
+	withoutServerFields := *metadata
+	withoutServerFields.Links = nil
+	withoutServerFields.Merged = nil
+	content, err := yaml.Marshal(&withoutServerFields)
+	if err != nil {
+		return err.Error()
+	}
+	return string(content)
+}
+
+type document struct {
+	// File the document was read from
+	source   string
+	metadata *core.Metadata
+}
+
+// Reads every YAML document of a file, of the .yaml and .yml files of a directory or of stdin
+func (c *cli) readDocuments(path string) ([]document, error) {
+	if path == "" {
+		return nil, fmt.Errorf("-f is required")
+	}
+	if path == "-" {
+		return decodeDocuments("stdin", c.stdin)
+	}
+
+	var documents []document
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		// Files given explicitly are read whatever their extension
+		extension := strings.ToLower(filepath.Ext(file))
+		if file != path && extension != ".yaml" && extension != ".yml" {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		decoded, err := decodeDocuments(file, f)
+		if err != nil {
+			return err
+		}
+		documents = append(documents, decoded...)
+		return nil
+	})
+	return documents, err
+}
+
+func decodeDocuments(source string, r io.Reader) ([]document, error) {
+	var documents []document
+	decoder := yaml.NewDecoder(r)
+	for {
+		metadata := &core.Metadata{}
+		err := decoder.Decode(metadata)
+		if err == io.EOF {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %v", source, err)
+		}
+		documents = append(documents, document{source: source, metadata: metadata})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"text/tabwriter"
+)
+
+// metadatactl config SUBCOMMAND
+func (c *cli) config(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("config requires a subcommand: get-contexts, current-context, use-context, set-context or delete-context")
+	}
+
+	switch args[0] {
+	case "get-contexts":
+		w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CURRENT\tNAME\tSERVER")
+		for _, name := range c.contexts.names() {
+			current := ""
+			if name == c.contexts.CurrentContext {
+				current = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", current, name, c.contexts.Contexts[name].Server)
+		}
+		return w.Flush()
+
+	case "current-context":
+		if c.contexts.CurrentContext == "" {
+			return fmt.Errorf("current context is not set")
+		}
+		fmt.Fprintln(c.stdout, c.contexts.CurrentContext)
+		return nil
+
+	case "use-context":
+		if len(args) != 2 {
+			return fmt.Errorf("use-context requires a context name")
+		}
+		if _, ok := c.contexts.Contexts[args[1]]; !ok {
+			return fmt.Errorf("context %s does not exist", args[1])
+		}
+		c.contexts.CurrentContext = args[1]
+		if err := c.contexts.save(); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "Switched to context %s\n", args[1])
+		return nil
+
+	case "set-context":
+		flags := c.newFlagSet("set-context")
+		server := flags.String("server", "", "Address of the server, IE: "+defaultServer)
+		apiKey := flags.String("apiKey", "", "API key sent in the X-API-Key header")
+		if len(args) < 2 {
+			return fmt.Errorf("set-context requires a context name")
+		}
+		name := args[1]
+		if err := flags.Parse(args[2:]); err != nil {
+			return err
+		}
+
+		context, ok := c.contexts.Contexts[name]
+		if !ok {
+			context = &Context{Server: defaultServer}
+			c.contexts.Contexts[name] = context
+		}
+		flags.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "server":
+				context.Server = *server
+			case "apiKey":
+				context.ApiKey = *apiKey
+			}
+		})
+		// The first context becomes the current one
+		if c.contexts.CurrentContext == "" {
+			c.contexts.CurrentContext = name
+		}
+		if err := c.contexts.save(); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "Context %s set\n", name)
+		return nil
+
+	case "delete-context":
+		if len(args) != 2 {
+			return fmt.Errorf("delete-context requires a context name")
+		}
+		if _, ok := c.contexts.Contexts[args[1]]; !ok {
+			return fmt.Errorf("context %s does not exist", args[1])
+		}
+		delete(c.contexts.Contexts, args[1])
+		if c.contexts.CurrentContext == args[1] {
+			c.contexts.CurrentContext = ""
+		}
+		if err := c.contexts.save(); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "Context %s deleted\n", args[1])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown config subcommand %s", args[0])
+	}
+}
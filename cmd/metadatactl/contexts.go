@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	configEnv     = "METADATACTL_CONFIG"
+	defaultServer = "http://localhost:8080"
+)
+
+// A server the CLI can talk to
+type Context struct {
+	Server string `yaml:"server"`
+	ApiKey string `yaml:"apiKey,omitempty"`
+}
+
+// Contents of the CLI config file, IE: ~/.metadatactl/config.yaml
+type Contexts struct {
+	CurrentContext string              `yaml:"currentContext"`
+	Contexts       map[string]*Context `yaml:"contexts"`
+
+	path string
+}
+
+// Default location of the config file, overridden by METADATACTL_CONFIG
+func defaultConfigPath(getenv func(string) string) string {
+	if path := getenv(configEnv); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".metadatactl.yaml"
+	}
+	return filepath.Join(home, ".metadatactl", "config.yaml")
+}
+
+// Reads the config file, a missing file is the same as no contexts
+func loadContexts(path string) (*Contexts, error) {
+	contexts := &Contexts{Contexts: map[string]*Context{}, path: path}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return contexts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, contexts); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if contexts.Contexts == nil {
+		contexts.Contexts = map[string]*Context{}
+	}
+	return contexts, nil
+}
+
+func (c *Contexts) save() error {
+	content, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	// The file can hold API keys
+	return ioutil.WriteFile(c.path, content, 0600)
+}
+
+// Returns the context selected by name, the current context when name is empty
+// Without any context the CLI talks to a local server.
+func (c *Contexts) resolve(name string) (*Context, error) {
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if name == "" {
+		return &Context{Server: defaultServer}, nil
+	}
+	context, ok := c.Contexts[name]
+	if !ok {
+		return nil, fmt.Errorf("context %s does not exist", name)
+	}
+	return context, nil
+}
+
+func (c *Contexts) names() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
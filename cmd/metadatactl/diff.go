@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Writes a line based diff of a and b, in the unified format without hunks
+// Returns whether there are differences.
+func writeDiff(out io.Writer, aName string, a string, bName string, b string) bool {
+	aLines, bLines := splitLines(a), splitLines(b)
+
+	// Longest common subsequence table, lcs[i][j] is the LCS of aLines[i:] and bLines[j:]
+	lcs := make([][]int, len(aLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bLines)+1)
+	}
+	for i := len(aLines) - 1; i >= 0; i-- {
+		for j := len(bLines) - 1; j >= 0; j-- {
+			if aLines[i] == bLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	changed := false
+	i, j := 0, 0
+	for i < len(aLines) || j < len(bLines) {
+		switch {
+		case i < len(aLines) && j < len(bLines) && aLines[i] == bLines[j]:
+			lines = append(lines, " "+aLines[i])
+			i++
+			j++
+		case i < len(aLines) && (j == len(bLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "-"+aLines[i])
+			changed = true
+			i++
+		default:
+			lines = append(lines, "+"+bLines[j])
+			changed = true
+			j++
+		}
+	}
+
+	if changed {
+		fmt.Fprintf(out, "--- %s\n+++ %s\n", aName, bName)
+		for _, line := range lines {
+			fmt.Fprintln(out, line)
+		}
+	}
+	return changed
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,152 @@
+// metadatactl manages application metadata stored in the metadata API server
+package main
+
+import (
+	"APIServerExercise/client"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const usage = `Usage: metadatactl [global flags] <command> [flags] [arguments]
+
+Commands:
+  get ID...             Print metadata by id
+  list                  Print the metadata matching the filters
+  apply -f PATH         Create or update the metadata of a file, a directory or - for stdin
+  diff -f PATH          Show the differences between files and the server copies
+  delete ID...          Delete metadata by id
+  export                Print every metadata as YAML documents that can be applied again
+  config SUBCOMMAND     Manage contexts: get-contexts, current-context, use-context, set-context, delete-context
+
+Global flags:
+`
+
+// Returned by commands that already reported the failure, IE: diff found differences
+var errSilentFailure = errors.New("failed")
+
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	contexts *Contexts
+	// Selected with -context, the current context when empty
+	contextName string
+	// Override the selected context
+	server string
+	apiKey string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, os.Getenv))
+}
+
+// Runs the command and returns the exit code
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer, getenv func(string) string) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	flags := flag.NewFlagSet("metadatactl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", defaultConfigPath(getenv), "Path of the config file holding the contexts")
+	flags.StringVar(&c.contextName, "context", "", "Context to use instead of the current context")
+	flags.StringVar(&c.server, "server", "", "Address of the server, overrides the context")
+	flags.StringVar(&c.apiKey, "apiKey", "", "API key sent in the X-API-Key header, overrides the context")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	var err error
+	c.contexts, err = loadContexts(*configPath)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	commands := map[string]func(args []string) error{
+		"get":    c.get,
+		"list":   c.list,
+		"apply":  c.apply,
+		"diff":   c.diff,
+		"delete": c.delete,
+		"export": c.export,
+		"config": c.config,
+	}
+	command, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %s\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+
+	err = command(flags.Args()[1:])
+	switch {
+	case err == nil:
+		return 0
+	case err == flag.ErrHelp:
+		return 0
+	case err == errSilentFailure:
+		return 1
+	default:
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+}
+
+// Returns the client of the selected context
+func (c *cli) client() (*client.Client, error) {
+	context, err := c.contexts.resolve(c.contextName)
+	if err != nil {
+		return nil, err
+	}
+	server, apiKey := context.Server, context.ApiKey
+	if c.server != "" {
+		server = c.server
+	}
+	if c.apiKey != "" {
+		apiKey = c.apiKey
+	}
+	cl := client.New(server)
+	cl.ApiKey = apiKey
+	return cl, nil
+}
+
+func (c *cli) newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	return flags
+}
+
+// Repeatable field=value flag
+type filterFlag map[string]string
+
+func (f filterFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for key, value := range f {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f filterFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("filter must be field=value, IE: license=MIT or license[ne]=MIT")
+	}
+	f[parts[0]] = parts[1]
+	return nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"APIServerExercise/config"
+	"APIServerExercise/server"
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validApp1 = `title: Valid App 1
+version: 0.0.1
+maintainers:
+- name: firstmaintainer app1
+  email: firstmaintainer@hotmail.com
+company: Random Inc.
+website: https://website.com
+source: https://github.com/random/repo
+license: Apache-2.0
+description: Some application content
+`
+
+const validApp2 = `title: Valid App 2
+version: 1.0.1
+maintainers:
+- name: AppTwo Maintainer
+  email: apptwo@hotmail.com
+company: Upbound Inc.
+website: https://upbound.io
+source: https://github.com/upbound/repo
+license: MIT
+description: Because it simply is...
+`
+
+type testEnv struct {
+	t          *testing.T
+	srv        *server.Server
+	dir        string
+	configPath string
+}
+
+func setupTest(t *testing.T) *testEnv {
+	srv := server.New(config.Defaults())
+	testServer := httptest.NewServer(srv.NewApiRouter())
+	t.Cleanup(testServer.Close)
+
+	env := &testEnv{t: t, srv: srv, dir: t.TempDir()}
+	env.configPath = filepath.Join(env.dir, "config.yaml")
+	code, _, _ := env.run("", "config", "set-context", "test", "-server", testServer.URL)
+	assert.Equal(t, 0, code)
+	return env
+}
+
+// Runs metadatactl and returns the exit code, stdout and stderr
+func (e *testEnv) run(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	getenv := func(name string) string {
+		if name == configEnv {
+			return e.configPath
+		}
+		return ""
+	}
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr, getenv)
+	return code, stdout.String(), stderr.String()
+}
+
+func (e *testEnv) writeFile(name string, content string) string {
+	path := filepath.Join(e.dir, name)
+	assert.Nil(e.t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(e.t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+// region apply
+
+func TestApply(t *testing.T) {
+	env := setupTest(t)
+	path := env.writeFile("apps/apps.yaml", validApp1+"---\n"+validApp2)
+	env.writeFile("apps/README.md", "not metadata")
+
+	code, stdout, stderr := env.run("", "apply", "-f", filepath.Dir(path))
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, 2, strings.Count(stdout, " created"))
+	assert.Len(t, env.srv.Database.Metadatas, 2)
+
+	// Matched by title, only the changed document is updated
+	env.writeFile("apps/apps.yaml", validApp1+"---\n"+strings.Replace(validApp2, "1.0.1", "1.0.2", 1))
+	code, stdout, _ = env.run("", "apply", "-f", path)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, 1, strings.Count(stdout, " unchanged"))
+	assert.Equal(t, 1, strings.Count(stdout, " configured"))
+	assert.Len(t, env.srv.Database.Metadatas, 2)
+}
+
+func TestApply_Stdin(t *testing.T) {
+	env := setupTest(t)
+
+	code, stdout, _ := env.run(validApp1, "apply", "-f", "-")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, " created")
+}
+
+func TestApply_ValidationFailed(t *testing.T) {
+	env := setupTest(t)
+
+	invalid := strings.Replace(validApp1, "version: 0.0.1\n", "", 1)
+	code, _, stderr := env.run(invalid+"---\n"+validApp2, "apply", "-f", "-")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "stdin: 400 Validation failed")
+	assert.Len(t, env.srv.Database.Metadatas, 1)
+}
+
+// endregion
+
+// region get, list, export and delete
+
+func TestList(t *testing.T) {
+	env := setupTest(t)
+	env.run(validApp1+"---\n"+validApp2, "apply", "-f", "-")
+
+	code, stdout, _ := env.run("", "list")
+	assert.Equal(t, 0, code)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "ID"))
+	assert.Contains(t, lines[1], "Valid App 1")
+
+	code, stdout, _ = env.run("", "list", "-filter", "license=MIT", "-o", "json")
+	assert.Equal(t, 0, code)
+	var values []map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(stdout), &values))
+	assert.Len(t, values, 1)
+	assert.Equal(t, "Valid App 2", values[0]["title"])
+	assert.Equal(t, "https://upbound.io", values[0]["website"])
+
+	code, _, stderr := env.run("", "list", "-filter", "licence=MIT")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, `did you mean "license"?`)
+}
+
+func TestGetExportDelete(t *testing.T) {
+	env := setupTest(t)
+	env.run(validApp1+"---\n"+validApp2, "apply", "-f", "-")
+	id := env.srv.Database.Ordering[0].String()
+
+	code, stdout, _ := env.run("", "get", "-o", "yaml", id)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "id: "+id)
+	assert.Contains(t, stdout, "title: Valid App 1")
+
+	// Export can be applied again without changes
+	code, exported, _ := env.run("", "export")
+	assert.Equal(t, 0, code)
+	code, stdout, _ = env.run(exported, "apply", "-f", "-")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, 2, strings.Count(stdout, " unchanged"))
+
+	code, stdout, _ = env.run("", "delete", id)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "metadata/"+id+" deleted\n", stdout)
+
+	code, _, stderr := env.run("", "get", id)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "404")
+}
+
+// endregion
+
+// region diff
+
+func TestDiff(t *testing.T) {
+	env := setupTest(t)
+	env.run(validApp1, "apply", "-f", "-")
+	path := env.writeFile("app.yaml", validApp1)
+
+	code, stdout, _ := env.run("", "diff", "-f", path)
+	assert.Equal(t, 0, code)
+	assert.Empty(t, stdout)
+
+	env.writeFile("app.yaml", strings.Replace(validApp1, "0.0.1", "0.0.2", 1)+"---\n"+validApp2)
+	code, stdout, _ = env.run("", "diff", "-f", path)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, "--- server/metadata/")
+	assert.Contains(t, stdout, "-version: 0.0.1\n+version: 0.0.2\n")
+	assert.Contains(t, stdout, "--- /dev/null\n+++ "+path)
+	assert.Contains(t, stdout, "+title: Valid App 2\n")
+}
+
+// endregion
+
+// region config
+
+func TestConfig_Contexts(t *testing.T) {
+	env := setupTest(t)
+
+	code, stdout, _ := env.run("", "config", "set-context", "prod", "-server", "https://metadata.example.com", "-apiKey", "secret")
+	assert.Equal(t, 0, code)
+	code, stdout, _ = env.run("", "config", "current-context")
+	assert.Equal(t, "test\n", stdout)
+
+	code, stdout, _ = env.run("", "config", "use-context", "prod")
+	assert.Equal(t, 0, code)
+	code, stdout, _ = env.run("", "config", "get-contexts")
+	assert.Contains(t, stdout, "*        prod  https://metadata.example.com")
+
+	content, err := ioutil.ReadFile(env.configPath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "apiKey: secret")
+
+	code, _, stderr := env.run("", "config", "use-context", "missing")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "context missing does not exist")
+
+	code, _, stderr = env.run("", "-context", "missing", "list")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "context missing does not exist")
+}
+
+// endregion
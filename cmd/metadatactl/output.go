@@ -0,0 +1,63 @@
+package main
+
+import (
+	"APIServerExercise/core"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"text/tabwriter"
+)
+
+const (
+	tableOutput = "table"
+	yamlOutput  = "yaml"
+	jsonOutput  = "json"
+)
+
+// Writes the metadata in the requested format
+// yaml is a stream of documents that can be applied again, json is a single array.
+func printMetadata(out io.Writer, format string, metadatas []*core.Metadata) error {
+	switch format {
+	case tableOutput:
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTITLE\tVERSION\tLICENSE\tCOMPANY")
+		for _, m := range metadatas {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", m.Id, m.Title, m.Version, m.License, m.Company)
+		}
+		return w.Flush()
+	case yamlOutput:
+		encoder := yaml.NewEncoder(out)
+		for _, m := range metadatas {
+			if err := encoder.Encode(m); err != nil {
+				return err
+			}
+		}
+		return encoder.Close()
+	case jsonOutput:
+		values := make([]interface{}, 0, len(metadatas))
+		for _, m := range metadatas {
+			value, err := toJsonValue(m)
+			if err != nil {
+				return err
+			}
+			values = append(values, value)
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(values)
+	default:
+		return fmt.Errorf("unknown output format %s, expected %s, %s or %s", format, tableOutput, yamlOutput, jsonOutput)
+	}
+}
+
+// The core types only have yaml tags, go through YAML so JSON uses the same field names
+func toJsonValue(metadata *core.Metadata) (interface{}, error) {
+	content, err := yaml.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]interface{}
+	err = yaml.Unmarshal(content, &value)
+	return value, err
+}
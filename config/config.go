@@ -0,0 +1,275 @@
+package config
+
+import (
+	"APIServerExercise/duplicates"
+	"APIServerExercise/licensepolicy"
+	"APIServerExercise/linkcheck"
+	"APIServerExercise/ratelimit"
+	"APIServerExercise/storage"
+	"APIServerExercise/tracing"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"regexp"
+	"time"
+)
+
+const (
+	MemoryBackend = "memory"
+	FileBackend   = "file"
+)
+
+// Host name, optionally with a leading wildcard matching the subdomains
+var sourceHostPattern = regexp.MustCompile(`^(\*\.)?[A-Za-z0-9]([-A-Za-z0-9]*[A-Za-z0-9])?(\.[A-Za-z0-9]([-A-Za-z0-9]*[A-Za-z0-9])?)*$`)
+
+// Every setting of the server
+// Settings are layered, later layers override earlier ones:
+// defaults, YAML config file, environment variables, command line flags
+type Config struct {
+	Server  ServerConfig                    `yaml:"server"`
+	Storage StorageConfig                   `yaml:"storage"`
+	Index   IndexConfig                     `yaml:"index"`
+	Paging  PagingConfig                    `yaml:"paging"`
+	Graphql GraphqlConfig                   `yaml:"graphql"`
+	Auth    AuthConfig                      `yaml:"auth"`
+	Tracing TracingConfig                   `yaml:"tracing"`
+	Limits  map[string]ratelimit.RoleLimits `yaml:"limits"`
+	// Rules of the metadata fields beyond their validate tags
+	Validation ValidationConfig `yaml:"validation"`
+	// License compliance policy checked when saving metadata
+	Licenses licensepolicy.Config `yaml:"licenses"`
+	// Background checks of the website and source URLs
+	LinkCheck linkcheck.Config `yaml:"linkCheck"`
+	// Background detection of metadata registered more than once
+	Duplicates duplicates.Config `yaml:"duplicates"`
+}
+
+type ServerConfig struct {
+	Address string `yaml:"address"`
+	// Address the gRPC API listens on, disabled when empty
+	GrpcAddress     string        `yaml:"grpcAddress"`
+	ReadTimeout     time.Duration `yaml:"readTimeout"`
+	WriteTimeout    time.Duration `yaml:"writeTimeout"`
+	IdleTimeout     time.Duration `yaml:"idleTimeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+}
+
+type StorageConfig struct {
+	// memory or file
+	Backend string `yaml:"backend"`
+	// Path of the data file when using the file backend
+	Path string `yaml:"path"`
+}
+
+type IndexConfig struct {
+	DisableIndexWords bool `yaml:"disableIndexWords"`
+}
+
+type PagingConfig struct {
+	DefaultPageSize int `yaml:"defaultPageSize"`
+	MaxPageSize     int `yaml:"maxPageSize"`
+}
+
+// Limits of the queries accepted by POST /graphql
+type GraphqlConfig struct {
+	// Deepest nesting of selections
+	MaxDepth int `yaml:"maxDepth"`
+	// Highest estimated number of resolved fields, list fields count once per item of a page
+	MaxComplexity int `yaml:"maxComplexity"`
+}
+
+type ValidationConfig struct {
+	// Hosts the source URL can be on, IE: github.com or *.example.com, any host when empty
+	SourceHosts []string `yaml:"sourceHosts"`
+	// Fields whose values no two metadata can share, IE: [[title, version], [source]]
+	Unique [][]string `yaml:"unique"`
+}
+
+type TracingConfig struct {
+	// none, stdout, file or otlp
+	Exporter string `yaml:"exporter"`
+	// File spans are appended to when using the file exporter
+	Path string `yaml:"path"`
+	// OTLP/HTTP endpoint of the otlp exporter, IE: http://localhost:4318, OTEL_EXPORTER_OTLP_ENDPOINT when empty
+	Endpoint string `yaml:"endpoint"`
+}
+
+type AuthConfig struct {
+	// Map of API key -> role name
+	ApiKeys map[string]string `yaml:"apiKeys"`
+	// Roles allowed to use the admin endpoints
+	AdminRoles []string `yaml:"adminRoles"`
+}
+
+func Defaults() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Address:         ":8080",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     2 * time.Minute,
+			ShutdownTimeout: 20 * time.Second,
+		},
+		Storage: StorageConfig{
+			Backend: MemoryBackend,
+		},
+		Paging: PagingConfig{
+			DefaultPageSize: 10,
+			MaxPageSize:     1000,
+		},
+		Graphql: GraphqlConfig{
+			MaxDepth:      10,
+			MaxComplexity: 5000,
+		},
+		Auth: AuthConfig{
+			ApiKeys:    map[string]string{},
+			AdminRoles: []string{"admin"},
+		},
+		Tracing: TracingConfig{
+			Exporter: tracing.NoneExporter,
+		},
+		Validation: ValidationConfig{
+			Unique: [][]string{{"title", "version"}},
+		},
+		Limits:     defaultLimits(),
+		Licenses:   licensepolicy.DefaultConfig(),
+		LinkCheck:  linkcheck.DefaultConfig(),
+		Duplicates: duplicates.DefaultConfig(),
+	}
+}
+
+// Default rate limits, with an admin role matching the default admin roles
+func defaultLimits() map[string]ratelimit.RoleLimits {
+	limits := ratelimit.DefaultConfig().Roles
+	limits["admin"] = ratelimit.RoleLimits{
+		Read:  ratelimit.Limit{Rate: 0},
+		Write: ratelimit.Limit{Rate: 50, Burst: 100},
+	}
+	return limits
+}
+
+// Merge the YAML file at path into the config
+// Only settings present in the file are changed.
+func (c *Config) loadFile(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	if err := yaml.Unmarshal(content, c); err != nil {
+		return fmt.Errorf("failed to decode config file %s: %v", path, err)
+	}
+	return nil
+}
+
+func (c *Config) Validate() error {
+	if c.Server.Address == "" {
+		return fmt.Errorf("server.address must not be empty")
+	}
+	if c.Server.GrpcAddress != "" && c.Server.GrpcAddress == c.Server.Address {
+		return fmt.Errorf("server.grpcAddress must be different from server.address")
+	}
+	switch c.Storage.Backend {
+	case MemoryBackend:
+		if c.Storage.Path != "" {
+			return fmt.Errorf("storage.path is only used by the %s backend", FileBackend)
+		}
+	case FileBackend:
+		if c.Storage.Path == "" {
+			return fmt.Errorf("storage.path is required by the %s backend", FileBackend)
+		}
+	default:
+		return fmt.Errorf("storage.backend must be %s or %s", MemoryBackend, FileBackend)
+	}
+	if c.Paging.DefaultPageSize < 1 {
+		return fmt.Errorf("paging.defaultPageSize must be greater than 0")
+	}
+	if c.Paging.MaxPageSize < c.Paging.DefaultPageSize {
+		return fmt.Errorf("paging.maxPageSize must be greater than or equal to paging.defaultPageSize")
+	}
+	if c.Graphql.MaxDepth < 1 {
+		return fmt.Errorf("graphql.maxDepth must be greater than 0")
+	}
+	if c.Graphql.MaxComplexity < 1 {
+		return fmt.Errorf("graphql.maxComplexity must be greater than 0")
+	}
+	switch c.Tracing.Exporter {
+	case tracing.NoneExporter, tracing.StdoutExporter, tracing.OtlpExporter:
+	case tracing.FileExporter:
+		if c.Tracing.Path == "" {
+			return fmt.Errorf("tracing.path is required by the %s exporter", tracing.FileExporter)
+		}
+	default:
+		return fmt.Errorf("tracing.exporter must be %s, %s, %s or %s",
+			tracing.NoneExporter, tracing.StdoutExporter, tracing.FileExporter, tracing.OtlpExporter)
+	}
+	for _, host := range c.Validation.SourceHosts {
+		if !sourceHostPattern.MatchString(host) {
+			return fmt.Errorf("validation.sourceHosts: %q must be a host name, IE: github.com or *.example.com", host)
+		}
+	}
+	if err := storage.ValidateConstraints(c.Validation.Unique); err != nil {
+		return fmt.Errorf("validation.unique: %v", err)
+	}
+	if err := c.Licenses.Validate(); err != nil {
+		return err
+	}
+	if err := c.LinkCheck.Validate(); err != nil {
+		return err
+	}
+	if err := c.Duplicates.Validate(); err != nil {
+		return err
+	}
+	return c.RateLimitConfig().Validate()
+}
+
+// Returns the rate limit configuration, roles come from limits and keys from auth
+func (c *Config) RateLimitConfig() *ratelimit.Config {
+	return &ratelimit.Config{
+		Roles:   c.Limits,
+		ApiKeys: c.Auth.ApiKeys,
+	}
+}
+
+// Returns the role of the API key
+func (a *AuthConfig) RoleFor(apiKey string) (string, bool) {
+	if apiKey == "" {
+		return "", false
+	}
+	role, ok := a.ApiKeys[apiKey]
+	return role, ok
+}
+
+func (a *AuthConfig) IsAdmin(role string) bool {
+	for _, adminRole := range a.AdminRoles {
+		if role == adminRole {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a copy of the config that is safe to show, secrets are masked
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.Auth.ApiKeys = make(map[string]string, len(c.Auth.ApiKeys))
+	for key, role := range c.Auth.ApiKeys {
+		masked := redact(key)
+		// Keep keys sharing the same prefix apart
+		for i := 2; ; i++ {
+			if _, exists := redacted.Auth.ApiKeys[masked]; !exists {
+				break
+			}
+			masked = fmt.Sprintf("%s (%d)", redact(key), i)
+		}
+		redacted.Auth.ApiKeys[masked] = role
+	}
+	return &redacted
+}
+
+// Only show the first few characters of a secret
+func redact(s string) string {
+	if len(s) <= 4 {
+		return "****"
+	}
+	return s[:4] + "****"
+}
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"APIServerExercise/licensepolicy"
+	"APIServerExercise/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string {
+		return values[key]
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+	assert.Nil(t, err)
+	return path
+}
+
+// region Load
+
+func TestLoad_WithDefaults(t *testing.T) {
+	c, err := Load([]string{}, env(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, Defaults(), c)
+}
+
+func TestLoad_Layering(t *testing.T) {
+	path := writeConfigFile(t, `
+server:
+  address: ":9000"
+  readTimeout: 5s
+paging:
+  defaultPageSize: 20
+  maxPageSize: 50
+limits:
+  reader:
+    read: {rate: 1, burst: 1}
+auth:
+  apiKeys:
+    filekey: reader
+`)
+
+	c, err := Load(
+		[]string{"-config", path, "-maxPageSize", "40"},
+		env(map[string]string{
+			"APISERVER_MAX_PAGE_SIZE":     "30",
+			"APISERVER_DEFAULT_PAGE_SIZE": "15",
+			"APISERVER_API_KEYS":          "envkey=reader",
+		}))
+	assert.Nil(t, err)
+
+	// From the file
+	assert.Equal(t, ":9000", c.Server.Address)
+	assert.Equal(t, 5*time.Second, c.Server.ReadTimeout)
+	assert.Equal(t, "reader", c.Auth.ApiKeys["filekey"])
+	assert.Equal(t, ratelimit.Limit{Rate: 1, Burst: 1}, c.Limits["reader"].Read)
+	// Defaults that were not overridden
+	assert.Equal(t, 30*time.Second, c.Server.WriteTimeout)
+	assert.Contains(t, c.Limits, ratelimit.AnonymousRole)
+	// Environment overrides the file
+	assert.Equal(t, 15, c.Paging.DefaultPageSize)
+	assert.Equal(t, "reader", c.Auth.ApiKeys["envkey"])
+	// Flags override the environment
+	assert.Equal(t, 40, c.Paging.MaxPageSize)
+}
+
+func TestLoad_WithConfigFromEnvironment(t *testing.T) {
+	path := writeConfigFile(t, "index:\n  disableIndexWords: true\n")
+
+	c, err := Load([]string{}, env(map[string]string{"APISERVER_CONFIG": path}))
+	assert.Nil(t, err)
+	assert.True(t, c.Index.DisableIndexWords)
+}
+
+func TestLoad_WithLicensePolicy(t *testing.T) {
+	path := writeConfigFile(t, `
+licenses:
+  allow: [MIT, Apache-2.0]
+  deny: [AGPL-3.0-only]
+  unlisted: review
+`)
+
+	c, err := Load([]string{"-config", path}, env(map[string]string{"APISERVER_LICENSE_ENFORCEMENT": "reject"}))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"MIT", "Apache-2.0"}, c.Licenses.Allow)
+	assert.Equal(t, []string{"AGPL-3.0-only"}, c.Licenses.Deny)
+	assert.Equal(t, licensepolicy.Review, c.Licenses.Unlisted)
+	assert.Equal(t, licensepolicy.Reject, c.Licenses.Enforcement)
+}
+
+func TestLoad_WithSourceHosts(t *testing.T) {
+	c, err := Load([]string{"-sourceHosts", "github.com, *.example.com"}, env(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"github.com", "*.example.com"}, c.Validation.SourceHosts)
+
+	_, err = Load([]string{"-sourceHosts", "https://github.com"}, env(nil))
+	assert.Error(t, err)
+	assert.Equal(t, `validation.sourceHosts: "https://github.com" must be a host name, IE: github.com or *.example.com`, err.Error())
+}
+
+func TestLoad_WithLinkCheck(t *testing.T) {
+	path := writeConfigFile(t, `
+linkCheck:
+  interval: 1h
+  hostDelay: 2s
+`)
+
+	c, err := Load([]string{"-config", path, "-linkCheckConcurrency", "8"}, env(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, time.Hour, c.LinkCheck.Interval)
+	assert.Equal(t, 2*time.Second, c.LinkCheck.HostDelay)
+	assert.Equal(t, 8, c.LinkCheck.Concurrency)
+	assert.Equal(t, 10*time.Second, c.LinkCheck.Timeout)
+
+	_, err = Load([]string{"-linkCheckConcurrency", "0"}, env(nil))
+	assert.Equal(t, "linkCheck.concurrency must be greater than 0", err.Error())
+}
+
+func TestLoad_WithDuplicates(t *testing.T) {
+	path := writeConfigFile(t, `
+duplicates:
+  interval: 30m
+`)
+
+	c, err := Load([]string{"-config", path, "-duplicateThreshold", "0.75"}, env(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Minute, c.Duplicates.Interval)
+	assert.Equal(t, 0.75, c.Duplicates.Threshold)
+
+	_, err = Load([]string{"-duplicateThreshold", "2"}, env(nil))
+	assert.Equal(t, "duplicates.threshold must be greater than 0 and at most 1", err.Error())
+}
+
+func TestLoad_WithUniqueConstraints(t *testing.T) {
+	c, err := Load([]string{}, env(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"title", "version"}}, c.Validation.Unique)
+
+	c, err = Load([]string{"-uniqueConstraints", "title+version, source"}, env(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"title", "version"}, {"source"}}, c.Validation.Unique)
+
+	c, err = Load([]string{"-uniqueConstraints", ""}, env(nil))
+	assert.Nil(t, err)
+	assert.Empty(t, c.Validation.Unique)
+
+	_, err = Load([]string{"-uniqueConstraints", "title+maintainers"}, env(nil))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `validation.unique: unknown field "maintainers" in unique constraint`)
+	}
+}
+
+func TestLoad_WithDataFile(t *testing.T) {
+	c, err := Load([]string{"-dataFile", "/tmp/data.yaml", "-disableIndexWords"}, env(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, FileBackend, c.Storage.Backend)
+	assert.Equal(t, "/tmp/data.yaml", c.Storage.Path)
+	assert.True(t, c.Index.DisableIndexWords)
+}
+
+func TestLoad_WithInvalidValue(t *testing.T) {
+	_, err := Load([]string{}, env(map[string]string{"APISERVER_READ_TIMEOUT": "soon"}))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid APISERVER_READ_TIMEOUT")
+
+	_, err = Load([]string{"-maxPageSize", "many"}, env(nil))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid -maxPageSize")
+}
+
+func TestLoad_WithInvalidConfig(t *testing.T) {
+	_, err := Load([]string{"-storage", "file"}, env(nil))
+	assert.Error(t, err)
+	assert.Equal(t, "storage.path is required by the file backend", err.Error())
+
+	_, err = Load([]string{"-defaultPageSize", "20", "-maxPageSize", "10"}, env(nil))
+	assert.Error(t, err)
+	assert.Equal(t, "paging.maxPageSize must be greater than or equal to paging.defaultPageSize", err.Error())
+
+	_, err = Load([]string{"-grpcAddress", ":8080"}, env(nil))
+	assert.Error(t, err)
+	assert.Equal(t, "server.grpcAddress must be different from server.address", err.Error())
+
+	_, err = Load([]string{"-graphqlMaxComplexity", "0"}, env(nil))
+	assert.Error(t, err)
+	assert.Equal(t, "graphql.maxComplexity must be greater than 0", err.Error())
+
+	_, err = Load([]string{"-licenseEnforcement", "block"}, env(nil))
+	assert.Error(t, err)
+	assert.Equal(t, "licenses.enforcement must be reject or warn", err.Error())
+
+	_, err = Load([]string{"-apiKeys", "key=missing"}, env(nil))
+	assert.Error(t, err)
+	assert.Equal(t, "api key **** references unknown role missing", err.Error())
+}
+
+// endregion
+
+func TestSetting_Env(t *testing.T) {
+	assert.Equal(t, "APISERVER_DISABLE_INDEX_WORDS", setting{flag: "disableIndexWords"}.env())
+	assert.Equal(t, "APISERVER_ADDRESS", setting{flag: "address"}.env())
+	assert.Equal(t, "APISERVER_GRPC_ADDRESS", setting{flag: "grpcAddress"}.env())
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	c := Defaults()
+	c.Auth.ApiKeys["supersecret"] = "admin"
+
+	redacted := c.Redacted()
+	assert.Equal(t, map[string]string{"supe****": "admin"}, redacted.Auth.ApiKeys)
+	// Original is untouched
+	assert.Equal(t, "admin", c.Auth.ApiKeys["supersecret"])
+}
+
+// region HandleConfig
+
+func TestConfig_HandleConfig(t *testing.T) {
+	c := Defaults()
+	c.Limits["admin"] = ratelimit.RoleLimits{}
+	c.Auth.ApiKeys["adminkey"] = "admin"
+
+	request := httptest.NewRequest(http.MethodGet, "/config", nil)
+	request.Header.Set(ratelimit.ApiKeyHeader, "adminkey")
+	responseRecorder := httptest.NewRecorder()
+	c.HandleConfig(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.NotContains(t, responseRecorder.Body.String(), "adminkey")
+
+	var actual Config
+	err := yaml.Unmarshal(responseRecorder.Body.Bytes(), &actual)
+	assert.Nil(t, err)
+	assert.Equal(t, c.Server, actual.Server)
+	assert.Equal(t, "admin", actual.Auth.ApiKeys["admi****"])
+}
+
+func TestConfig_HandleConfig_WithoutApiKey(t *testing.T) {
+	c := Defaults()
+
+	responseRecorder := httptest.NewRecorder()
+	c.HandleConfig(responseRecorder, httptest.NewRequest(http.MethodGet, "/config", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+}
+
+func TestConfig_HandleConfig_WithoutAdminRole(t *testing.T) {
+	c := Defaults()
+	c.Limits["reader"] = ratelimit.RoleLimits{}
+	c.Auth.ApiKeys["readerkey"] = "reader"
+
+	request := httptest.NewRequest(http.MethodGet, "/config", nil)
+	request.Header.Set(ratelimit.ApiKeyHeader, "readerkey")
+	responseRecorder := httptest.NewRecorder()
+	c.HandleConfig(responseRecorder, request)
+
+	assert.Equal(t, http.StatusForbidden, responseRecorder.Code)
+}
+
+// endregion
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"APIServerExercise/ratelimit"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net/http"
+)
+
+// GET /config
+// Returns the effective configuration with secrets masked, only for admin roles
+func (c *Config) HandleConfig(w http.ResponseWriter, req *http.Request) {
+	if !c.Auth.RequireAdmin(w, req, "view the configuration") {
+		return
+	}
+
+	r, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf("Error marshalling config: Error: %v", err.Error())))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(r)
+}
+
+// Whether the API key of the request belongs to an admin role
+// Otherwise writes a 401 or 403 response naming the action, IE: view the configuration
+func (a *AuthConfig) RequireAdmin(w http.ResponseWriter, req *http.Request, action string) bool {
+	role, ok := a.RoleFor(req.Header.Get(ratelimit.ApiKeyHeader))
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(fmt.Sprintf("A valid API key is required in the %s header\n", ratelimit.ApiKeyHeader)))
+		return false
+	}
+	if !a.IsAdmin(role) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(fmt.Sprintf("Role %s is not allowed to %s\n", role, action)))
+		return false
+	}
+	return true
+}
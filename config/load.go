@@ -0,0 +1,276 @@
+package config
+
+import (
+	"APIServerExercise/tracing"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	envPrefix  = "APISERVER_"
+	configFlag = "config"
+)
+
+// A single value that can be set from an environment variable and a flag
+type setting struct {
+	flag   string
+	usage  string
+	isBool bool
+	set    func(c *Config, value string) error
+}
+
+// Environment variable name of the setting, IE: maxPageSize -> APISERVER_MAX_PAGE_SIZE
+func (s setting) env() string {
+	var b strings.Builder
+	b.WriteString(envPrefix)
+	for i, r := range s.flag {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+	}
+	return strings.ToUpper(b.String())
+}
+
+var settings = []setting{
+	{flag: "address", usage: "Address to listen on", set: func(c *Config, v string) error {
+		c.Server.Address = v
+		return nil
+	}},
+	{flag: "grpcAddress", usage: "Address the gRPC API listens on, disabled when empty", set: func(c *Config, v string) error {
+		c.Server.GrpcAddress = v
+		return nil
+	}},
+	{flag: "readTimeout", usage: "Maximum duration for reading an entire request", set: durationSetter(func(c *Config) *time.Duration {
+		return &c.Server.ReadTimeout
+	})},
+	{flag: "writeTimeout", usage: "Maximum duration before timing out writes of the response", set: durationSetter(func(c *Config) *time.Duration {
+		return &c.Server.WriteTimeout
+	})},
+	{flag: "idleTimeout", usage: "Maximum duration to wait for the next request on a keep-alive connection", set: durationSetter(func(c *Config) *time.Duration {
+		return &c.Server.IdleTimeout
+	})},
+	{flag: "shutdownTimeout", usage: "Maximum duration to wait for in-flight requests to finish on shutdown", set: durationSetter(func(c *Config) *time.Duration {
+		return &c.Server.ShutdownTimeout
+	})},
+	{flag: "storage", usage: "Storage backend, memory or file", set: func(c *Config, v string) error {
+		c.Storage.Backend = v
+		return nil
+	}},
+	{flag: "dataFile", usage: "Path to the YAML file used by the file storage backend. Selects the file backend", set: func(c *Config, v string) error {
+		c.Storage.Path = v
+		if v != "" {
+			c.Storage.Backend = FileBackend
+		}
+		return nil
+	}},
+	{flag: "disableIndexWords", isBool: true, usage: "Disable indexing part of values. IE: Do not index each word in description field", set: boolSetter(func(c *Config) *bool {
+		return &c.Index.DisableIndexWords
+	})},
+	{flag: "defaultPageSize", usage: "Page size used when the request does not specify one", set: intSetter(func(c *Config) *int {
+		return &c.Paging.DefaultPageSize
+	})},
+	{flag: "maxPageSize", usage: "Largest page size a request can ask for", set: intSetter(func(c *Config) *int {
+		return &c.Paging.MaxPageSize
+	})},
+	{flag: "graphqlMaxDepth", usage: "Deepest nesting of selections a GraphQL query can have", set: intSetter(func(c *Config) *int {
+		return &c.Graphql.MaxDepth
+	})},
+	{flag: "graphqlMaxComplexity", usage: "Highest estimated number of fields a GraphQL query can resolve", set: intSetter(func(c *Config) *int {
+		return &c.Graphql.MaxComplexity
+	})},
+	{flag: "traceExporter", usage: "Where spans are exported to, none, stdout, file or otlp", set: func(c *Config, v string) error {
+		c.Tracing.Exporter = v
+		return nil
+	}},
+	{flag: "traceFile", usage: "File spans are appended to by the file exporter. Selects the file exporter", set: func(c *Config, v string) error {
+		c.Tracing.Path = v
+		if v != "" {
+			c.Tracing.Exporter = tracing.FileExporter
+		}
+		return nil
+	}},
+	{flag: "traceEndpoint", usage: "OTLP/HTTP endpoint spans are sent to, IE: http://localhost:4318. Selects the otlp exporter", set: func(c *Config, v string) error {
+		c.Tracing.Endpoint = v
+		if v != "" {
+			c.Tracing.Exporter = tracing.OtlpExporter
+		}
+		return nil
+	}},
+	{flag: "sourceHosts", usage: "Comma separated hosts the source URL can be on, IE: github.com,*.example.com. Any host when empty", set: func(c *Config, v string) error {
+		c.Validation.SourceHosts = nil
+		for _, host := range strings.Split(v, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				c.Validation.SourceHosts = append(c.Validation.SourceHosts, host)
+			}
+		}
+		return nil
+	}},
+	{flag: "uniqueConstraints", usage: "Comma separated unique constraints, fields joined by +, IE: title+version,source. None when empty", set: func(c *Config, v string) error {
+		c.Validation.Unique = nil
+		for _, constraint := range strings.Split(v, ",") {
+			if constraint = strings.TrimSpace(constraint); constraint == "" {
+				continue
+			}
+			var fields []string
+			for _, field := range strings.Split(constraint, "+") {
+				fields = append(fields, strings.TrimSpace(field))
+			}
+			c.Validation.Unique = append(c.Validation.Unique, fields)
+		}
+		return nil
+	}},
+	{flag: "licenseEnforcement", usage: "What saving metadata with a denied license does, reject or warn", set: func(c *Config, v string) error {
+		c.Licenses.Enforcement = v
+		return nil
+	}},
+	{flag: "linkCheckInterval", usage: "Time between two checks of the website and source URLs, disabled when 0", set: durationSetter(func(c *Config) *time.Duration {
+		return &c.LinkCheck.Interval
+	})},
+	{flag: "linkCheckConcurrency", usage: "Largest number of link check requests in flight", set: intSetter(func(c *Config) *int {
+		return &c.LinkCheck.Concurrency
+	})},
+	{flag: "duplicateInterval", usage: "Time between two detections of duplicate metadata, on every request when 0", set: durationSetter(func(c *Config) *time.Duration {
+		return &c.Duplicates.Interval
+	})},
+	{flag: "duplicateThreshold", usage: "Lowest score, between 0 and 1, of a pair of duplicate candidates", set: floatSetter(func(c *Config) *float64 {
+		return &c.Duplicates.Threshold
+	})},
+	{flag: "apiKeys", usage: "Comma separated list of key=role API keys, added to the keys in the config file", set: func(c *Config, v string) error {
+		for _, pair := range strings.Split(v, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return fmt.Errorf("api keys must be formatted as key=role")
+			}
+			c.Auth.ApiKeys[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+		return nil
+	}},
+}
+
+// Flag value that only records the raw value
+// Flags are applied last, after the config file and environment variables have been read.
+type rawValue struct {
+	value   string
+	isBool  bool
+	present bool
+}
+
+func (r *rawValue) String() string { return r.value }
+func (r *rawValue) Set(v string) error {
+	r.value = v
+	r.present = true
+	return nil
+}
+func (r *rawValue) IsBoolFlag() bool { return r.isBool }
+
+// Build the configuration from the defaults, the config file, environment variables and args
+// The config file is selected with -config or APISERVER_CONFIG.
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	flagSet := flag.NewFlagSet("APIServerExercise", flag.ContinueOnError)
+	flagSet.SetOutput(ioutil.Discard)
+
+	configPath := flagSet.String(configFlag, "", "Path to a YAML config file")
+	values := make([]*rawValue, len(settings))
+	for i, s := range settings {
+		values[i] = &rawValue{isBool: s.isBool}
+		flagSet.Var(values[i], s.flag, fmt.Sprintf("%s (env %s)", s.usage, s.env()))
+	}
+	if err := flagSet.Parse(args); err != nil {
+		return nil, err
+	}
+
+	c := Defaults()
+
+	path := *configPath
+	if path == "" {
+		path = getenv(envPrefix + "CONFIG")
+	}
+	if path != "" {
+		if err := c.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if v := getenv(s.env()); v != "" {
+			if err := s.set(c, v); err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", s.env(), err)
+			}
+		}
+	}
+
+	for i, s := range settings {
+		if values[i].present {
+			if err := s.set(c, values[i].value); err != nil {
+				return nil, fmt.Errorf("invalid -%s: %v", s.flag, err)
+			}
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Usage of every flag, for -help
+func Usage() string {
+	var b strings.Builder
+	b.WriteString("  -config string\n    \tPath to a YAML config file (env APISERVER_CONFIG)\n")
+	for _, s := range settings {
+		b.WriteString(fmt.Sprintf("  -%s\n    \t%s (env %s)\n", s.flag, s.usage, s.env()))
+	}
+	return b.String()
+}
+
+func durationSetter(field func(c *Config) *time.Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+func boolSetter(field func(c *Config) *bool) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func intSetter(field func(c *Config) *int) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = i
+		return nil
+	}
+}
+
+func floatSetter(field func(c *Config) *float64) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = f
+		return nil
+	}
+}
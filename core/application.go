@@ -0,0 +1,29 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Longest application slug, the same as a label value
+const maxSlugLength = 63
+
+var (
+	slugPattern    = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Lower case alphanumeric characters and dashes, starting and ending alphanumeric, IE: valid-app-1
+func IsSlug(slug string) bool {
+	return len(slug) <= maxSlugLength && slugPattern.MatchString(slug)
+}
+
+// Returns the slug of a title, IE: Valid App 1 -> valid-app-1
+// Runs of other characters than letters and digits become a dash.
+func Slug(title string) string {
+	slug := strings.Trim(slugSeparators.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	return slug
+}
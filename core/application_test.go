@@ -0,0 +1,29 @@
+package core
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestSlug(t *testing.T) {
+	for title, expected := range map[string]string{
+		"Valid App 1":                  "valid-app-1",
+		"  My_App: The (Return)":       "my-app-the-return",
+		"Ünïcode":                      "n-code",
+		strings.Repeat("a", 62) + " b": strings.Repeat("a", 62),
+	} {
+		slug := Slug(title)
+		assert.Equal(t, expected, slug, title)
+		assert.True(t, IsSlug(slug), slug)
+	}
+}
+
+func TestIsSlug(t *testing.T) {
+	for _, slug := range []string{"app", "valid-app-1", "1"} {
+		assert.True(t, IsSlug(slug), slug)
+	}
+	for _, slug := range []string{"", "App", "-app", "app-", "my_app", strings.Repeat("a", 64)} {
+		assert.False(t, IsSlug(slug), slug)
+	}
+}
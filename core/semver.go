@@ -0,0 +1,107 @@
+package core
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Semantic version 2.0.0 with an optional v prefix, IE: 1.2.3, v1.2.3-rc.1+build.5
+var semverPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+[0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*)?$`)
+
+type Semver struct {
+	Major, Minor, Patch uint64
+	// Dot separated identifiers after the -, empty for a release
+	Prerelease []string
+}
+
+// Parses a semantic version, the build metadata is ignored
+func ParseSemver(version string) (Semver, bool) {
+	match := semverPattern.FindStringSubmatch(version)
+	if match == nil {
+		return Semver{}, false
+	}
+	var v Semver
+	var err error
+	for i, number := range []*uint64{&v.Major, &v.Minor, &v.Patch} {
+		if *number, err = strconv.ParseUint(match[i+1], 10, 64); err != nil {
+			return Semver{}, false
+		}
+	}
+	if match[4] != "" {
+		v.Prerelease = strings.Split(match[4], ".")
+	}
+	return v, true
+}
+
+// Whether the version is a pre-release, IE: 1.0.0-rc.1
+func (v Semver) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// Returns -1, 0 or 1 when the version has a lower, the same or a higher precedence than the other
+// A pre-release has a lower precedence than its release, IE: 1.0.0-alpha < 1.0.0-alpha.1 < 1.0.0-beta < 1.0.0.
+func (v Semver) Compare(other Semver) int {
+	for _, pair := range [][2]uint64{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if pair[0] != pair[1] {
+			return compareUint(pair[0], pair[1])
+		}
+	}
+	switch {
+	case !v.IsPrerelease() && !other.IsPrerelease():
+		return 0
+	case !v.IsPrerelease():
+		return 1
+	case !other.IsPrerelease():
+		return -1
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(other.Prerelease); i++ {
+		if c := compareIdentifiers(v.Prerelease[i], other.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(v.Prerelease)), uint64(len(other.Prerelease)))
+}
+
+// Numeric identifiers are compared as numbers and are lower than alphanumeric ones
+func compareIdentifiers(a string, b string) int {
+	aNumber, aErr := strconv.ParseUint(a, 10, 64)
+	bNumber, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(aNumber, bNumber)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareUint(a uint64, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Orders two versions by semantic version precedence
+// Versions that are not semantic versions are lower than those that are and are compared as strings.
+func CompareVersions(a string, b string) int {
+	aVersion, aOk := ParseSemver(a)
+	bVersion, bOk := ParseSemver(b)
+	switch {
+	case aOk && bOk:
+		return aVersion.Compare(bVersion)
+	case aOk:
+		return 1
+	case bOk:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseSemver(t *testing.T) {
+	version, ok := ParseSemver("v1.2.3-rc.1+build.5")
+	assert.True(t, ok)
+	assert.Equal(t, Semver{Major: 1, Minor: 2, Patch: 3, Prerelease: []string{"rc", "1"}}, version)
+	assert.True(t, version.IsPrerelease())
+
+	for _, invalid := range []string{"", "1", "1.2", "01.2.3", "1.2.3-", "1.2.3-01", "latest", "1.2.3.4"} {
+		_, ok := ParseSemver(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	// In increasing precedence
+	ordered := []string{
+		"not a version",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.2.0",
+		"1.10.0",
+		"2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			assert.Equal(t, expected, CompareVersions(ordered[i], ordered[j]), "%s <=> %s", ordered[i], ordered[j])
+		}
+	}
+	assert.Equal(t, 0, CompareVersions("v1.0.0", "1.0.0+build.1"))
+}
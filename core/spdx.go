@@ -0,0 +1,232 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	LicenseAnd  = "AND"
+	LicenseOr   = "OR"
+	licenseWith = "WITH"
+)
+
+var (
+	// Lower case identifier -> canonical identifier
+	canonicalLicenses   = canonicalIds(spdxLicenseIds)
+	canonicalExceptions = canonicalIds(spdxExceptionIds)
+
+	// User defined licenses, IE: LicenseRef-internal or DocumentRef-spdx-tool:LicenseRef-mit-style
+	licenseRefPattern = regexp.MustCompile(`^(DocumentRef-[A-Za-z0-9.-]+:)?LicenseRef-[A-Za-z0-9.-]+$`)
+)
+
+func canonicalIds(ids []string) map[string]string {
+	canonical := make(map[string]string, len(ids))
+	for _, id := range ids {
+		canonical[strings.ToLower(id)] = id
+	}
+	return canonical
+}
+
+// A parsed SPDX license expression, IE: (MIT OR Apache-2.0) AND BSD-3-Clause
+// A single license has an identifier, a compound expression an operator and its operands.
+type LicenseExpression struct {
+	// AND or OR, empty for a single license
+	Operator string
+	Operands []*LicenseExpression
+	// Canonical identifier, IE: Apache-2.0, or a LicenseRef
+	License string
+	// The license or any later version, IE: GPL-2.0+
+	OrLater bool
+	// Canonical identifier of the exception, IE: Classpath-exception-2.0
+	Exception string
+}
+
+// Parses an SPDX license expression against the SPDX license list
+// Identifiers and operators are matched case insensitively, AND takes precedence over OR.
+func ParseLicenseExpression(expression string) (*LicenseExpression, error) {
+	p := &licenseParser{tokens: tokenizeLicense(expression)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("invalid license expression %q: empty expression", expression)
+	}
+	parsed, err := p.or()
+	if err == nil && p.position < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.position])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid license expression %q: %v", expression, err)
+	}
+	return parsed, nil
+}
+
+// Whether the expression is a valid SPDX license expression
+func IsLicenseExpression(expression string) bool {
+	_, err := ParseLicenseExpression(expression)
+	return err == nil
+}
+
+// Returns the canonical form of the expression, IE: apache-2.0 or mit -> Apache-2.0 OR MIT
+// Invalid expressions are returned unchanged.
+func NormalizeLicense(expression string) string {
+	parsed, err := ParseLicenseExpression(expression)
+	if err != nil {
+		return expression
+	}
+	return parsed.String()
+}
+
+// Canonical form of the expression, parentheses are only kept where they change the meaning
+func (e *LicenseExpression) String() string {
+	if e.Operator == "" {
+		s := e.License
+		if e.OrLater {
+			s += "+"
+		}
+		if e.Exception != "" {
+			s += " " + licenseWith + " " + e.Exception
+		}
+		return s
+	}
+
+	operands := make([]string, 0, len(e.Operands))
+	for _, operand := range e.Operands {
+		s := operand.String()
+		// AND takes precedence over OR
+		if e.Operator == LicenseAnd && operand.Operator == LicenseOr {
+			s = "(" + s + ")"
+		}
+		operands = append(operands, s)
+	}
+	return strings.Join(operands, " "+e.Operator+" ")
+}
+
+// Returns the identifiers of the licenses in the expression, in order and without duplicates
+// The + of later versions and the exceptions are left out, IE: GPL-2.0+ WITH Classpath-exception-2.0 -> GPL-2.0
+func (e *LicenseExpression) Licenses() []string {
+	var licenses []string
+	seen := map[string]bool{}
+	var walk func(e *LicenseExpression)
+	walk = func(e *LicenseExpression) {
+		if e.Operator == "" {
+			if !seen[e.License] {
+				seen[e.License] = true
+				licenses = append(licenses, e.License)
+			}
+			return
+		}
+		for _, operand := range e.Operands {
+			walk(operand)
+		}
+	}
+	walk(e)
+	return licenses
+}
+
+// Splits on white space, parentheses are tokens of their own
+func tokenizeLicense(expression string) []string {
+	expression = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression)
+	return strings.Fields(expression)
+}
+
+// Recursive descent parser, one method per precedence level
+type licenseParser struct {
+	tokens   []string
+	position int
+}
+
+func (p *licenseParser) peek() string {
+	if p.position < len(p.tokens) {
+		return p.tokens[p.position]
+	}
+	return ""
+}
+
+func (p *licenseParser) next() string {
+	token := p.peek()
+	p.position++
+	return token
+}
+
+func (p *licenseParser) isOperator(operator string) bool {
+	return strings.EqualFold(p.peek(), operator)
+}
+
+// or := and (OR and)*
+func (p *licenseParser) or() (*LicenseExpression, error) {
+	return p.compound(LicenseOr, p.and)
+}
+
+// and := with (AND with)*
+func (p *licenseParser) and() (*LicenseExpression, error) {
+	return p.compound(LicenseAnd, p.with)
+}
+
+func (p *licenseParser) compound(operator string, operand func() (*LicenseExpression, error)) (*LicenseExpression, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	compound := &LicenseExpression{Operator: operator, Operands: []*LicenseExpression{first}}
+	for p.isOperator(operator) {
+		p.next()
+		next, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		compound.Operands = append(compound.Operands, next)
+	}
+	if len(compound.Operands) == 1 {
+		return first, nil
+	}
+	return compound, nil
+}
+
+// with := license (WITH exception)? | ( or )
+func (p *licenseParser) with() (*LicenseExpression, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("expected a license at the end")
+	case token == "(":
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing %q", ")")
+		}
+		return inner, nil
+	case token == ")" || strings.EqualFold(token, LicenseAnd) || strings.EqualFold(token, LicenseOr) ||
+		strings.EqualFold(token, licenseWith):
+		return nil, fmt.Errorf("expected a license before %q", token)
+	}
+
+	license := &LicenseExpression{}
+	id := token
+	if strings.HasSuffix(id, "+") {
+		license.OrLater = true
+		id = strings.TrimSuffix(id, "+")
+	}
+	if canonical, ok := canonicalLicenses[strings.ToLower(id)]; ok {
+		license.License = canonical
+	} else if licenseRefPattern.MatchString(id) && !license.OrLater {
+		license.License = id
+	} else {
+		return nil, fmt.Errorf("unknown license %q", token)
+	}
+
+	if p.isOperator(licenseWith) {
+		p.next()
+		exception := p.next()
+		canonical, ok := canonicalExceptions[strings.ToLower(exception)]
+		if !ok {
+			if exception == "" {
+				return nil, fmt.Errorf("expected an exception at the end")
+			}
+			return nil, fmt.Errorf("unknown exception %q", exception)
+		}
+		license.Exception = canonical
+	}
+	return license, nil
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseLicenseExpression(t *testing.T) {
+	for expression, expected := range map[string]string{
+		"apache-2.0":                                "Apache-2.0",
+		"MIT or Apache-2.0":                         "MIT OR Apache-2.0",
+		"(mit AND bsd-3-clause) or gpl-2.0+":        "MIT AND BSD-3-Clause OR GPL-2.0+",
+		"MIT AND (Apache-2.0 OR BSD-3-Clause)":      "MIT AND (Apache-2.0 OR BSD-3-Clause)",
+		"((MIT))":                                   "MIT",
+		"GPL-2.0-only with classpath-exception-2.0": "GPL-2.0-only WITH Classpath-exception-2.0",
+		"LicenseRef-internal OR DocumentRef-spdx-tool:LicenseRef-mit-style": "LicenseRef-internal OR DocumentRef-spdx-tool:LicenseRef-mit-style",
+	} {
+		parsed, err := ParseLicenseExpression(expression)
+		if assert.Nil(t, err, expression) {
+			assert.Equal(t, expected, parsed.String(), expression)
+		}
+	}
+}
+
+func TestParseLicenseExpression_Invalid(t *testing.T) {
+	for expression, expected := range map[string]string{
+		"":                     `invalid license expression "": empty expression`,
+		"Apache 2":             `invalid license expression "Apache 2": unknown license "Apache"`,
+		"Apache-5.0":           `invalid license expression "Apache-5.0": unknown license "Apache-5.0"`,
+		"MIT OR":               `invalid license expression "MIT OR": expected a license at the end`,
+		"MIT Apache-2.0":       `invalid license expression "MIT Apache-2.0": unexpected "Apache-2.0"`,
+		"(MIT OR Apache-2.0":   `invalid license expression "(MIT OR Apache-2.0": missing ")"`,
+		"AND MIT":              `invalid license expression "AND MIT": expected a license before "AND"`,
+		"MIT WITH foo":         `invalid license expression "MIT WITH foo": unknown exception "foo"`,
+		"LicenseRef-internal+": `invalid license expression "LicenseRef-internal+": unknown license "LicenseRef-internal+"`,
+	} {
+		_, err := ParseLicenseExpression(expression)
+		if assert.Error(t, err, expression) {
+			assert.Equal(t, expected, err.Error())
+		}
+	}
+}
+
+func TestLicenseExpression_Licenses(t *testing.T) {
+	parsed, err := ParseLicenseExpression("(MIT OR GPL-2.0+ WITH Classpath-exception-2.0) AND MIT")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"MIT", "GPL-2.0"}, parsed.Licenses())
+}
+
+func TestNormalizeLicense(t *testing.T) {
+	assert.Equal(t, "Apache-2.0 OR MIT", NormalizeLicense("apache-2.0 or mit"))
+	// Invalid expressions are kept as they are
+	assert.Equal(t, "Apache 2", NormalizeLicense("Apache 2"))
+}
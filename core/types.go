@@ -3,6 +3,7 @@ package core
 import (
 	"APIServerExercise/util"
 	"github.com/google/uuid"
+	"time"
 )
 
 type Database struct {
@@ -11,15 +12,86 @@ type Database struct {
 }
 
 type Metadata struct {
-	Id          uuid.UUID     `yaml:"id"`
-	Title       string        `yaml:"title" validate:"required"`
-	Version     string        `yaml:"version" validate:"required"`
+	Id      uuid.UUID `yaml:"id"`
+	Title   string    `yaml:"title" validate:"required"`
+	Version string    `yaml:"version" validate:"required"`
+	// Slug of the application the metadata is a release of, defaults to the slug of the title, IE: valid-app-1
+	Application string        `yaml:"application,omitempty" validate:"omitempty,slug"`
 	Maintainers []*Maintainer `yaml:"maintainers" validate:"required,gt=0,dive"`
 	Company     string        `yaml:"company" validate:"required"`
-	Website     util.Yamlurl  `yaml:"website" validate:"required"`
-	Source      util.Yamlurl  `yaml:"source" validate:"required"`
-	License     string        `yaml:"license" validate:"required"`
-	Description string        `yaml:"description" validate:"required"`
+	// Absolute http or https URLs, also indexed by component, IE: source.host or source.owner
+	Website util.Yamlurl `yaml:"website" validate:"required" index:"url"`
+	Source  util.Yamlurl `yaml:"source" validate:"required" index:"url"`
+	// SPDX license expression, saved in its canonical form, IE: Apache-2.0 OR MIT
+	License     string `yaml:"license" validate:"required,spdx" index:"spdx"`
+	Description string `yaml:"description" validate:"required"`
+	// Identifying key/value pairs, indexed and matched by label selectors, IE: tier: critical
+	Labels map[string]string `yaml:"labels,omitempty" validate:"omitempty,dive,keys,labelkey,endkeys,labelvalue"`
+	// Non identifying key/value pairs for tools and people, not indexed
+	Annotations map[string]string `yaml:"annotations,omitempty" validate:"omitempty,annotationsize,dive,keys,labelkey,endkeys" index:"-"`
+	// Other metadata this one depends on, IE: a library or a service it calls
+	Dependencies []*Dependency `yaml:"dependencies,omitempty" validate:"omitempty,dive,required"`
+	// Lifecycle state, draft, published, deprecated or retired, keeps the state of the metadata it replaces or is published
+	Lifecycle string `yaml:"lifecycle,omitempty" validate:"omitempty,oneof=draft published deprecated retired"`
+	// Why the metadata is deprecated, required for a deprecated or retired metadata
+	Deprecation *Deprecation `yaml:"deprecation,omitempty"`
+	// Result of the last check of the website and source, set by the link checker and ignored in payloads
+	Links *LinkStatus `yaml:"links,omitempty"`
+	// Metadata merged into this one, directly or not, oldest merge first, set by merges and ignored in payloads
+	Merged []*MergeRecord `yaml:"merged,omitempty" index:"-"`
+}
+
+// Reference to another metadata, by id or by title and version range
+// A reference by title resolves to the newest version in the range, IE: title: Valid App 1, version: ^1.2.0
+type Dependency struct {
+	Id    string `yaml:"id,omitempty" validate:"required_without=Title,excluded_with=Title,omitempty,uuid"`
+	Title string `yaml:"title,omitempty" validate:"required_without=Id"`
+	// Semantic version range, any version when empty, IE: >=1.2.0 <2.0.0, ^1.2.0 or ~1.2.3
+	Version string `yaml:"version,omitempty" validate:"excluded_with=Id,omitempty,versionrange" index:"-"`
+}
+
+type Deprecation struct {
+	Reason string `yaml:"reason" validate:"required"`
+	// Id of the metadata replacing the deprecated one, optional
+	Replacement string `yaml:"replacement,omitempty" validate:"omitempty,uuid"`
+	// When the metadata is or was retired, sent in the Sunset header, optional
+	Sunset time.Time `yaml:"sunset,omitempty" index:"-"`
+	// When the metadata was deprecated, set when saving and ignored in payloads
+	DeprecatedAt time.Time `yaml:"deprecatedAt" index:"-"`
+}
+
+// A metadata as it was when it was merged into another one
+type MergeRecord struct {
+	Id          uuid.UUID     `yaml:"id"`
+	Title       string        `yaml:"title"`
+	Version     string        `yaml:"version"`
+	Maintainers []*Maintainer `yaml:"maintainers"`
+	Company     string        `yaml:"company"`
+	Website     util.Yamlurl  `yaml:"website"`
+	Source      util.Yamlurl  `yaml:"source"`
+	License     string        `yaml:"license"`
+	Description string        `yaml:"description"`
+	MergedAt    time.Time     `yaml:"mergedAt"`
+}
+
+type LinkStatus struct {
+	// Whether the website or the source is broken
+	Broken  bool       `yaml:"broken"`
+	Website *LinkCheck `yaml:"website"`
+	Source  *LinkCheck `yaml:"source"`
+}
+
+// Result of a request to a URL
+type LinkCheck struct {
+	// URL that was checked
+	URL string `yaml:"url" index:"-"`
+	// Status code of the response, 0 when the request failed
+	Status int  `yaml:"status"`
+	Broken bool `yaml:"broken"`
+	// Final URL after following the redirects, empty when the URL did not redirect
+	RedirectTo string    `yaml:"redirectTo,omitempty" index:"-"`
+	Error      string    `yaml:"error,omitempty" index:"-"`
+	CheckedAt  time.Time `yaml:"checkedAt" index:"-"`
 }
 
 type Maintainer struct {
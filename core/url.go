@@ -0,0 +1,159 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// A website or source URL that is not an absolute http or https URL, or not on an allowed host
+type URLError struct {
+	Field  string
+	Value  string
+	Reason string
+}
+
+func (e *URLError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("%s %s", e.Field, e.Reason)
+	}
+	return fmt.Sprintf("%s %q %s", e.Field, e.Value, e.Reason)
+}
+
+// Checks that the URL is an absolute http or https URL without credentials
+// When hosts is not empty, the host must be one of them. A host starting with *. also matches its subdomains,
+// IE: *.example.com matches git.example.com and example.com.
+func CheckURL(field string, u *url.URL, hosts []string) error {
+	if u == nil || u.String() == "" {
+		return &URLError{Field: field, Reason: "is required"}
+	}
+	fail := func(format string, args ...interface{}) error {
+		return &URLError{Field: field, Value: u.String(), Reason: fmt.Sprintf(format, args...)}
+	}
+	if !u.IsAbs() {
+		return fail("must be an absolute URL, IE: https://example.com")
+	}
+	if _, ok := defaultPorts[strings.ToLower(u.Scheme)]; !ok {
+		return fail("has scheme %q, must be http or https", u.Scheme)
+	}
+	if u.Opaque != "" || u.Hostname() == "" {
+		return fail("must have a host")
+	}
+	if u.User != nil {
+		return fail("must not contain credentials")
+	}
+	if len(hosts) > 0 && !MatchesHost(u.Hostname(), hosts) {
+		return fail("is on host %q, must be on %s", strings.ToLower(u.Hostname()), strings.Join(hosts, ", "))
+	}
+	return nil
+}
+
+// Whether the host is one of the hosts, matched case insensitively
+// A host starting with *. also matches its subdomains.
+func MatchesHost(host string, hosts []string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range hosts {
+		allowed = strings.ToLower(allowed)
+		if domain := strings.TrimPrefix(allowed, "*."); domain != allowed {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a copy of the URL in its canonical form
+// The scheme and host are lower cased, the default port and the trailing slashes of the path are removed,
+// IE: HTTPS://GitHub.com:443/random/repo/ -> https://github.com/random/repo
+func NormalizeURL(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+	normalized := *u
+	normalized.Scheme = strings.ToLower(u.Scheme)
+	normalized.Host = strings.ToLower(u.Host)
+	if port := normalized.Port(); port != "" && port == defaultPorts[normalized.Scheme] {
+		normalized.Host = strings.TrimSuffix(normalized.Host, ":"+port)
+	}
+	normalized.Path = strings.TrimRight(u.Path, "/")
+	normalized.RawPath = strings.TrimRight(u.RawPath, "/")
+	return &normalized
+}
+
+// Checks the website and source of the metadata, the source must be on one of the source hosts when there are some
+func ValidateURLs(metadata *Metadata, sourceHosts []string) error {
+	if err := CheckURL("website", metadata.Website.URL, nil); err != nil {
+		return err
+	}
+	return CheckURL("source", metadata.Source.URL, sourceHosts)
+}
+
+// Parts of a URL that are indexed on their own, IE: source.host=github.com
+const (
+	URLHost  = "host"
+	URLPath  = "path"
+	URLOwner = "owner"
+	URLRepo  = "repo"
+)
+
+// Every URL component, in the order they are listed
+var URLComponentNames = []string{URLHost, URLPath, URLOwner, URLRepo}
+
+// Hosts whose paths start with the owner and the repository, IE: github.com/upbound/repo
+// Nested forges allow groups of groups, the repository is the last segment before a - segment,
+// IE: gitlab.com/group/subgroup/repo/-/tree/main has owner group/subgroup and repo repo.
+var knownForges = map[string]bool{
+	"github.com":    false,
+	"bitbucket.org": false,
+	"codeberg.org":  false,
+	"gitlab.com":    true,
+}
+
+// Returns the values of each component of the URL
+// The host is lower case and without port, the path has a value per segment. The owner and the repository are only
+// known for the URLs of known forges, IE: https://github.com/upbound/repo.git has owner upbound and repo repo.
+func URLComponents(u *url.URL) map[string][]string {
+	components := map[string][]string{}
+	if u == nil || u.Hostname() == "" {
+		return components
+	}
+	host := strings.ToLower(u.Hostname())
+	components[URLHost] = []string{host}
+
+	var segments []string
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) > 0 {
+		components[URLPath] = segments
+	}
+
+	nested, ok := knownForges[host]
+	if !ok || len(segments) < 2 {
+		return components
+	}
+	repository := segments[:2]
+	if nested {
+		repository = segments
+		for i, segment := range segments {
+			if segment == "-" {
+				repository = segments[:i]
+				break
+			}
+		}
+		if len(repository) < 2 {
+			return components
+		}
+	}
+	last := len(repository) - 1
+	components[URLOwner] = []string{strings.Join(repository[:last], "/")}
+	components[URLRepo] = []string{strings.TrimSuffix(repository[last], ".git")}
+	return components
+}
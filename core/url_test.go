@@ -0,0 +1,114 @@
+package core
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	u, err := url.Parse(raw)
+	assert.Nil(t, err)
+	return u
+}
+
+func TestCheckURL(t *testing.T) {
+	for _, raw := range []string{"https://website.com", "http://website.com:8080/docs?lang=en#intro", "HTTPS://GitHub.com/random/repo/"} {
+		assert.Nil(t, CheckURL("website", mustParseURL(t, raw), nil), raw)
+	}
+
+	for raw, expected := range map[string]string{
+		"":                      `website is required`,
+		"website.com":           `website "website.com" must be an absolute URL, IE: https://example.com`,
+		"/docs":                 `website "/docs" must be an absolute URL, IE: https://example.com`,
+		"javascript:alert(1)":   `website "javascript:alert(1)" has scheme "javascript", must be http or https`,
+		"ftp://website.com":     `website "ftp://website.com" has scheme "ftp", must be http or https`,
+		"http:website.com":      `website "http:website.com" must have a host`,
+		"https:///docs":         `website "https:///docs" must have a host`,
+		"https://a:b@site.com/": `website "https://a:b@site.com/" must not contain credentials`,
+	} {
+		err := CheckURL("website", mustParseURL(t, raw), nil)
+		if assert.Error(t, err, raw) {
+			assert.Equal(t, expected, err.Error())
+		}
+	}
+	assert.Equal(t, "website is required", CheckURL("website", nil, nil).Error())
+}
+
+func TestCheckURL_WithHosts(t *testing.T) {
+	hosts := []string{"github.com", "*.example.com"}
+
+	for _, raw := range []string{"https://GitHub.com/random/repo", "https://git.example.com/repo", "https://example.com/repo"} {
+		assert.Nil(t, CheckURL("source", mustParseURL(t, raw), hosts), raw)
+	}
+	err := CheckURL("source", mustParseURL(t, "https://bitbucket.org/random/repo"), hosts)
+	assert.Equal(t, `source "https://bitbucket.org/random/repo" is on host "bitbucket.org", must be on github.com, *.example.com`, err.Error())
+	assert.Error(t, CheckURL("source", mustParseURL(t, "https://notexample.com/repo"), hosts))
+	assert.Error(t, CheckURL("source", mustParseURL(t, "https://github.com.evil.com/repo"), hosts))
+}
+
+func TestNormalizeURL(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"HTTPS://GitHub.com:443/random/repo/": "https://github.com/random/repo",
+		"http://Website.com:80/":              "http://website.com",
+		"http://website.com:8080/docs":        "http://website.com:8080/docs",
+		"https://website.com:80":              "https://website.com:80",
+		"https://website.com/Docs/?lang=en":   "https://website.com/Docs?lang=en",
+		"https://website.com/a%2Fb/":          "https://website.com/a%2Fb",
+	} {
+		assert.Equal(t, expected, NormalizeURL(mustParseURL(t, raw)).String(), raw)
+	}
+	assert.Nil(t, NormalizeURL(nil))
+
+	// The original is untouched
+	u := mustParseURL(t, "https://Website.com/")
+	NormalizeURL(u)
+	assert.Equal(t, "https://Website.com/", u.String())
+}
+
+func TestValidateURLs(t *testing.T) {
+	setupTest()
+	assert.Nil(t, ValidateURLs(&testMetadata, []string{"github.com"}))
+
+	err := ValidateURLs(&testMetadata, []string{"gitlab.com"})
+	assert.Equal(t, `source "https://github.com/random/repo" is on host "github.com", must be on gitlab.com`, err.Error())
+
+	// Only the source is restricted to the hosts
+	testMetadata.Website.URL = mustParseURL(t, "https://website.com")
+	assert.Nil(t, ValidateURLs(&testMetadata, []string{"github.com"}))
+}
+
+func TestURLComponents(t *testing.T) {
+	for raw, expected := range map[string]map[string][]string{
+		"https://Website.com:8443": {URLHost: {"website.com"}},
+		"https://website.com/docs/getting-started": {
+			URLHost: {"website.com"},
+			URLPath: {"docs", "getting-started"},
+		},
+		"https://github.com/upbound/repo.git": {
+			URLHost:  {"github.com"},
+			URLPath:  {"upbound", "repo.git"},
+			URLOwner: {"upbound"},
+			URLRepo:  {"repo"},
+		},
+		"https://github.com/upbound/repo/tree/main": {
+			URLHost:  {"github.com"},
+			URLPath:  {"upbound", "repo", "tree", "main"},
+			URLOwner: {"upbound"},
+			URLRepo:  {"repo"},
+		},
+		"https://gitlab.com/group/subgroup/repo/-/tree/main": {
+			URLHost:  {"gitlab.com"},
+			URLPath:  {"group", "subgroup", "repo", "-", "tree", "main"},
+			URLOwner: {"group/subgroup"},
+			URLRepo:  {"repo"},
+		},
+		"https://github.com/upbound": {
+			URLHost: {"github.com"},
+			URLPath: {"upbound"},
+		},
+	} {
+		assert.Equal(t, expected, URLComponents(mustParseURL(t, raw)), raw)
+	}
+	assert.Empty(t, URLComponents(nil))
+}
@@ -1,8 +1,71 @@
 package core
 
-import "github.com/go-playground/validator/v10"
+import (
+	"APIServerExercise/util"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Largest total size of the keys and values of the annotations of a metadata
+const maxAnnotationsSize = 256 * 1024
+
+var (
+	// Kubernetes label key syntax, an optional DNS subdomain prefix and a name, IE: example.com/tier
+	labelPrefixPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	labelNamePattern   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+)
 
 func ValidateStruct(structToValidate interface{}) error {
 	v := validator.New()
+	// Validate URLs as their string, required would otherwise accept any struct
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if u := field.Interface().(util.Yamlurl); u.URL != nil {
+			return u.String()
+		}
+		return ""
+	}, util.Yamlurl{})
+	v.RegisterValidation("labelkey", func(fl validator.FieldLevel) bool {
+		return IsLabelKey(fl.Field().String())
+	})
+	v.RegisterValidation("labelvalue", func(fl validator.FieldLevel) bool {
+		return IsLabelValue(fl.Field().String())
+	})
+	v.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return IsSlug(fl.Field().String())
+	})
+	v.RegisterValidation("spdx", func(fl validator.FieldLevel) bool {
+		return IsLicenseExpression(fl.Field().String())
+	})
+	v.RegisterValidation("versionrange", func(fl validator.FieldLevel) bool {
+		return IsVersionRange(fl.Field().String())
+	})
+	v.RegisterValidation("annotationsize", func(fl validator.FieldLevel) bool {
+		size := 0
+		iter := fl.Field().MapRange()
+		for iter.Next() {
+			size += len(iter.Key().String()) + len(iter.Value().String())
+		}
+		return size <= maxAnnotationsSize
+	})
 	return v.Struct(structToValidate)
 }
+
+// A name of at most 63 characters, optionally prefixed by a DNS subdomain of at most 253 characters and a slash
+func IsLabelKey(key string) bool {
+	name := key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		prefix := key[:i]
+		if len(prefix) == 0 || len(prefix) > 253 || !labelPrefixPattern.MatchString(prefix) {
+			return false
+		}
+		name = key[i+1:]
+	}
+	return len(name) > 0 && len(name) <= 63 && labelNamePattern.MatchString(name)
+}
+
+// Empty, or at most 63 alphanumeric characters, dashes, underscores and dots, starting and ending alphanumeric
+func IsLabelValue(value string) bool {
+	return value == "" || (len(value) <= 63 && labelNamePattern.MatchString(value))
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -64,3 +65,125 @@ func TestValidateStruct_NotEnoughMaintainers(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, "Key: 'Metadata.Maintainers' Error:Field validation for 'Maintainers' failed on the 'gt' tag", err.Error())
 }
+
+func TestValidateStruct_WithLabelsAndAnnotations(t *testing.T) {
+	setupTest()
+	testMetadata.Labels = map[string]string{"tier": "critical", "example.com/team": "payments", "empty": ""}
+	testMetadata.Annotations = map[string]string{"example.com/notes": "Free text: anything goes"}
+	err := ValidateStruct(testMetadata)
+	assert.Nil(t, err)
+}
+
+func TestValidateStruct_InvalidLabels(t *testing.T) {
+	setupTest()
+	testMetadata.Labels = map[string]string{"tier": "critical value"}
+	err := ValidateStruct(testMetadata)
+	assert.Error(t, err)
+	assert.Equal(t, "Key: 'Metadata.Labels[tier]' Error:Field validation for 'Labels[tier]' failed on the 'labelvalue' tag", err.Error())
+
+	testMetadata.Labels = map[string]string{"-tier": "critical"}
+	err = ValidateStruct(testMetadata)
+	assert.Error(t, err)
+	assert.Equal(t, "Key: 'Metadata.Labels[-tier]' Error:Field validation for 'Labels[-tier]' failed on the 'labelkey' tag", err.Error())
+}
+
+func TestValidateStruct_AnnotationsTooLarge(t *testing.T) {
+	setupTest()
+	testMetadata.Annotations = map[string]string{"notes": strings.Repeat("a", 256*1024)}
+	err := ValidateStruct(testMetadata)
+	assert.Error(t, err)
+	assert.Equal(t, "Key: 'Metadata.Annotations' Error:Field validation for 'Annotations' failed on the 'annotationsize' tag", err.Error())
+}
+
+func TestIsLabelKey(t *testing.T) {
+	assert.True(t, IsLabelKey("tier"))
+	assert.True(t, IsLabelKey("app.kubernetes.io/name"))
+	assert.True(t, IsLabelKey("Tier_1.x"))
+	assert.False(t, IsLabelKey(""))
+	assert.False(t, IsLabelKey("/tier"))
+	assert.False(t, IsLabelKey("Example.com/tier"))
+	assert.False(t, IsLabelKey("tier-"))
+	assert.False(t, IsLabelKey(strings.Repeat("a", 64)))
+}
+
+func TestValidateStruct_MissingURL(t *testing.T) {
+	setupTest()
+	testMetadata.Website.URL = nil
+	err := ValidateStruct(testMetadata)
+	assert.Error(t, err)
+	assert.Equal(t, "Key: 'Metadata.Website' Error:Field validation for 'Website' failed on the 'required' tag", err.Error())
+
+	setupTest()
+	testMetadata.Source.URL = &url.URL{}
+	err = ValidateStruct(testMetadata)
+	assert.Error(t, err)
+	assert.Equal(t, "Key: 'Metadata.Source' Error:Field validation for 'Source' failed on the 'required' tag", err.Error())
+}
+
+func TestValidateStruct_InvalidLicense(t *testing.T) {
+	setupTest()
+	testMetadata.License = "Apache 2"
+	err := ValidateStruct(testMetadata)
+	assert.Error(t, err)
+	assert.Equal(t, "Key: 'Metadata.License' Error:Field validation for 'License' failed on the 'spdx' tag", err.Error())
+
+	testMetadata.License = "apache-2.0 OR mit"
+	assert.Nil(t, ValidateStruct(testMetadata))
+}
+
+func TestValidateStruct_InvalidApplication(t *testing.T) {
+	setupTest()
+	testMetadata.Application = "Valid App"
+	err := ValidateStruct(testMetadata)
+	assert.Error(t, err)
+	assert.Equal(t, "Key: 'Metadata.Application' Error:Field validation for 'Application' failed on the 'slug' tag", err.Error())
+
+	testMetadata.Application = "valid-app"
+	assert.Nil(t, ValidateStruct(testMetadata))
+}
+
+func TestValidateStruct_Lifecycle(t *testing.T) {
+	setupTest()
+	testMetadata.Lifecycle = "archived"
+	err := ValidateStruct(testMetadata)
+	if assert.Error(t, err) {
+		assert.Equal(t, "Key: 'Metadata.Lifecycle' Error:Field validation for 'Lifecycle' failed on the 'oneof' tag", err.Error())
+	}
+
+	testMetadata.Lifecycle = "deprecated"
+	testMetadata.Deprecation = &Deprecation{Replacement: "1234"}
+	err = ValidateStruct(testMetadata)
+	if assert.Error(t, err) {
+		assert.Equal(t, "Key: 'Metadata.Deprecation.Reason' Error:Field validation for 'Reason' failed on the 'required' tag\n"+
+			"Key: 'Metadata.Deprecation.Replacement' Error:Field validation for 'Replacement' failed on the 'uuid' tag", err.Error())
+	}
+
+	testMetadata.Deprecation = &Deprecation{Reason: "Unmaintained"}
+	assert.Nil(t, ValidateStruct(testMetadata))
+}
+
+func TestValidateStruct_InvalidDependencies(t *testing.T) {
+	setupTest()
+	id := uuid.New().String()
+	for _, test := range []struct {
+		dependency *Dependency
+		expected   string
+	}{
+		{&Dependency{}, "Key: 'Metadata.Dependencies[0].Id' Error:Field validation for 'Id' failed on the 'required_without' tag\n" +
+			"Key: 'Metadata.Dependencies[0].Title' Error:Field validation for 'Title' failed on the 'required_without' tag"},
+		{&Dependency{Id: "1234"}, "Key: 'Metadata.Dependencies[0].Id' Error:Field validation for 'Id' failed on the 'uuid' tag"},
+		{&Dependency{Id: id, Title: "Valid App 2"}, "Key: 'Metadata.Dependencies[0].Id' Error:Field validation for 'Id' failed on the 'excluded_with' tag"},
+		{&Dependency{Id: id, Version: "^1.0.0"}, "Key: 'Metadata.Dependencies[0].Version' Error:Field validation for 'Version' failed on the 'excluded_with' tag"},
+		{&Dependency{Title: "Valid App 2", Version: "latest"}, "Key: 'Metadata.Dependencies[0].Version' Error:Field validation for 'Version' failed on the 'versionrange' tag"},
+		{nil, "Key: 'Metadata.Dependencies[0]' Error:Field validation for 'Dependencies[0]' failed on the 'required' tag"},
+	} {
+		testMetadata.Dependencies = []*Dependency{test.dependency}
+		err := ValidateStruct(testMetadata)
+		if assert.Error(t, err) {
+			assert.Equal(t, test.expected, err.Error())
+		}
+	}
+
+	testMetadata.Dependencies = []*Dependency{{Id: id}, {Title: "Valid App 2"}, {Title: "Valid App 3", Version: ">=1.0.0 <2.0.0"}}
+	assert.Nil(t, ValidateStruct(testMetadata))
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Semantic version ranges, the same as npm without hyphen ranges and x wildcards
+// IE: >=1.2.0 <2.0.0, ^1.2.0, ~1.2.3, 1.2.3, 1.0.0 || ^2.0.0, any version when empty or *.
+type VersionRange struct {
+	// The range matches a version matching every comparator of one of the sets, any version when empty
+	sets [][]comparator
+}
+
+type comparator struct {
+	operator string
+	version  Semver
+}
+
+var rangeOperators = []string{">=", "<=", ">", "<", "=", "^", "~"}
+
+// Parses a version range, the versions of the comparators must be semantic versions
+func ParseVersionRange(value string) (VersionRange, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "*" {
+		return VersionRange{}, nil
+	}
+	var r VersionRange
+	for _, set := range strings.Split(value, "||") {
+		var comparators []comparator
+		for _, field := range strings.Fields(set) {
+			parsed, err := parseComparator(field)
+			if err != nil {
+				return VersionRange{}, err
+			}
+			comparators = append(comparators, parsed...)
+		}
+		if len(comparators) == 0 {
+			return VersionRange{}, fmt.Errorf("version range %q has an empty set", value)
+		}
+		r.sets = append(r.sets, comparators)
+	}
+	return r, nil
+}
+
+// Whether the value is a version range, IE: ^1.2.0
+func IsVersionRange(value string) bool {
+	_, err := ParseVersionRange(value)
+	return err == nil
+}
+
+// Parses a comparator, ^ and ~ are expanded to a lower and an upper bound
+// IE: ^1.2.3 is >=1.2.3 <2.0.0, ^0.2.3 is >=0.2.3 <0.3.0, ^0.0.3 is >=0.0.3 <0.0.4 and ~1.2.3 is >=1.2.3 <1.3.0.
+func parseComparator(field string) ([]comparator, error) {
+	operator := ""
+	for _, candidate := range rangeOperators {
+		if strings.HasPrefix(field, candidate) {
+			operator = candidate
+			break
+		}
+	}
+	version, ok := ParseSemver(strings.TrimPrefix(field, operator))
+	if !ok {
+		return nil, fmt.Errorf("%q is not a semantic version comparator, IE: >=1.2.0, ^1.2.0 or ~1.2.3", field)
+	}
+
+	upper := Semver{Major: version.Major, Minor: version.Minor + 1}
+	switch {
+	case operator == "^" && version.Major > 0:
+		upper = Semver{Major: version.Major + 1}
+	case operator == "^" && version.Minor == 0:
+		upper = Semver{Patch: version.Patch + 1}
+	case operator == "^" || operator == "~":
+	case operator == "":
+		return []comparator{{operator: "=", version: version}}, nil
+	default:
+		return []comparator{{operator: operator, version: version}}, nil
+	}
+	return []comparator{{operator: ">=", version: version}, {operator: "<", version: upper}}, nil
+}
+
+// Whether the version is in the range
+// A pre-release is only matched by a set with a comparator on a pre-release of the same major, minor and patch, so
+// ^1.0.0 does not match 1.1.0-rc.1 but >=1.1.0-rc.0 does. Versions that are not semantic versions are only matched by
+// the empty range.
+func (r VersionRange) Contains(version string) bool {
+	if len(r.sets) == 0 {
+		return true
+	}
+	v, ok := ParseSemver(version)
+	if !ok {
+		return false
+	}
+	for _, set := range r.sets {
+		if setContains(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func setContains(set []comparator, v Semver) bool {
+	allowsPrerelease := !v.IsPrerelease()
+	for _, c := range set {
+		if !c.matches(v) {
+			return false
+		}
+		if c.version.IsPrerelease() && c.version.Major == v.Major && c.version.Minor == v.Minor && c.version.Patch == v.Patch {
+			allowsPrerelease = true
+		}
+	}
+	return allowsPrerelease
+}
+
+func (c comparator) matches(v Semver) bool {
+	compared := v.Compare(c.version)
+	switch c.operator {
+	case ">=":
+		return compared >= 0
+	case "<=":
+		return compared <= 0
+	case ">":
+		return compared > 0
+	case "<":
+		return compared < 0
+	}
+	return compared == 0
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVersionRange_Contains(t *testing.T) {
+	for _, test := range []struct {
+		versionRange string
+		matches      []string
+		others       []string
+	}{
+		{"", []string{"1.0.0", "1.0.0-rc.1", "not a version"}, nil},
+		{"*", []string{"0.0.1"}, nil},
+		{"1.2.3", []string{"1.2.3", "v1.2.3", "1.2.3+build.5"}, []string{"1.2.4", "not a version"}},
+		{"=v1.2.3", []string{"1.2.3"}, []string{"1.2.2"}},
+		{">=1.2.0 <2.0.0", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0", "2.0.0-rc.1", "1.5.0-rc.1"}},
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"1.2.2", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0"}},
+		{">1.0.0 <=1.1.0", []string{"1.0.1", "1.1.0"}, []string{"1.0.0", "1.1.1"}},
+		{"1.0.0 || ^2.0.0", []string{"1.0.0", "2.5.0"}, []string{"1.5.0", "3.0.0"}},
+		{">=1.1.0-rc.0", []string{"1.1.0-rc.1", "1.1.0", "2.0.0"}, []string{"1.2.0-rc.1"}},
+	} {
+		r, err := ParseVersionRange(test.versionRange)
+		assert.Nil(t, err, test.versionRange)
+		for _, version := range test.matches {
+			assert.True(t, r.Contains(version), "%s should contain %s", test.versionRange, version)
+		}
+		for _, version := range test.others {
+			assert.False(t, r.Contains(version), "%s should not contain %s", test.versionRange, version)
+		}
+	}
+}
+
+func TestParseVersionRange_Invalid(t *testing.T) {
+	for _, invalid := range []string{"latest", ">=1.2", "1.x", "1.0.0 ||", "=>1.0.0"} {
+		assert.False(t, IsVersionRange(invalid), invalid)
+	}
+	_, err := ParseVersionRange(">=1.2")
+	assert.EqualError(t, err, `">=1.2" is not a semantic version comparator, IE: >=1.2.0, ^1.2.0 or ~1.2.3`)
+}
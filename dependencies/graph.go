@@ -0,0 +1,294 @@
+package dependencies
+
+import (
+	"APIServerExercise/core"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// A dependency that does not resolve to a metadata
+type UnresolvedError struct {
+	// Position of the dependency in the dependencies of the metadata
+	Index      int
+	Dependency *core.Dependency
+}
+
+func (e *UnresolvedError) Error() string {
+	if e.Dependency.Id != "" {
+		return fmt.Sprintf("dependencies[%d]: no metadata has id %s", e.Index, e.Dependency.Id)
+	}
+	if e.Dependency.Version != "" {
+		return fmt.Sprintf("dependencies[%d]: no metadata titled %q has a version in %q", e.Index, e.Dependency.Title, e.Dependency.Version)
+	}
+	return fmt.Sprintf("dependencies[%d]: no metadata is titled %q", e.Index, e.Dependency.Title)
+}
+
+// Dependencies that lead back to the metadata they start from
+type CycleError struct {
+	// The metadata of the cycle, starting and ending with the same metadata
+	Cycle []*core.Metadata
+}
+
+func (e *CycleError) Error() string {
+	names := make([]string, len(e.Cycle))
+	for i, metadata := range e.Cycle {
+		names[i] = fmt.Sprintf("%s %s", metadata.Title, metadata.Version)
+	}
+	return fmt.Sprintf("dependencies form a cycle: %s", strings.Join(names, " -> "))
+}
+
+// The metadata of a database indexed to resolve dependencies
+type Graph struct {
+	metadatas []*core.Metadata
+	byId      map[uuid.UUID]*core.Metadata
+	// By lower case title, in the default ordering
+	byTitle map[string][]*core.Metadata
+}
+
+// Indexes the metadata of the database, the replacements replace the metadata with the same id or are added
+func NewGraph(database *core.Database, replacements ...*core.Metadata) *Graph {
+	return newChangedGraph(database, nil, replacements)
+}
+
+// Indexes the metadata of the database as if the removed metadata were deleted and the replacements saved
+func newChangedGraph(database *core.Database, removed []uuid.UUID, replacements []*core.Metadata) *Graph {
+	g := &Graph{byId: map[uuid.UUID]*core.Metadata{}, byTitle: map[string][]*core.Metadata{}}
+	replaced := map[uuid.UUID]*core.Metadata{}
+	for _, metadata := range replacements {
+		replaced[metadata.Id] = metadata
+	}
+	isRemoved := map[uuid.UUID]bool{}
+	for _, id := range removed {
+		isRemoved[id] = true
+	}
+	for _, id := range database.Ordering {
+		if isRemoved[id] {
+			continue
+		}
+		metadata, ok := database.Metadatas[id]
+		if replacement, isReplaced := replaced[id]; isReplaced {
+			metadata, ok = replacement, true
+			delete(replaced, id)
+		}
+		if ok {
+			g.add(metadata)
+		}
+	}
+	for _, metadata := range replacements {
+		if _, added := replaced[metadata.Id]; added {
+			g.add(metadata)
+		}
+	}
+	return g
+}
+
+func (g *Graph) add(metadata *core.Metadata) {
+	g.metadatas = append(g.metadatas, metadata)
+	g.byId[metadata.Id] = metadata
+	title := strings.ToLower(metadata.Title)
+	g.byTitle[title] = append(g.byTitle[title], metadata)
+}
+
+// Returns the metadata the dependency refers to
+// A reference by title resolves to the newest version in the range, the first one in the default ordering on a tie.
+func (g *Graph) Resolve(dependency *core.Dependency) (*core.Metadata, bool) {
+	if dependency.Id != "" {
+		id, err := uuid.Parse(dependency.Id)
+		if err != nil {
+			return nil, false
+		}
+		metadata, ok := g.byId[id]
+		return metadata, ok
+	}
+	versionRange, err := core.ParseVersionRange(dependency.Version)
+	if err != nil {
+		return nil, false
+	}
+	var newest *core.Metadata
+	for _, metadata := range g.byTitle[strings.ToLower(dependency.Title)] {
+		if versionRange.Contains(metadata.Version) && (newest == nil || core.CompareVersions(metadata.Version, newest.Version) > 0) {
+			newest = metadata
+		}
+	}
+	return newest, newest != nil
+}
+
+// Checks that every dependency of the metadata resolves and that none leads back to it
+// Returns an *UnresolvedError or a *CycleError. The metadata must be in the graph.
+func (g *Graph) Check(metadata *core.Metadata) error {
+	for i, dependency := range metadata.Dependencies {
+		if _, ok := g.Resolve(dependency); !ok {
+			return &UnresolvedError{Index: i, Dependency: dependency}
+		}
+	}
+	return g.checkCycle(metadata)
+}
+
+// Returns a *CycleError when the dependencies of the metadata lead back to it
+func (g *Graph) checkCycle(metadata *core.Metadata) error {
+	if path := g.pathTo(metadata, metadata.Id, map[uuid.UUID]bool{}); path != nil {
+		return &CycleError{Cycle: append([]*core.Metadata{metadata}, path...)}
+	}
+	return nil
+}
+
+// Returns the metadata leading from the dependencies of the metadata to the target, nil if there is no path
+func (g *Graph) pathTo(metadata *core.Metadata, target uuid.UUID, visited map[uuid.UUID]bool) []*core.Metadata {
+	for _, dependency := range metadata.Dependencies {
+		resolved, ok := g.Resolve(dependency)
+		if !ok {
+			continue
+		}
+		if resolved.Id == target {
+			return []*core.Metadata{resolved}
+		}
+		if visited[resolved.Id] {
+			continue
+		}
+		visited[resolved.Id] = true
+		if path := g.pathTo(resolved, target, visited); path != nil {
+			return append([]*core.Metadata{resolved}, path...)
+		}
+	}
+	return nil
+}
+
+// Checks the dependencies of the metadata as if it replaced the metadata with the same id of the database
+// Metadata without dependencies is always valid, it can not be part of a cycle.
+func Check(database *core.Database, metadata *core.Metadata) error {
+	if len(metadata.Dependencies) == 0 {
+		return nil
+	}
+	return NewGraph(database, metadata).Check(metadata)
+}
+
+// Checks that deleting the removed metadata and saving the replacements does not form a cycle, returns a *CycleError
+// A dependency by title can resolve to another metadata once a metadata is deleted or its title or version changes, so
+// the dependents of the changed metadata are checked as well. Dependencies that no longer resolve are allowed.
+func CheckChange(database *core.Database, removed []uuid.UUID, replacements ...*core.Metadata) error {
+	// Dependencies referring to a changed metadata, by its id or by its previous or new title, may resolve differently
+	changedIds := map[string]bool{}
+	changedTitles := map[string]bool{}
+	changed := func(metadata *core.Metadata) {
+		changedIds[metadata.Id.String()] = true
+		changedTitles[strings.ToLower(metadata.Title)] = true
+	}
+	for _, id := range removed {
+		if metadata, ok := database.Metadatas[id]; ok {
+			changed(metadata)
+		}
+	}
+	isReplaced := map[uuid.UUID]bool{}
+	for _, metadata := range replacements {
+		isReplaced[metadata.Id] = true
+		changed(metadata)
+		if existing, ok := database.Metadatas[metadata.Id]; ok {
+			changed(existing)
+		}
+	}
+
+	g := newChangedGraph(database, removed, replacements)
+	for _, metadata := range g.metadatas {
+		if isReplaced[metadata.Id] || refersTo(metadata, changedIds, changedTitles) {
+			if err := g.checkCycle(metadata); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Whether a dependency of the metadata refers to one of the ids or titles
+func refersTo(metadata *core.Metadata, ids map[string]bool, titles map[string]bool) bool {
+	for _, dependency := range metadata.Dependencies {
+		if ids[dependency.Id] || (dependency.Id == "" && titles[strings.ToLower(dependency.Title)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Metadata reached from a metadata through its dependencies or through its dependents
+type Result struct {
+	Root Entry `yaml:"root"`
+	// Breadth first, each metadata once at its lowest depth
+	Entries []Entry `yaml:"entries"`
+	// Dependencies of the reached metadata that no longer resolve, IE: the metadata they referred to was deleted
+	Unresolved []Unresolved `yaml:"unresolved,omitempty"`
+}
+
+type Entry struct {
+	Id      string `yaml:"id"`
+	Title   string `yaml:"title"`
+	Version string `yaml:"version"`
+	// 1 for the direct dependencies or dependents, 2 for theirs, and so on
+	Depth int `yaml:"depth"`
+}
+
+type Unresolved struct {
+	// Id of the metadata with the dependency
+	Id         string           `yaml:"id"`
+	Dependency *core.Dependency `yaml:"dependency"`
+}
+
+func newEntry(metadata *core.Metadata, depth int) Entry {
+	return Entry{Id: metadata.Id.String(), Title: metadata.Title, Version: metadata.Version, Depth: depth}
+}
+
+// Returns what the metadata depends on, only its direct dependencies unless transitive
+func (g *Graph) Dependencies(id uuid.UUID, transitive bool) (*Result, bool) {
+	return g.walk(id, transitive, func(metadata *core.Metadata, result *Result) []*core.Metadata {
+		var next []*core.Metadata
+		for _, dependency := range metadata.Dependencies {
+			if resolved, ok := g.Resolve(dependency); ok {
+				next = append(next, resolved)
+			} else {
+				result.Unresolved = append(result.Unresolved, Unresolved{Id: metadata.Id.String(), Dependency: dependency})
+			}
+		}
+		return next
+	})
+}
+
+// Returns what depends on the metadata, only its direct dependents unless transitive
+// IE: the metadata impacted when it is deprecated.
+func (g *Graph) Dependents(id uuid.UUID, transitive bool) (*Result, bool) {
+	dependents := map[uuid.UUID][]*core.Metadata{}
+	for _, metadata := range g.metadatas {
+		for _, dependency := range metadata.Dependencies {
+			if resolved, ok := g.Resolve(dependency); ok {
+				dependents[resolved.Id] = append(dependents[resolved.Id], metadata)
+			}
+		}
+	}
+	return g.walk(id, transitive, func(metadata *core.Metadata, result *Result) []*core.Metadata {
+		return dependents[metadata.Id]
+	})
+}
+
+// Visits the metadata breadth first from the metadata with the id, returns false if it does not exist
+func (g *Graph) walk(id uuid.UUID, transitive bool, next func(metadata *core.Metadata, result *Result) []*core.Metadata) (*Result, bool) {
+	root, ok := g.byId[id]
+	if !ok {
+		return nil, false
+	}
+	result := &Result{Root: newEntry(root, 0), Entries: []Entry{}}
+	visited := map[uuid.UUID]bool{id: true}
+	level := []*core.Metadata{root}
+	for depth := 1; len(level) > 0 && (transitive || depth == 1); depth++ {
+		var reached []*core.Metadata
+		for _, metadata := range level {
+			for _, other := range next(metadata, result) {
+				if visited[other.Id] {
+					continue
+				}
+				visited[other.Id] = true
+				result.Entries = append(result.Entries, newEntry(other, depth))
+				reached = append(reached, other)
+			}
+		}
+		level = reached
+	}
+	return result, true
+}
@@ -0,0 +1,149 @@
+package dependencies
+
+import (
+	"APIServerExercise/core"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestMetadata(title string, version string, dependencies ...*core.Dependency) *core.Metadata {
+	return &core.Metadata{Id: uuid.New(), Title: title, Version: version, Dependencies: dependencies}
+}
+
+func newTestDatabase(metadatas ...*core.Metadata) *core.Database {
+	database := &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}}
+	for _, metadata := range metadatas {
+		database.Metadatas[metadata.Id] = metadata
+		database.Ordering = append(database.Ordering, metadata.Id)
+	}
+	return database
+}
+
+func names(entries []Entry) []string {
+	var result []string
+	for _, entry := range entries {
+		result = append(result, fmt.Sprintf("%s %s %d", entry.Title, entry.Version, entry.Depth))
+	}
+	return result
+}
+
+func TestGraph_Resolve(t *testing.T) {
+	old := newTestMetadata("Library", "1.0.0")
+	newest := newTestMetadata("Library", "1.2.0")
+	prerelease := newTestMetadata("Library", "1.3.0-rc.1")
+	major := newTestMetadata("Library", "2.0.0")
+	graph := NewGraph(newTestDatabase(old, newest, prerelease, major))
+
+	for _, test := range []struct {
+		dependency *core.Dependency
+		expected   *core.Metadata
+	}{
+		{&core.Dependency{Id: old.Id.String()}, old},
+		{&core.Dependency{Title: "library"}, major},
+		{&core.Dependency{Title: "Library", Version: "^1.0.0"}, newest},
+		{&core.Dependency{Title: "Library", Version: "~1.0.0"}, old},
+		{&core.Dependency{Title: "Library", Version: ">=1.3.0-rc.0 <2.0.0"}, prerelease},
+	} {
+		resolved, ok := graph.Resolve(test.dependency)
+		assert.True(t, ok, test.dependency)
+		assert.Same(t, test.expected, resolved, test.dependency)
+	}
+
+	for _, dependency := range []*core.Dependency{{Id: uuid.New().String()}, {Title: "Other"}, {Title: "Library", Version: "^3.0.0"}} {
+		_, ok := graph.Resolve(dependency)
+		assert.False(t, ok, dependency)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	library := newTestMetadata("Library", "1.0.0")
+	service := newTestMetadata("Service", "1.0.0", &core.Dependency{Title: "Library", Version: "^1.0.0"})
+	database := newTestDatabase(library, service)
+
+	assert.Nil(t, Check(database, newTestMetadata("App", "1.0.0", &core.Dependency{Id: service.Id.String()})))
+
+	err := Check(database, newTestMetadata("App", "1.0.0", &core.Dependency{Id: service.Id.String()}, &core.Dependency{Title: "Other", Version: "^1.0.0"}))
+	assert.Equal(t, &UnresolvedError{Index: 1, Dependency: &core.Dependency{Title: "Other", Version: "^1.0.0"}}, err)
+	assert.EqualError(t, err, `dependencies[1]: no metadata titled "Other" has a version in "^1.0.0"`)
+
+	// The library depending on the service leads back to it
+	updated := newTestMetadata("Library", "1.0.0", &core.Dependency{Title: "Service"})
+	updated.Id = library.Id
+	err = Check(database, updated)
+	assert.EqualError(t, err, "dependencies form a cycle: Library 1.0.0 -> Service 1.0.0 -> Library 1.0.0")
+
+	// So does a new version of the library, which the range of the service now resolves to
+	err = Check(database, newTestMetadata("Library", "1.1.0", &core.Dependency{Title: "Service"}))
+	assert.EqualError(t, err, "dependencies form a cycle: Library 1.1.0 -> Service 1.0.0 -> Library 1.1.0")
+	assert.Nil(t, Check(database, newTestMetadata("Library", "2.0.0", &core.Dependency{Title: "Service"})))
+
+	self := newTestMetadata("Self", "1.0.0")
+	self.Dependencies = []*core.Dependency{{Id: self.Id.String()}}
+	assert.EqualError(t, Check(database, self), "dependencies form a cycle: Self 1.0.0 -> Self 1.0.0")
+}
+
+func TestCheckChange(t *testing.T) {
+	older := newTestMetadata("Library", "1.0.0", &core.Dependency{Title: "Service"})
+	newest := newTestMetadata("Library", "2.0.0")
+	service := newTestMetadata("Service", "1.0.0", &core.Dependency{Title: "Library"})
+	unresolved := newTestMetadata("App", "1.0.0", &core.Dependency{Id: uuid.New().String()})
+	database := newTestDatabase(older, newest, service, unresolved)
+
+	// The service resolves to the older version once the newest one is gone
+	err := CheckChange(database, []uuid.UUID{newest.Id})
+	assert.EqualError(t, err, "dependencies form a cycle: Service 1.0.0 -> Library 1.0.0 -> Service 1.0.0")
+	downgraded := newTestMetadata("Library", "0.1.0")
+	downgraded.Id = newest.Id
+	assert.IsType(t, &CycleError{}, CheckChange(database, nil, downgraded))
+	renamed := newTestMetadata("Renamed", "2.0.0")
+	renamed.Id = newest.Id
+	assert.IsType(t, &CycleError{}, CheckChange(database, nil, renamed))
+
+	// Dependencies that no longer resolve are allowed
+	assert.Nil(t, CheckChange(database, []uuid.UUID{older.Id}))
+	assert.Nil(t, CheckChange(database, []uuid.UUID{service.Id, newest.Id}))
+	assert.Nil(t, CheckChange(database, nil, newTestMetadata("Other", "1.0.0")))
+}
+
+func TestGraph_Dependencies(t *testing.T) {
+	library := newTestMetadata("Library", "1.0.0")
+	client := newTestMetadata("Client", "1.0.0", &core.Dependency{Id: library.Id.String()})
+	service := newTestMetadata("Service", "1.0.0", &core.Dependency{Title: "Client"}, &core.Dependency{Title: "Library"})
+	app := newTestMetadata("App", "1.0.0", &core.Dependency{Title: "Service"}, &core.Dependency{Title: "Deleted"})
+	graph := NewGraph(newTestDatabase(library, client, service, app))
+
+	result, ok := graph.Dependencies(app.Id, false)
+	assert.True(t, ok)
+	assert.Equal(t, Entry{Id: app.Id.String(), Title: "App", Version: "1.0.0"}, result.Root)
+	assert.Equal(t, []string{"Service 1.0.0 1"}, names(result.Entries))
+	assert.Equal(t, []Unresolved{{Id: app.Id.String(), Dependency: &core.Dependency{Title: "Deleted"}}}, result.Unresolved)
+
+	result, _ = graph.Dependencies(app.Id, true)
+	assert.Equal(t, []string{"Service 1.0.0 1", "Client 1.0.0 2", "Library 1.0.0 2"}, names(result.Entries))
+
+	result, _ = graph.Dependencies(library.Id, true)
+	assert.Equal(t, []Entry{}, result.Entries)
+
+	_, ok = graph.Dependencies(uuid.New(), true)
+	assert.False(t, ok)
+}
+
+func TestGraph_Dependents(t *testing.T) {
+	library := newTestMetadata("Library", "1.0.0")
+	client := newTestMetadata("Client", "1.0.0", &core.Dependency{Id: library.Id.String()})
+	service := newTestMetadata("Service", "1.0.0", &core.Dependency{Title: "Client"}, &core.Dependency{Title: "Library"})
+	app := newTestMetadata("App", "1.0.0", &core.Dependency{Title: "Service"})
+	graph := NewGraph(newTestDatabase(library, client, service, app))
+
+	result, ok := graph.Dependents(library.Id, false)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"Client 1.0.0 1", "Service 1.0.0 1"}, names(result.Entries))
+
+	result, _ = graph.Dependents(library.Id, true)
+	assert.Equal(t, []string{"Client 1.0.0 1", "Service 1.0.0 1", "App 1.0.0 2"}, names(result.Entries))
+
+	result, _ = graph.Dependents(app.Id, true)
+	assert.Equal(t, []Entry{}, result.Entries)
+}
@@ -0,0 +1,57 @@
+package dependencies
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/handlerutil"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+const transitiveParameter = "transitive"
+
+// Gives access to the database while it can not change, IE: the *storage.MetadataStore
+type Reader interface {
+	Read(read func(database *core.Database))
+}
+
+// HTTP handlers of the dependency graph, read only as the graph is made of the metadata
+type Handler struct {
+	Store Reader
+}
+
+// GET /metadata/{id}/dependencies?transitive=true
+func (h *Handler) HandleDependenciesGet(w http.ResponseWriter, req *http.Request) {
+	h.handleWalk(w, req, (*Graph).Dependencies)
+}
+
+// GET /metadata/{id}/dependents?transitive=true
+func (h *Handler) HandleDependentsGet(w http.ResponseWriter, req *http.Request) {
+	h.handleWalk(w, req, (*Graph).Dependents)
+}
+
+func (h *Handler) handleWalk(w http.ResponseWriter, req *http.Request, walk func(g *Graph, id uuid.UUID, transitive bool) (*Result, bool)) {
+	id, err := uuid.Parse(mux.Vars(req)["id"])
+	if err != nil {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("Error parsing ID: %v", err.Error()))
+		return
+	}
+	transitive := false
+	if value := req.URL.Query().Get(transitiveParameter); value != "" {
+		if transitive, err = strconv.ParseBool(value); err != nil {
+			handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("%s must be true or false", transitiveParameter))
+			return
+		}
+	}
+
+	var result *Result
+	ok := false
+	h.Store.Read(func(database *core.Database) { result, ok = walk(NewGraph(database), id, transitive) })
+	if !ok {
+		handlerutil.WriteError(w, req, http.StatusNotFound, fmt.Sprintf("Metadata %s not found", id))
+		return
+	}
+	handlerutil.WriteYaml(w, req, http.StatusOK, result)
+}
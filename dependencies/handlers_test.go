@@ -0,0 +1,69 @@
+package dependencies
+
+import (
+	"APIServerExercise/core"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Reads the database without a lock, the tests do not change it while serving
+type testReader struct {
+	database *core.Database
+}
+
+func (r testReader) Read(read func(database *core.Database)) {
+	read(r.database)
+}
+
+func serve(handle http.HandlerFunc, id string, query string) *httptest.ResponseRecorder {
+	request := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/metadata/"+id+"/dependencies"+query, nil), map[string]string{"id": id})
+	responseRecorder := httptest.NewRecorder()
+	handle(responseRecorder, request)
+	return responseRecorder
+}
+
+func TestHandler(t *testing.T) {
+	library := newTestMetadata("Library", "1.0.0")
+	service := newTestMetadata("Service", "1.0.0", &core.Dependency{Title: "Library"})
+	app := newTestMetadata("App", "1.0.0", &core.Dependency{Id: service.Id.String()})
+	handler := &Handler{Store: testReader{newTestDatabase(library, service, app)}}
+
+	// region Dependencies
+	responseRecorder := serve(handler.HandleDependenciesGet, app.Id.String(), "?transitive=true")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "application/x-yaml", responseRecorder.Header().Get("Content-Type"))
+	result := &Result{}
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), result))
+	assert.Equal(t, []string{"Service 1.0.0 1", "Library 1.0.0 2"}, names(result.Entries))
+
+	responseRecorder = serve(handler.HandleDependenciesGet, app.Id.String(), "")
+	result = &Result{}
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), result))
+	assert.Equal(t, []string{"Service 1.0.0 1"}, names(result.Entries))
+	// endregion
+
+	// region Dependents
+	responseRecorder = serve(handler.HandleDependentsGet, library.Id.String(), "?transitive=1")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	result = &Result{}
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), result))
+	assert.Equal(t, []string{"Service 1.0.0 1", "App 1.0.0 2"}, names(result.Entries))
+	// endregion
+
+	// region Errors
+	responseRecorder = serve(handler.HandleDependenciesGet, "1234", "")
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	responseRecorder = serve(handler.HandleDependenciesGet, app.Id.String(), "?transitive=maybe")
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, "transitive must be true or false\n", responseRecorder.Body.String())
+	unknown := uuid.New().String()
+	responseRecorder = serve(handler.HandleDependentsGet, unknown, "")
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+	assert.Equal(t, "Metadata "+unknown+" not found\n", responseRecorder.Body.String())
+	// endregion
+}
@@ -0,0 +1,209 @@
+package duplicates
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/search"
+	"APIServerExercise/storage"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Weight of each signal in the score of a pair
+const (
+	titleWeight       = 0.5
+	sourceWeight      = 0.3
+	maintainersWeight = 0.2
+)
+
+type Config struct {
+	// Time between two detections, when 0 every request for the candidates detects them
+	Interval time.Duration `yaml:"interval"`
+	// Lowest score of a candidate pair, between 0 and 1
+	// The default flags metadata with the same title, or with a similar title and the same source or maintainers.
+	Threshold float64 `yaml:"threshold"`
+}
+
+func DefaultConfig() Config {
+	return Config{Interval: time.Hour, Threshold: 0.5}
+}
+
+func (c *Config) Validate() error {
+	if c.Interval < 0 {
+		return fmt.Errorf("duplicates.interval must not be negative")
+	}
+	if c.Threshold <= 0 || c.Threshold > 1 {
+		return fmt.Errorf("duplicates.threshold must be greater than 0 and at most 1")
+	}
+	return nil
+}
+
+// Two metadata that are likely the same application
+type Candidate struct {
+	// Weighted sum of the signals, between 0 and 1
+	Score float64 `yaml:"score"`
+	// Similarity of each signal, between 0 and 1
+	Title       float64 `yaml:"title"`
+	Source      float64 `yaml:"source"`
+	Maintainers float64 `yaml:"maintainers"`
+	// First in the default ordering
+	First  Entry `yaml:"first"`
+	Second Entry `yaml:"second"`
+}
+
+type Entry struct {
+	Id      string `yaml:"id"`
+	Title   string `yaml:"title"`
+	Version string `yaml:"version"`
+}
+
+type Result struct {
+	DetectedAt time.Time `yaml:"detectedAt"`
+	// Highest score first
+	Candidates []Candidate `yaml:"candidates"`
+}
+
+// Compares two metadata, returns false when they are not candidates whatever their score
+// Releases of the same application with different versions are not duplicates.
+func Compare(a *core.Metadata, b *core.Metadata) (Candidate, bool) {
+	if a.Application == b.Application && core.CompareVersions(a.Version, b.Version) != 0 {
+		return Candidate{}, false
+	}
+	candidate := Candidate{
+		Title:       round(TitleSimilarity(a.Title, b.Title)),
+		Source:      round(SourceSimilarity(a, b)),
+		Maintainers: round(MaintainersSimilarity(a.Maintainers, b.Maintainers)),
+		First:       Entry{Id: a.Id.String(), Title: a.Title, Version: a.Version},
+		Second:      Entry{Id: b.Id.String(), Title: b.Title, Version: b.Version},
+	}
+	candidate.Score = round(titleWeight*candidate.Title + sourceWeight*candidate.Source + maintainersWeight*candidate.Maintainers)
+	return candidate, true
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// One minus the edit distance of the titles over the length of the longest, ignoring case and punctuation
+// IE: Valid App and valid-app2 have a similarity of 0.9.
+func TitleSimilarity(a string, b string) float64 {
+	a, b = strings.ReplaceAll(core.Slug(a), "-", " "), strings.ReplaceAll(core.Slug(b), "-", " ")
+	longest := len([]rune(a))
+	if l := len([]rune(b)); l > longest {
+		longest = l
+	}
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(search.Levenshtein(a, b))/float64(longest)
+}
+
+// 1 when the sources are the same repository or the same URL, 0 otherwise
+// IE: https://github.com/upbound/repo and https://github.com/upbound/repo/tree/main are the same repository.
+func SourceSimilarity(a *core.Metadata, b *core.Metadata) float64 {
+	keyA, keyB := sourceKey(a), sourceKey(b)
+	if keyA == "" || keyA != keyB {
+		return 0
+	}
+	return 1
+}
+
+func sourceKey(metadata *core.Metadata) string {
+	if metadata.Source.URL == nil {
+		return ""
+	}
+	components := core.URLComponents(metadata.Source.URL)
+	if owner, repo := components[core.URLOwner], components[core.URLRepo]; len(owner) > 0 && len(repo) > 0 {
+		return strings.ToLower(fmt.Sprintf("%s/%s/%s", components[core.URLHost][0], owner[0], repo[0]))
+	}
+	return core.NormalizeURL(metadata.Source.URL).String()
+}
+
+// Number of maintainer emails of both over the number of emails of either, ignoring case
+func MaintainersSimilarity(a []*core.Maintainer, b []*core.Maintainer) float64 {
+	emails := func(maintainers []*core.Maintainer) map[string]bool {
+		set := map[string]bool{}
+		for _, maintainer := range maintainers {
+			set[strings.ToLower(maintainer.Email)] = true
+		}
+		return set
+	}
+	setA, setB := emails(a), emails(b)
+	shared := 0
+	for email := range setA {
+		if setB[email] {
+			shared++
+		}
+	}
+	if all := len(setA) + len(setB) - shared; all > 0 {
+		return float64(shared) / float64(all)
+	}
+	return 0
+}
+
+// Compares every pair of metadata periodically and keeps the candidates of the last detection
+type Detector struct {
+	Config Config
+	Store  *storage.MetadataStore
+	// Uses time.Now when nil
+	Now func() time.Time
+
+	mutex  sync.RWMutex
+	result *Result
+}
+
+func (d *Detector) now() time.Time {
+	if d.Now != nil {
+		return d.Now()
+	}
+	return time.Now()
+}
+
+// Detects right away, then every interval until the context is cancelled
+func (d *Detector) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Config.Interval)
+	defer ticker.Stop()
+	for {
+		d.DetectOnce()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Compares every pair of metadata and keeps the pairs scoring at least the threshold
+func (d *Detector) DetectOnce() *Result {
+	metadatas := d.Store.Snapshot()
+
+	result := &Result{DetectedAt: d.now(), Candidates: []Candidate{}}
+	for i, a := range metadatas {
+		for _, b := range metadatas[i+1:] {
+			if candidate, ok := Compare(a, b); ok && candidate.Score >= d.Config.Threshold {
+				result.Candidates = append(result.Candidates, candidate)
+			}
+		}
+	}
+	sort.SliceStable(result.Candidates, func(i, j int) bool { return result.Candidates[i].Score > result.Candidates[j].Score })
+
+	d.mutex.Lock()
+	d.result = result
+	d.mutex.Unlock()
+	return result
+}
+
+// Returns the result of the last detection, detects first when there was none or when there is no interval
+func (d *Detector) Result() *Result {
+	d.mutex.RLock()
+	result := d.result
+	d.mutex.RUnlock()
+	if result == nil || d.Config.Interval == 0 {
+		return d.DetectOnce()
+	}
+	return result
+}
@@ -0,0 +1,131 @@
+package duplicates
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/search"
+	"APIServerExercise/storage"
+	"APIServerExercise/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var detectedAt = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newTestMetadata(t *testing.T, title string, source string, emails ...string) *core.Metadata {
+	u, err := url.Parse(source)
+	assert.Nil(t, err)
+	metadata := &core.Metadata{Title: title, Version: "1.0.0", Source: util.Yamlurl{URL: u}}
+	for _, email := range emails {
+		metadata.Maintainers = append(metadata.Maintainers, &core.Maintainer{Name: email, Email: email})
+	}
+	return metadata
+}
+
+func newTestStore(metadatas ...*core.Metadata) *storage.MetadataStore {
+	store := &storage.MetadataStore{
+		Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
+		Indexer:  &search.Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}},
+	}
+	for _, metadata := range metadatas {
+		store.Put(metadata)
+	}
+	return store
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, TitleSimilarity("Valid App", "valid-app"))
+	assert.Equal(t, 0.9, TitleSimilarity("Valid App", "valid-app2"))
+	assert.Equal(t, 0.0, TitleSimilarity("abc", "xyz"))
+	assert.Equal(t, 0.0, TitleSimilarity("", ""))
+}
+
+func TestSourceSimilarity(t *testing.T) {
+	a := newTestMetadata(t, "a", "https://github.com/upbound/repo")
+	assert.Equal(t, 1.0, SourceSimilarity(a, newTestMetadata(t, "b", "https://GitHub.com/Upbound/repo/tree/main")))
+	assert.Equal(t, 0.0, SourceSimilarity(a, newTestMetadata(t, "b", "https://github.com/upbound/other")))
+	assert.Equal(t, 1.0, SourceSimilarity(newTestMetadata(t, "a", "https://example.com/repo"), newTestMetadata(t, "b", "https://example.com/repo/")))
+	assert.Equal(t, 0.0, SourceSimilarity(&core.Metadata{}, &core.Metadata{}))
+}
+
+func TestMaintainersSimilarity(t *testing.T) {
+	a := newTestMetadata(t, "a", "https://example.com", "a@b.com", "c@d.com")
+	b := newTestMetadata(t, "b", "https://example.com", "A@B.com", "e@f.com")
+	assert.Equal(t, 1.0/3, MaintainersSimilarity(a.Maintainers, b.Maintainers))
+	assert.Equal(t, 0.0, MaintainersSimilarity(nil, nil))
+}
+
+func TestCompare(t *testing.T) {
+	a := newTestMetadata(t, "Valid App", "https://github.com/upbound/repo", "a@b.com")
+	b := newTestMetadata(t, "valid-app2", "https://github.com/upbound/repo", "a@b.com", "c@d.com")
+	candidate, ok := Compare(a, b)
+	assert.True(t, ok)
+	assert.Equal(t, Candidate{
+		Score:       0.5*0.9 + 0.3 + 0.2*0.5,
+		Title:       0.9,
+		Source:      1,
+		Maintainers: 0.5,
+		First:       Entry{Id: a.Id.String(), Title: "Valid App", Version: "1.0.0"},
+		Second:      Entry{Id: b.Id.String(), Title: "valid-app2", Version: "1.0.0"},
+	}, candidate)
+
+	// Releases of an application are not duplicates
+	a.Application, b.Application, b.Version = "app", "app", "2.0.0"
+	_, ok = Compare(a, b)
+	assert.False(t, ok)
+}
+
+func TestDetector_DetectOnce(t *testing.T) {
+	store := newTestStore(
+		newTestMetadata(t, "Valid App", "https://github.com/upbound/repo", "a@b.com"),
+		newTestMetadata(t, "Other", "https://github.com/random/other", "e@f.com"),
+		newTestMetadata(t, "valid-app", "https://github.com/upbound/repo", "a@b.com"),
+		newTestMetadata(t, "Valid App 2", "https://github.com/random/two"),
+	)
+	ids := store.Database.Ordering
+	detector := &Detector{Config: DefaultConfig(), Store: store, Now: func() time.Time { return detectedAt }}
+
+	result := detector.DetectOnce()
+	assert.Equal(t, detectedAt, result.DetectedAt)
+	if assert.Len(t, result.Candidates, 1) {
+		assert.Equal(t, 1.0, result.Candidates[0].Score)
+		assert.Equal(t, ids[0].String(), result.Candidates[0].First.Id)
+		assert.Equal(t, ids[2].String(), result.Candidates[0].Second.Id)
+	}
+	assert.Same(t, result, detector.Result())
+
+	// A similar title alone scores below the default threshold
+	detector.Config.Threshold = 0.4
+	result = detector.DetectOnce()
+	if assert.Len(t, result.Candidates, 3) {
+		assert.Equal(t, 1.0, result.Candidates[0].Score)
+		for _, candidate := range result.Candidates[1:] {
+			assert.Equal(t, ids[3].String(), candidate.Second.Id)
+			assert.Equal(t, 0.41, candidate.Score)
+		}
+	}
+}
+
+func TestDetector_Result(t *testing.T) {
+	detector := &Detector{Config: DefaultConfig(), Store: newTestStore()}
+	result := detector.Result()
+	assert.Equal(t, []Candidate{}, result.Candidates)
+	assert.Same(t, result, detector.Result())
+
+	// Without an interval, every call detects
+	detector.Config.Interval = 0
+	assert.NotSame(t, result, detector.Result())
+}
+
+func TestConfig_Validate(t *testing.T) {
+	config := DefaultConfig()
+	assert.Nil(t, config.Validate())
+
+	config.Threshold = 0
+	assert.EqualError(t, config.Validate(), "duplicates.threshold must be greater than 0 and at most 1")
+	config = DefaultConfig()
+	config.Interval = -time.Second
+	assert.EqualError(t, config.Validate(), "duplicates.interval must not be negative")
+}
@@ -0,0 +1,117 @@
+package duplicates
+
+import (
+	"APIServerExercise/dependencies"
+	"APIServerExercise/handlerutil"
+	"APIServerExercise/storage"
+	"APIServerExercise/tracing"
+	"fmt"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+const minScoreParameter = "minScore"
+
+// Checks the API key of a request, implemented by config.AuthConfig
+type Authorizer interface {
+	// Whether the request is allowed, otherwise writes a 401 or 403 response naming the action
+	RequireAdmin(w http.ResponseWriter, req *http.Request, action string) bool
+}
+
+// HTTP handlers of the duplicate candidates
+// Merging requires an admin API key.
+type Handler struct {
+	Detector *Detector
+	Store    *storage.MetadataStore
+	Auth     Authorizer
+}
+
+// Body of a merge request
+type MergeRequest struct {
+	// Id of the metadata that is kept
+	Keep string `yaml:"keep"`
+	// Id of the metadata merged into the kept one and deleted
+	Merge string `yaml:"merge"`
+}
+
+// GET /duplicates?minScore=0.8
+// Returns the candidates of the last detection scoring at least minScore, the threshold by default.
+// Candidates whose metadata were deleted or merged since are left out.
+func (h *Handler) HandleDuplicatesGet(w http.ResponseWriter, req *http.Request) {
+	minScore := 0.0
+	if value := req.URL.Query().Get(minScoreParameter); value != "" {
+		var err error
+		if minScore, err = strconv.ParseFloat(value, 64); err != nil || minScore < 0 || minScore > 1 {
+			handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("%s must be a number between 0 and 1", minScoreParameter))
+			return
+		}
+	}
+
+	result := h.Detector.Result()
+	filtered := Result{DetectedAt: result.DetectedAt, Candidates: []Candidate{}}
+	for _, candidate := range result.Candidates {
+		if candidate.Score >= minScore && h.exists(candidate.First.Id) && h.exists(candidate.Second.Id) {
+			filtered.Candidates = append(filtered.Candidates, candidate)
+		}
+	}
+	handlerutil.WriteYaml(w, req, http.StatusOK, filtered)
+}
+
+func (h *Handler) exists(id string) bool {
+	_, ok := h.Store.Get(uuid.MustParse(id))
+	return ok
+}
+
+// POST /duplicates/merge
+// Merges the metadata of a candidate pair, returns the kept metadata with its merge history.
+func (h *Handler) HandleMergePost(w http.ResponseWriter, req *http.Request) {
+	if !h.Auth.RequireAdmin(w, req, "merge metadata") {
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("Error reading body: %v", err))
+		return
+	}
+	request := MergeRequest{}
+	if err := yaml.Unmarshal(body, &request); err != nil {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("Error unmarshalling body: %v", err))
+		return
+	}
+	keepId, err := uuid.Parse(request.Keep)
+	if err != nil {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("keep must be a metadata id: %v", err))
+		return
+	}
+	mergeId, err := uuid.Parse(request.Merge)
+	if err != nil {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("merge must be a metadata id: %v", err))
+		return
+	}
+	if keepId == mergeId {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, "keep and merge must be different metadata")
+		return
+	}
+
+	_, span := tracing.Start(req.Context(), "storage.Merge")
+	merged, err := h.Store.Merge(keepId, mergeId, h.Detector.now())
+	span.SetAttribute("metadata.id", keepId.String())
+	span.SetError(err)
+	span.End()
+	switch e := err.(type) {
+	case nil:
+		handlerutil.WriteYaml(w, req, http.StatusOK, merged)
+	case *storage.NotFoundError:
+		handlerutil.WriteError(w, req, http.StatusNotFound, e.Error())
+	case *storage.ConflictError:
+		w.Header().Set("Location", fmt.Sprintf("/metadata/%s", e.Existing.Id))
+		handlerutil.WriteError(w, req, http.StatusConflict, e.Error())
+	case *dependencies.CycleError:
+		handlerutil.WriteError(w, req, http.StatusConflict, e.Error())
+	default:
+		handlerutil.WriteError(w, req, http.StatusInternalServerError, e.Error())
+	}
+}
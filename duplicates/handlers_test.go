@@ -0,0 +1,131 @@
+package duplicates
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/storage"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Allows the requests with an admin key header
+type testAuthorizer struct{}
+
+func (testAuthorizer) RequireAdmin(w http.ResponseWriter, req *http.Request, action string) bool {
+	if req.Header.Get("X-API-Key") != "admin" {
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func newTestHandler(t *testing.T) *Handler {
+	store := newTestStore(
+		newTestMetadata(t, "Valid App", "https://github.com/upbound/repo", "a@b.com"),
+		newTestMetadata(t, "valid-app", "https://github.com/upbound/repo", "c@d.com"),
+		newTestMetadata(t, "Other", "https://github.com/random/other"),
+	)
+	store.Unique = storage.NewUniqueIndex([][]string{{"title", "version"}})
+	store.Unique.Rebuild(store.Database)
+	return &Handler{
+		Detector: &Detector{Config: DefaultConfig(), Store: store, Now: func() time.Time { return detectedAt }},
+		Store:    store,
+		Auth:     testAuthorizer{},
+	}
+}
+
+func TestHandler_HandleDuplicatesGet(t *testing.T) {
+	handler := newTestHandler(t)
+	ids := handler.Store.Database.Ordering
+
+	// region Candidates
+	responseRecorder := httptest.NewRecorder()
+	handler.HandleDuplicatesGet(responseRecorder, httptest.NewRequest(http.MethodGet, "/duplicates", nil))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "application/x-yaml", responseRecorder.Header().Get("Content-Type"))
+	result := &Result{}
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), result))
+	assert.Equal(t, detectedAt, result.DetectedAt)
+	if assert.Len(t, result.Candidates, 1) {
+		assert.Equal(t, 0.8, result.Candidates[0].Score)
+		assert.Equal(t, ids[0].String(), result.Candidates[0].First.Id)
+		assert.Equal(t, ids[1].String(), result.Candidates[0].Second.Id)
+	}
+	// endregion
+
+	// region Minimum score
+	responseRecorder = httptest.NewRecorder()
+	handler.HandleDuplicatesGet(responseRecorder, httptest.NewRequest(http.MethodGet, "/duplicates?minScore=0.9", nil))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	result = &Result{}
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), result))
+	assert.Empty(t, result.Candidates)
+
+	for _, minScore := range []string{"high", "-1", "1.5"} {
+		responseRecorder = httptest.NewRecorder()
+		handler.HandleDuplicatesGet(responseRecorder, httptest.NewRequest(http.MethodGet, "/duplicates?minScore="+minScore, nil))
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		assert.Equal(t, "minScore must be a number between 0 and 1\n", responseRecorder.Body.String())
+	}
+	// endregion
+
+	// region Deleted metadata
+	handler.Store.Delete(ids[1])
+	responseRecorder = httptest.NewRecorder()
+	handler.HandleDuplicatesGet(responseRecorder, httptest.NewRequest(http.MethodGet, "/duplicates", nil))
+	result = &Result{}
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), result))
+	assert.Empty(t, result.Candidates)
+	// endregion
+}
+
+func merge(handler *Handler, key string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/duplicates/merge", strings.NewReader(body))
+	request.Header.Set("X-API-Key", key)
+	responseRecorder := httptest.NewRecorder()
+	handler.HandleMergePost(responseRecorder, request)
+	return responseRecorder
+}
+
+func TestHandler_HandleMergePost(t *testing.T) {
+	handler := newTestHandler(t)
+	ids := handler.Store.Database.Ordering
+	keep, other := ids[0], ids[1]
+
+	// region Errors
+	assert.Equal(t, http.StatusForbidden, merge(handler, "", fmt.Sprintf("keep: %s\nmerge: %s\n", keep, other)).Code)
+
+	responseRecorder := merge(handler, "admin", "keep: [")
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	responseRecorder = merge(handler, "admin", fmt.Sprintf("keep: %s\nmerge: 1234\n", keep))
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "merge must be a metadata id")
+	responseRecorder = merge(handler, "admin", fmt.Sprintf("keep: %s\nmerge: %s\n", keep, keep))
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, "keep and merge must be different metadata\n", responseRecorder.Body.String())
+	responseRecorder = merge(handler, "admin", fmt.Sprintf("keep: %s\nmerge: 8ed4ab71-3b4e-4a1f-9c7a-7f3d6e2b1c0a\n", keep))
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+	assert.Equal(t, "metadata 8ed4ab71-3b4e-4a1f-9c7a-7f3d6e2b1c0a not found\n", responseRecorder.Body.String())
+	// endregion
+
+	// region Merge
+	responseRecorder = merge(handler, "admin", fmt.Sprintf("keep: %s\nmerge: %s\n", keep, other))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	merged := &core.Metadata{}
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), merged))
+	assert.Equal(t, keep, merged.Id)
+	assert.Len(t, merged.Maintainers, 2)
+	if assert.Len(t, merged.Merged, 1) {
+		assert.Equal(t, other, merged.Merged[0].Id)
+		assert.Equal(t, "valid-app", merged.Merged[0].Title)
+		assert.Equal(t, detectedAt, merged.Merged[0].MergedAt)
+	}
+	_, ok := handler.Store.Get(other)
+	assert.False(t, ok)
+	// endregion
+}
@@ -0,0 +1,145 @@
+package graphqlserver
+
+import (
+	"github.com/graphql-go/graphql/language/ast"
+	"strconv"
+)
+
+// Estimated number of maintainers or labels of a metadata, lists without a size argument are counted as this many items
+const estimatedListSize = 3
+
+// Depth and estimated number of resolved fields of a query
+// Computed before execution so expensive queries are rejected without touching the database.
+type cost struct {
+	depth      int
+	complexity int
+}
+
+type costCalculator struct {
+	fragments       map[string]*ast.FragmentDefinition
+	variables       map[string]interface{}
+	defaults        map[string]ast.Value
+	defaultPageSize int
+}
+
+// Returns the cost of the operation that will be executed
+// The document must be valid, IE: fragments are defined and do not form cycles.
+func measure(document *ast.Document, operationName string, variables map[string]interface{}, defaultPageSize int) cost {
+	c := &costCalculator{
+		fragments:       map[string]*ast.FragmentDefinition{},
+		variables:       variables,
+		defaults:        map[string]ast.Value{},
+		defaultPageSize: defaultPageSize,
+	}
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			c.fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		}
+	}
+	// Execution reports the missing operation
+	if operation == nil {
+		return cost{}
+	}
+	for _, definition := range operation.VariableDefinitions {
+		if definition.DefaultValue != nil {
+			c.defaults[definition.Variable.Name.Value] = definition.DefaultValue
+		}
+	}
+	return c.selectionSet(operation.SelectionSet, 1)
+}
+
+func (c *costCalculator) selectionSet(set *ast.SelectionSet, depth int) cost {
+	total := cost{depth: depth}
+	if set == nil {
+		return total
+	}
+	for _, selection := range set.Selections {
+		var selected cost
+		switch selection := selection.(type) {
+		case *ast.Field:
+			selected = cost{depth: depth, complexity: 1}
+			if selection.SelectionSet != nil {
+				children := c.selectionSet(selection.SelectionSet, depth+1)
+				selected.depth = children.depth
+				selected.complexity += c.listSize(selection) * children.complexity
+			}
+		case *ast.InlineFragment:
+			selected = c.selectionSet(selection.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			if fragment, ok := c.fragments[selection.Name.Value]; ok {
+				selected = c.selectionSet(fragment.SelectionSet, depth)
+			}
+		}
+		if selected.depth > total.depth {
+			total.depth = selected.depth
+		}
+		total.complexity += selected.complexity
+	}
+	return total
+}
+
+// Estimated number of items of the field, the cost of its selections is multiplied by it
+func (c *costCalculator) listSize(field *ast.Field) int {
+	switch field.Name.Value {
+	case "searchMetadata":
+		return c.intArgument(field, "pageSize", c.defaultPageSize)
+	case "facets":
+		return c.listArgumentLength(field, "fields") * c.intArgument(field, "limit", defaultFacetLimit)
+	case "maintainers", "labels", "annotations":
+		return estimatedListSize
+	}
+	return 1
+}
+
+// Returns the value of the argument, from a literal or a variable
+func (c *costCalculator) argument(field *ast.Field, name string) interface{} {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != name {
+			continue
+		}
+		value := argument.Value
+		if variable, ok := value.(*ast.Variable); ok {
+			if v, ok := c.variables[variable.Name.Value]; ok {
+				return v
+			}
+			value = c.defaults[variable.Name.Value]
+		}
+		if value == nil {
+			return nil
+		}
+		return value.GetValue()
+	}
+	return nil
+}
+
+func (c *costCalculator) intArgument(field *ast.Field, name string, defaultValue int) int {
+	switch value := c.argument(field, name).(type) {
+	case string:
+		// Int literals keep their source text
+		if i, err := strconv.Atoi(value); err == nil && i > 0 {
+			return i
+		}
+	case float64:
+		// Variables are decoded from JSON
+		if value > 0 {
+			return int(value)
+		}
+	}
+	return defaultValue
+}
+
+func (c *costCalculator) listArgumentLength(field *ast.Field, name string) int {
+	switch value := c.argument(field, name).(type) {
+	case []ast.Value:
+		return len(value)
+	case []interface{}:
+		return len(value)
+	}
+	return 1
+}
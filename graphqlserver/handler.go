@@ -0,0 +1,129 @@
+package graphqlserver
+
+import (
+	"APIServerExercise/logging"
+	"APIServerExercise/search"
+	"APIServerExercise/storage"
+	"APIServerExercise/tracing"
+	"encoding/json"
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"net/http"
+)
+
+// Page size used when the request does not specify one and the server has no default
+const defaultPageSize = 10
+
+// Body of POST /graphql
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphQL endpoint of the catalog, read only
+// Shares the store and the searcher with the REST handlers.
+type Handler struct {
+	Store    *storage.MetadataStore
+	Filterer search.Filterer
+	// Page size used when the request does not specify one, uses defaultPageSize if 0
+	DefaultPageSize int
+	// Largest page size a request can ask for, unlimited if 0
+	MaxPageSize int
+	// Deepest nesting of selections, unlimited if 0
+	MaxDepth int
+	// Highest estimated number of resolved fields, unlimited if 0
+	MaxComplexity int
+}
+
+// POST /graphql
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var request Request
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		writeErrors(w, req, http.StatusBadRequest, gqlerrors.NewFormattedError(fmt.Sprintf("Error decoding request: %v", err)))
+		return
+	}
+
+	_, span := tracing.Start(req.Context(), "graphql.Parse")
+	document, err := parser.Parse(parser.ParseParams{Source: request.Query})
+	span.SetError(err)
+	span.End()
+	if err != nil {
+		writeErrors(w, req, http.StatusBadRequest, gqlerrors.FormatError(err))
+		return
+	}
+
+	_, span = tracing.Start(req.Context(), "graphql.Validate")
+	validation := graphql.ValidateDocument(&schema, document, nil)
+	span.End()
+	if !validation.IsValid {
+		writeErrors(w, req, http.StatusBadRequest, validation.Errors...)
+		return
+	}
+
+	pageSize := h.DefaultPageSize
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	c := measure(document, request.OperationName, request.Variables, pageSize)
+	if h.MaxDepth > 0 && c.depth > h.MaxDepth {
+		writeErrors(w, req, http.StatusBadRequest, gqlerrors.NewFormattedError(
+			fmt.Sprintf("Query depth %d exceeds the maximum depth %d", c.depth, h.MaxDepth)))
+		return
+	}
+	if h.MaxComplexity > 0 && c.complexity > h.MaxComplexity {
+		writeErrors(w, req, http.StatusBadRequest, gqlerrors.NewFormattedError(
+			fmt.Sprintf("Query complexity %d exceeds the maximum complexity %d, request smaller pages or fewer fields",
+				c.complexity, h.MaxComplexity)))
+		return
+	}
+
+	ctx, span := tracing.Start(req.Context(), "graphql.Execute")
+	span.SetAttribute("graphql.complexity", c.complexity)
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        schema,
+		Root:          h,
+		AST:           document,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       ctx,
+	})
+	span.End()
+
+	addRequestId(req, result.Errors)
+	writeJson(w, http.StatusOK, result)
+}
+
+// Writes a response without data
+func writeErrors(w http.ResponseWriter, req *http.Request, status int, errors ...gqlerrors.FormattedError) {
+	addRequestId(req, errors)
+	writeJson(w, status, &graphql.Result{Errors: errors})
+}
+
+// Includes the request id so a failing request can be found in the access logs
+func addRequestId(req *http.Request, errors []gqlerrors.FormattedError) {
+	requestId := logging.RequestId(req.Context())
+	if requestId == "" {
+		return
+	}
+	for i := range errors {
+		if errors[i].Extensions == nil {
+			errors[i].Extensions = map[string]interface{}{}
+		}
+		errors[i].Extensions["requestId"] = requestId
+	}
+}
+
+func writeJson(w http.ResponseWriter, status int, result *graphql.Result) {
+	body, err := json.Marshal(result)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf("Error marshalling response: Error: %v\n", err)))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
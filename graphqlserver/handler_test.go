@@ -0,0 +1,202 @@
+package graphqlserver
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/search"
+	"APIServerExercise/storage"
+	"APIServerExercise/util"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type response struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func newTestMetadata(title string, license string, email string) *core.Metadata {
+	website, _ := url.Parse("https://website.com")
+	source, _ := url.Parse("https://github.com/random/repo")
+	return &core.Metadata{
+		Title:       title,
+		Version:     "0.0.1",
+		Maintainers: []*core.Maintainer{{Name: "firstmaintainer app1", Email: email}},
+		Company:     "Random Inc.",
+		Website:     util.Yamlurl{URL: website},
+		Source:      util.Yamlurl{URL: source},
+		License:     license,
+		Description: "Some application content",
+	}
+}
+
+func newHandler() *Handler {
+	searcher := &search.Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}}
+	store := &storage.MetadataStore{
+		Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
+		Indexer:  searcher,
+	}
+	store.Put(newTestMetadata("App B", "MIT", "a@hotmail.com"))
+	store.Put(newTestMetadata("App A", "MIT", "b@hotmail.com"))
+	labeled := newTestMetadata("App C", "Apache-2.0", "a@hotmail.com")
+	labeled.Labels = map[string]string{"tier": "critical", "env": "prod"}
+	store.Put(labeled)
+	return &Handler{Store: store, Filterer: searcher, MaxPageSize: 50, MaxDepth: 5, MaxComplexity: 500}
+}
+
+func post(t *testing.T, handler *Handler, query string, variables map[string]interface{}) (int, response) {
+	body, _ := json.Marshal(Request{Query: query, Variables: variables})
+	responseRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+	assert.Equal(t, "application/json", responseRecorder.Header().Get("Content-Type"))
+
+	var r response
+	assert.Nil(t, json.Unmarshal(responseRecorder.Body.Bytes(), &r))
+	return responseRecorder.Code, r
+}
+
+func TestHandler_SearchMetadata(t *testing.T) {
+	handler := newHandler()
+
+	status, r := post(t, handler, `{
+		searchMetadata(filters: [{field: "license", value: "MIT"}], sort: [{field: "title"}], pageSize: 1) {
+			totalCount
+			nextOffset
+			items { title maintainers { email } }
+			facets(fields: ["maintainers.email"]) { field values { value count } }
+		}
+	}`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, r.Errors)
+
+	page := r.Data["searchMetadata"].(map[string]interface{})
+	assert.Equal(t, float64(2), page["totalCount"])
+	assert.Equal(t, float64(1), page["nextOffset"])
+	items := page["items"].([]interface{})
+	assert.Len(t, items, 1)
+	assert.Equal(t, "App A", items[0].(map[string]interface{})["title"])
+	facets := page["facets"].([]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"field": "maintainers.email",
+		"values": []interface{}{
+			map[string]interface{}{"value": "a@hotmail.com", "count": float64(1)},
+			map[string]interface{}{"value": "b@hotmail.com", "count": float64(1)},
+		},
+	}, facets[0])
+}
+
+func TestHandler_SearchMetadata_WithVariables(t *testing.T) {
+	handler := newHandler()
+
+	status, r := post(t, handler, `query Search($license: String!, $offset: Int) {
+		searchMetadata(filters: [{field: "license", operator: NE, value: $license}], sort: [{field: "title", direction: DESC}], offset: $offset) {
+			items { title }
+			nextOffset
+		}
+	}`, map[string]interface{}{"license": "Apache-2.0", "offset": 1})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, r.Errors)
+
+	page := r.Data["searchMetadata"].(map[string]interface{})
+	assert.Equal(t, []interface{}{map[string]interface{}{"title": "App A"}}, page["items"])
+	assert.Nil(t, page["nextOffset"])
+}
+
+func TestHandler_SearchMetadata_WithInvalidArguments(t *testing.T) {
+	handler := newHandler()
+
+	_, r := post(t, handler, `{ searchMetadata(filters: [{field: "licence", value: "MIT"}]) { totalCount } }`, nil)
+	assert.Equal(t, `unknown field "licence", did you mean "license"?`, r.Errors[0].Message)
+
+	_, r = post(t, handler, `{ searchMetadata(sort: [{field: "maintainers.email"}]) { totalCount } }`, nil)
+	assert.Contains(t, r.Errors[0].Message, `can not sort on field "maintainers.email"`)
+
+	_, r = post(t, handler, `{ searchMetadata(pageSize: 51) { totalCount } }`, nil)
+	assert.Equal(t, "pageSize must be less than or equal to 50", r.Errors[0].Message)
+}
+
+func TestHandler_SearchMetadata_WithLabelSelector(t *testing.T) {
+	handler := newHandler()
+
+	status, r := post(t, handler, `{
+		searchMetadata(labelSelector: "tier in (critical,high),env") { items { title labels { key value } } }
+	}`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, r.Errors)
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"title": "App C",
+		"labels": []interface{}{
+			map[string]interface{}{"key": "env", "value": "prod"},
+			map[string]interface{}{"key": "tier", "value": "critical"},
+		},
+	}}, r.Data["searchMetadata"].(map[string]interface{})["items"])
+
+	_, r = post(t, handler, `{ searchMetadata(labelSelector: "tier in critical") { totalCount } }`, nil)
+	assert.Equal(t, `invalid label selector "tier in critical": invalid label key "tier in critical"`, r.Errors[0].Message)
+}
+
+func TestHandler_Metadata(t *testing.T) {
+	handler := newHandler()
+	id := handler.Store.Database.Ordering[0]
+
+	status, r := post(t, handler, `query Get($id: ID!) { metadata(id: $id) { id title website } }`,
+		map[string]interface{}{"id": id.String()})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, r.Errors)
+	assert.Equal(t, map[string]interface{}{
+		"id":      id.String(),
+		"title":   "App B",
+		"website": "https://website.com",
+	}, r.Data["metadata"])
+
+	_, r = post(t, handler, `{ metadata(id: "`+uuid.New().String()+`") { id } }`, nil)
+	assert.Empty(t, r.Errors)
+	assert.Nil(t, r.Data["metadata"])
+}
+
+func TestHandler_InvalidQuery(t *testing.T) {
+	handler := newHandler()
+
+	status, r := post(t, handler, `{ searchMetadata { unknown } }`, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, r.Errors[0].Message, `Cannot query field "unknown"`)
+
+	status, r = post(t, handler, `{ searchMetadata {`, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.NotEmpty(t, r.Errors)
+
+	responseRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader("query")))
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+}
+
+func TestHandler_Limits(t *testing.T) {
+	handler := newHandler()
+
+	handler.MaxComplexity = 450
+
+	// searchMetadata + 50 * (items + title + maintainers + 3 * (name + email)) fields
+	status, r := post(t, handler, `query Search($pageSize: Int = 10) {
+		searchMetadata(pageSize: $pageSize) { items { title maintainers { name email } } }
+	}`, map[string]interface{}{"pageSize": 50})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "Query complexity 451 exceeds the maximum complexity 450, request smaller pages or fewer fields",
+		r.Errors[0].Message)
+
+	status, r = post(t, handler, `fragment Page on MetadataPage { items { maintainers { name } } }
+		{ searchMetadata { ...Page facets(fields: ["license"]) { values { value } } } }`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, r.Errors)
+
+	handler.MaxDepth = 3
+	status, r = post(t, handler, `{ searchMetadata { items { maintainers { name } } } }`, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "Query depth 4 exceeds the maximum depth 3", r.Errors[0].Message)
+}
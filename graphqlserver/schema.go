@@ -0,0 +1,357 @@
+package graphqlserver
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/lifecycle"
+	"APIServerExercise/search"
+	"APIServerExercise/tracing"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"sort"
+)
+
+// Number of values of each facet when the query does not specify one
+const defaultFacetLimit = 10
+
+// Result of searchMetadata, the facets are counted over every result and not only the page
+type page struct {
+	results  []*core.Metadata
+	offset   int
+	pageSize int
+}
+
+var maintainerType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Maintainer",
+	Fields: graphql.Fields{
+		"name":  stringField(func(m interface{}) string { return m.(*core.Maintainer).Name }),
+		"email": stringField(func(m interface{}) string { return m.(*core.Maintainer).Email }),
+	},
+})
+
+// A label or an annotation
+type keyValue struct {
+	key   string
+	value string
+}
+
+var keyValueType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "KeyValue",
+	Fields: graphql.Fields{
+		"key":   stringField(func(kv interface{}) string { return kv.(keyValue).key }),
+		"value": stringField(func(kv interface{}) string { return kv.(keyValue).value }),
+	},
+})
+
+// List field of the entries of a map, sorted by key
+func keyValuesField(get func(metadata *core.Metadata) map[string]string) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(keyValueType))),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			entries := get(p.Source.(*core.Metadata))
+			keyValues := make([]keyValue, 0, len(entries))
+			for key, value := range entries {
+				keyValues = append(keyValues, keyValue{key: key, value: value})
+			}
+			sort.Slice(keyValues, func(i, j int) bool { return keyValues[i].key < keyValues[j].key })
+			return keyValues, nil
+		},
+	}
+}
+
+var metadataType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Metadata",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*core.Metadata).Id.String(), nil
+			},
+		},
+		"title":       stringField(func(m interface{}) string { return m.(*core.Metadata).Title }),
+		"version":     stringField(func(m interface{}) string { return m.(*core.Metadata).Version }),
+		"application": stringField(func(m interface{}) string { return m.(*core.Metadata).Application }),
+		"lifecycle":   stringField(func(m interface{}) string { return lifecycle.Of(m.(*core.Metadata)) }),
+		"maintainers": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(maintainerType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*core.Metadata).Maintainers, nil
+			},
+		},
+		"company": stringField(func(m interface{}) string { return m.(*core.Metadata).Company }),
+		"website": stringField(func(m interface{}) string {
+			return search.FieldValues(m.(*core.Metadata), "website")[0]
+		}),
+		"source": stringField(func(m interface{}) string {
+			return search.FieldValues(m.(*core.Metadata), "source")[0]
+		}),
+		"license":     stringField(func(m interface{}) string { return m.(*core.Metadata).License }),
+		"description": stringField(func(m interface{}) string { return m.(*core.Metadata).Description }),
+		"labels":      keyValuesField(func(m *core.Metadata) map[string]string { return m.Labels }),
+		"annotations": keyValuesField(func(m *core.Metadata) map[string]string { return m.Annotations }),
+	},
+})
+
+var facetValueType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "FacetValue",
+	Fields: graphql.Fields{
+		"value": stringField(func(v interface{}) string { return v.(search.FacetValue).Value }),
+		"count": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(search.FacetValue).Count, nil
+			},
+		},
+	},
+})
+
+// Values of a field with the number of results having them
+type facet struct {
+	field  string
+	values []search.FacetValue
+}
+
+var facetType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Facet",
+	Fields: graphql.Fields{
+		"field": stringField(func(f interface{}) string { return f.(*facet).field }),
+		"values": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(facetValueType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*facet).values, nil
+			},
+		},
+	},
+})
+
+var metadataPageType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "MetadataPage",
+	Fields: graphql.Fields{
+		"totalCount": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Int),
+			Description: "Number of metadata matching every filter",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return len(p.Source.(*page).results), nil
+			},
+		},
+		"items": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(metadataType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				pg := p.Source.(*page)
+				begin, end := pg.offset, pg.offset+pg.pageSize
+				if begin > len(pg.results) {
+					begin = len(pg.results)
+				}
+				if end > len(pg.results) {
+					end = len(pg.results)
+				}
+				return pg.results[begin:end], nil
+			},
+		},
+		"nextOffset": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Offset of the next page, null on the last page",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				pg := p.Source.(*page)
+				if end := pg.offset + pg.pageSize; end < len(pg.results) {
+					return end, nil
+				}
+				return nil, nil
+			},
+		},
+		"facets": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(facetType))),
+			Description: "Most common values of the fields among every result",
+			Args: graphql.FieldConfigArgument{
+				"fields": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+					Description: "Field paths, IE: license or maintainers.email",
+				},
+				"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultFacetLimit},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				_, span := tracing.Start(p.Context, "search.Facets")
+				defer span.End()
+
+				limit := p.Args["limit"].(int)
+				if limit < 1 {
+					return nil, fmt.Errorf("limit must be greater than 0")
+				}
+				var facets []*facet
+				for _, field := range p.Args["fields"].([]interface{}) {
+					values, err := search.Facets(p.Source.(*page).results, field.(string))
+					if err != nil {
+						span.SetError(err)
+						return nil, err
+					}
+					if len(values) > limit {
+						values = values[:limit]
+					}
+					facets = append(facets, &facet{field: field.(string), values: values})
+				}
+				return facets, nil
+			},
+		},
+	},
+})
+
+var filterOperatorType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "FilterOperator",
+	Values: graphql.EnumValueConfigMap{
+		"EQ": &graphql.EnumValueConfig{Value: search.OperatorEqual, Description: "The whole field value or one of its words"},
+		"NE": &graphql.EnumValueConfig{Value: search.OperatorNotEqual, Description: "Inverse of EQ"},
+	},
+})
+
+var filterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "Filter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"field":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String), Description: "Field path, IE: license or maintainers.email"},
+		"operator": &graphql.InputObjectFieldConfig{Type: filterOperatorType, DefaultValue: search.OperatorEqual},
+		"value":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+var sortDirectionType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "SortDirection",
+	Values: graphql.EnumValueConfigMap{
+		"ASC":  &graphql.EnumValueConfig{Value: false},
+		"DESC": &graphql.EnumValueConfig{Value: true},
+	},
+})
+
+var sortType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "Sort",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"field":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String), Description: "Field name, nested fields can not be sorted on"},
+		"direction": &graphql.InputObjectFieldConfig{Type: sortDirectionType, DefaultValue: false},
+	},
+})
+
+// Non null string field of an object, get returns the value from the source
+func stringField(get func(source interface{}) string) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(graphql.String),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source), nil
+		},
+	}
+}
+
+// The schema is static, the fields of the query get the handler as source
+var schema = mustSchema()
+
+func mustSchema() graphql.Schema {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"metadata": &graphql.Field{
+				Type:        metadataType,
+				Description: "The metadata with the id, null if there is none",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: resolveMetadata,
+			},
+			"searchMetadata": &graphql.Field{
+				Type:        graphql.NewNonNull(metadataPageType),
+				Description: "The metadata matching every filter, in the default ordering unless sorted",
+				Args: graphql.FieldConfigArgument{
+					"filters": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(filterType))},
+					"labelSelector": &graphql.ArgumentConfig{
+						Type:        graphql.String,
+						Description: "Kubernetes style label selector, IE: tier=critical,env in (prod,staging)",
+					},
+					"sort":     &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(sortType))},
+					"offset":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+					"pageSize": &graphql.ArgumentConfig{Type: graphql.Int, Description: "The server default when omitted"},
+				},
+				Resolve: resolveSearchMetadata,
+			},
+		},
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	if err != nil {
+		panic(fmt.Sprintf("invalid GraphQL schema: %v", err))
+	}
+	return schema
+}
+
+func resolveMetadata(p graphql.ResolveParams) (interface{}, error) {
+	h := p.Source.(*Handler)
+	id, err := uuid.Parse(p.Args["id"].(string))
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing ID: %v", err)
+	}
+
+	_, span := tracing.Start(p.Context, "storage.Get")
+	defer span.End()
+	metadata, ok := h.Store.Get(id)
+	if !ok {
+		return nil, nil
+	}
+	return metadata, nil
+}
+
+func resolveSearchMetadata(p graphql.ResolveParams) (interface{}, error) {
+	h := p.Source.(*Handler)
+	offset := p.Args["offset"].(int)
+	pageSize := h.pageSize(p.Args["pageSize"])
+	if offset < 0 {
+		return nil, fmt.Errorf("offset must be greater than or equal to 0")
+	}
+	if pageSize < 1 {
+		return nil, fmt.Errorf("pageSize must be greater than 0")
+	}
+	if h.MaxPageSize > 0 && pageSize > h.MaxPageSize {
+		return nil, fmt.Errorf("pageSize must be less than or equal to %d", h.MaxPageSize)
+	}
+
+	query := map[string][]string{}
+	filters, _ := p.Args["filters"].([]interface{})
+	for _, f := range filters {
+		filter := f.(map[string]interface{})
+		key := fmt.Sprintf("%s[%s]", filter["field"], filter["operator"])
+		// Queries only keep one value per key
+		if _, exists := query[key]; exists {
+			return nil, fmt.Errorf("field %q is filtered more than once with %s", filter["field"], filter["operator"])
+		}
+		query[key] = []string{filter["value"].(string)}
+	}
+	if selector, ok := p.Args["labelSelector"].(string); ok {
+		query[search.LabelSelectorParameter] = []string{selector}
+	}
+	var results []*core.Metadata
+	var err error
+	h.Store.Read(func(database *core.Database) { results, err = h.Filterer.FilterMetadata(p.Context, query, database) })
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []search.SortKey
+	sorts, _ := p.Args["sort"].([]interface{})
+	for _, s := range sorts {
+		sort := s.(map[string]interface{})
+		keys = append(keys, search.SortKey{Field: sort["field"].(string), Descending: sort["direction"].(bool)})
+	}
+	_, span := tracing.Start(p.Context, "search.Sort")
+	err = search.SortMetadata(results, keys)
+	span.SetError(err)
+	span.End()
+	if err != nil {
+		return nil, err
+	}
+
+	return &page{results: results, offset: offset, pageSize: pageSize}, nil
+}
+
+// Returns the requested page size, or the default one when the argument is omitted
+func (h *Handler) pageSize(argument interface{}) int {
+	if pageSize, ok := argument.(int); ok {
+		return pageSize
+	}
+	if h.DefaultPageSize > 0 {
+		return h.DefaultPageSize
+	}
+	return defaultPageSize
+}
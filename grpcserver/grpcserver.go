@@ -0,0 +1,312 @@
+package grpcserver
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/dependencies"
+	"APIServerExercise/licensepolicy"
+	"APIServerExercise/lifecycle"
+	"APIServerExercise/metrics"
+	metadatav1 "APIServerExercise/proto/metadata/v1"
+	"APIServerExercise/search"
+	"APIServerExercise/storage"
+	"APIServerExercise/util"
+	"APIServerExercise/watch"
+	"context"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Page size used when the request does not specify one and the server has no default
+const defaultPageSize = 10
+
+// gRPC implementation of the metadata API
+// Shares the store, the validation and the searcher with the REST handlers.
+type MetadataServer struct {
+	metadatav1.UnimplementedMetadataServiceServer
+
+	Store    *storage.MetadataStore
+	Filterer search.Filterer
+	// Page size used when the request does not specify one, uses defaultPageSize if 0
+	DefaultPageSize int
+	// Largest page size a request can ask for, unlimited if 0
+	MaxPageSize int
+	// Checks the license of saved metadata, optional
+	Policy *licensepolicy.Policy
+	// Hosts the source URL can be on, any host when empty
+	SourceHosts []string
+}
+
+// Header the license policy warnings of a saved metadata are sent in, one value per warning
+const WarningHeader = "warning"
+
+var _ metadatav1.MetadataServiceServer = &MetadataServer{}
+
+func (s *MetadataServer) GetMetadata(ctx context.Context, req *metadatav1.GetMetadataRequest) (*metadatav1.Metadata, error) {
+	id, err := parseId(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	metadata, ok := s.Store.Get(id)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "metadata %s not found", id)
+	}
+	return toProto(metadata), nil
+}
+
+// page_token is the offset of the page, the same as the offset parameter of the REST API
+func (s *MetadataServer) ListMetadata(ctx context.Context, req *metadatav1.ListMetadataRequest) (*metadatav1.ListMetadataResponse, error) {
+	offset := 0
+	if req.PageToken != "" {
+		var err error
+		offset, err = strconv.Atoi(req.PageToken)
+		if err != nil || offset < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page token %q", req.PageToken)
+		}
+	}
+	pageSize := int(req.PageSize)
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must be greater than 0")
+	case pageSize == 0 && s.DefaultPageSize > 0:
+		pageSize = s.DefaultPageSize
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case s.MaxPageSize > 0 && pageSize > s.MaxPageSize:
+		return nil, status.Errorf(codes.InvalidArgument, "page_size must be less than or equal to %d", s.MaxPageSize)
+	}
+
+	query := make(map[string][]string, len(req.Filters))
+	for key, value := range req.Filters {
+		query[key] = []string{value}
+	}
+	var results []*core.Metadata
+	var err error
+	s.Store.Read(func(database *core.Database) { results, err = s.Filterer.FilterMetadata(ctx, query, database) })
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	response := &metadatav1.ListMetadataResponse{}
+	if offset > len(results) {
+		offset = len(results)
+	}
+	end := offset + pageSize
+	if end < len(results) {
+		response.NextPageToken = strconv.Itoa(end)
+	} else {
+		end = len(results)
+	}
+	for _, metadata := range results[offset:end] {
+		response.Metadata = append(response.Metadata, toProto(metadata))
+	}
+	return response, nil
+}
+
+func (s *MetadataServer) PutMetadata(ctx context.Context, req *metadatav1.PutMetadataRequest) (*metadatav1.Metadata, error) {
+	if req.Metadata == nil {
+		return nil, status.Error(codes.InvalidArgument, "metadata is required")
+	}
+	metadata, err := fromProto(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	err = core.ValidateStruct(metadata)
+	if err == nil {
+		err = core.ValidateURLs(metadata, s.SourceHosts)
+	}
+	if err != nil {
+		metrics.RecordValidationError(err)
+		return nil, status.Errorf(codes.InvalidArgument, "Validation failed: %v", err)
+	}
+	warnings, err := s.Policy.Check(metadata.License)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if _, err := s.Store.Put(metadata); err != nil {
+		switch err.(type) {
+		case *storage.ConflictError:
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		case *dependencies.UnresolvedError, *dependencies.CycleError, *lifecycle.DeprecationError, *lifecycle.TransitionError:
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if len(warnings) > 0 {
+		grpc.SetHeader(ctx, grpcmetadata.MD{WarningHeader: warnings})
+	}
+	return toProto(metadata), nil
+}
+
+func (s *MetadataServer) DeleteMetadata(ctx context.Context, req *metadatav1.DeleteMetadataRequest) (*metadatav1.DeleteMetadataResponse, error) {
+	id, err := parseId(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := s.Store.Delete(id)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if !deleted {
+		return nil, status.Errorf(codes.NotFound, "metadata %s not found", id)
+	}
+	return &metadatav1.DeleteMetadataResponse{}, nil
+}
+
+var eventTypes = map[watch.EventType]metadatav1.WatchMetadataResponse_EventType{
+	watch.Added:    metadatav1.WatchMetadataResponse_EVENT_TYPE_ADDED,
+	watch.Modified: metadatav1.WatchMetadataResponse_EVENT_TYPE_MODIFIED,
+	watch.Deleted:  metadatav1.WatchMetadataResponse_EVENT_TYPE_DELETED,
+}
+
+// Streams changes until the client cancels or falls too far behind
+// The headers are sent once subscribed, changes made after receiving them are never missed.
+func (s *MetadataServer) WatchMetadata(req *metadatav1.WatchMetadataRequest, stream metadatav1.MetadataService_WatchMetadataServer) error {
+	if s.Store.Events == nil {
+		return status.Error(codes.Unimplemented, "watching is not enabled")
+	}
+	events, unsubscribe := s.Store.Events.Subscribe()
+	defer unsubscribe()
+	// Lets the client know every change from now on is streamed
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "the watch fell too far behind, watch again")
+			}
+			err := stream.Send(&metadatav1.WatchMetadataResponse{
+				Type:     eventTypes[event.Type],
+				Metadata: toProto(event.Metadata),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func parseId(id string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.UUID{}, status.Errorf(codes.InvalidArgument, "Error parsing ID: %v", err)
+	}
+	return parsed, nil
+}
+
+func toProto(metadata *core.Metadata) *metadatav1.Metadata {
+	m := &metadatav1.Metadata{
+		Id:          metadata.Id.String(),
+		Title:       metadata.Title,
+		Version:     metadata.Version,
+		Application: metadata.Application,
+		Company:     metadata.Company,
+		License:     metadata.License,
+		Description: metadata.Description,
+		Labels:      metadata.Labels,
+		Annotations: metadata.Annotations,
+	}
+	if metadata.Website.URL != nil {
+		m.Website = metadata.Website.String()
+	}
+	if metadata.Source.URL != nil {
+		m.Source = metadata.Source.String()
+	}
+	for _, maintainer := range metadata.Maintainers {
+		m.Maintainers = append(m.Maintainers, &metadatav1.Maintainer{Name: maintainer.Name, Email: maintainer.Email})
+	}
+	for _, dependency := range metadata.Dependencies {
+		m.Dependencies = append(m.Dependencies, &metadatav1.Dependency{
+			Id:      dependency.Id,
+			Title:   dependency.Title,
+			Version: dependency.Version,
+		})
+	}
+	m.Lifecycle = lifecycle.Of(metadata)
+	if deprecation := metadata.Deprecation; deprecation != nil {
+		m.Deprecation = &metadatav1.Deprecation{
+			Reason:       deprecation.Reason,
+			Replacement:  deprecation.Replacement,
+			Sunset:       toTimestamp(deprecation.Sunset),
+			DeprecatedAt: toTimestamp(deprecation.DeprecatedAt),
+		}
+	}
+	return m
+}
+
+func fromProto(m *metadatav1.Metadata) (*core.Metadata, error) {
+	metadata := &core.Metadata{
+		Title:       m.Title,
+		Version:     m.Version,
+		Application: m.Application,
+		Company:     m.Company,
+		License:     m.License,
+		Description: m.Description,
+	}
+	// Empty maps are omitted, the same as in the YAML payload
+	if len(m.Labels) > 0 {
+		metadata.Labels = m.Labels
+	}
+	if len(m.Annotations) > 0 {
+		metadata.Annotations = m.Annotations
+	}
+	if m.Id != "" {
+		id, err := parseId(m.Id)
+		if err != nil {
+			return nil, err
+		}
+		metadata.Id = id
+	}
+	website, err := url.Parse(m.Website)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid website: %v", err)
+	}
+	source, err := url.Parse(m.Source)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid source: %v", err)
+	}
+	metadata.Website = util.Yamlurl{URL: website}
+	metadata.Source = util.Yamlurl{URL: source}
+	for _, maintainer := range m.Maintainers {
+		metadata.Maintainers = append(metadata.Maintainers, &core.Maintainer{Name: maintainer.Name, Email: maintainer.Email})
+	}
+	for _, dependency := range m.Dependencies {
+		metadata.Dependencies = append(metadata.Dependencies, &core.Dependency{
+			Id:      dependency.Id,
+			Title:   dependency.Title,
+			Version: dependency.Version,
+		})
+	}
+	metadata.Lifecycle = m.Lifecycle
+	// deprecated_at is set when saving, the same as deprecatedAt in the YAML payload
+	if deprecation := m.Deprecation; deprecation != nil {
+		metadata.Deprecation = &core.Deprecation{Reason: deprecation.Reason, Replacement: deprecation.Replacement}
+		if deprecation.Sunset != nil {
+			if err := deprecation.Sunset.CheckValid(); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid deprecation.sunset: %v", err)
+			}
+			metadata.Deprecation.Sunset = deprecation.Sunset.AsTime()
+		}
+	}
+	return metadata, nil
+}
+
+// Unset for the zero time
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
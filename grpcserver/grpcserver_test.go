@@ -0,0 +1,318 @@
+package grpcserver
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/licensepolicy"
+	metadatav1 "APIServerExercise/proto/metadata/v1"
+	"APIServerExercise/search"
+	"APIServerExercise/storage"
+	"APIServerExercise/watch"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net"
+	"testing"
+	"time"
+)
+
+func newClient(t *testing.T) metadatav1.MetadataServiceClient {
+	searcher := &search.Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}}
+	grpcServer := grpc.NewServer()
+	metadatav1.RegisterMetadataServiceServer(grpcServer, &MetadataServer{
+		Store: &storage.MetadataStore{
+			Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
+			Indexer:  searcher,
+			Events:   watch.NewBroadcaster(),
+			Unique:   storage.NewUniqueIndex([][]string{{"title", "version"}}),
+		},
+		Filterer:    searcher,
+		MaxPageSize: 50,
+		Policy: licensepolicy.New(licensepolicy.Config{
+			Review:      []string{"GPL-3.0-only"},
+			Deny:        []string{"AGPL-3.0-only"},
+			Unlisted:    licensepolicy.Allowed,
+			Enforcement: licensepolicy.Reject,
+		}),
+	})
+
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return metadatav1.NewMetadataServiceClient(conn)
+}
+
+func newTestMetadata(title string) *metadatav1.Metadata {
+	return &metadatav1.Metadata{
+		Title:       title,
+		Version:     "0.0.1",
+		Maintainers: []*metadatav1.Maintainer{{Name: "firstmaintainer app1", Email: "firstmaintainer@hotmail.com"}},
+		Company:     "Random Inc.",
+		Website:     "https://website.com",
+		Source:      "https://github.com/random/repo",
+		License:     "Apache-2.0",
+		Description: "Some application content",
+	}
+}
+
+func TestMetadataServer_Crud(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	metadata := newTestMetadata("App")
+	metadata.Labels = map[string]string{"tier": "critical"}
+	created, err := client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: metadata})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, created.Id)
+	assert.Equal(t, "https://website.com", created.Website)
+
+	page, err := client.ListMetadata(ctx, &metadatav1.ListMetadataRequest{Filters: map[string]string{"labelSelector": "tier"}})
+	assert.Nil(t, err)
+	assert.Len(t, page.Metadata, 1)
+	assert.Equal(t, map[string]string{"tier": "critical"}, page.Metadata[0].Labels)
+
+	got, err := client.GetMetadata(ctx, &metadatav1.GetMetadataRequest{Id: created.Id})
+	assert.Nil(t, err)
+	assert.Equal(t, "App", got.Title)
+	assert.Equal(t, "firstmaintainer@hotmail.com", got.Maintainers[0].Email)
+
+	_, err = client.DeleteMetadata(ctx, &metadatav1.DeleteMetadataRequest{Id: created.Id})
+	assert.Nil(t, err)
+
+	_, err = client.GetMetadata(ctx, &metadatav1.GetMetadataRequest{Id: created.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.DeleteMetadata(ctx, &metadatav1.DeleteMetadataRequest{Id: created.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMetadataServer_Application(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	created, err := client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: newTestMetadata("App")})
+	assert.Nil(t, err)
+	assert.Equal(t, "app", created.Application)
+
+	// Moved to another application, then kept when the application is not sent
+	created.Application = "platform"
+	_, err = client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: created})
+	assert.Nil(t, err)
+	created.Application = ""
+	_, err = client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: created})
+	assert.Nil(t, err)
+
+	got, err := client.GetMetadata(ctx, &metadatav1.GetMetadataRequest{Id: created.Id})
+	assert.Nil(t, err)
+	assert.Equal(t, "platform", got.Application)
+}
+
+// Dependencies are sent back, so an update made from a read metadata keeps them
+func TestMetadataServer_Dependencies(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	library, err := client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: newTestMetadata("Library")})
+	assert.Nil(t, err)
+	metadata := newTestMetadata("App")
+	metadata.Dependencies = []*metadatav1.Dependency{{Id: library.Id}, {Title: "Library", Version: "^0.0.1"}}
+	created, err := client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: metadata})
+	assert.Nil(t, err)
+
+	got, err := client.GetMetadata(ctx, &metadatav1.GetMetadataRequest{Id: created.Id})
+	assert.Nil(t, err)
+	got.Description = "Updated"
+	_, err = client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: got})
+	assert.Nil(t, err)
+
+	updated, err := client.GetMetadata(ctx, &metadatav1.GetMetadataRequest{Id: created.Id})
+	assert.Nil(t, err)
+	assert.Equal(t, "Updated", updated.Description)
+	if assert.Len(t, updated.Dependencies, 2) {
+		assert.Equal(t, library.Id, updated.Dependencies[0].Id)
+		assert.Equal(t, "Library", updated.Dependencies[1].Title)
+		assert.Equal(t, "^0.0.1", updated.Dependencies[1].Version)
+	}
+
+	// Dependencies are validated like in the REST payload
+	metadata = newTestMetadata("Other")
+	metadata.Dependencies = []*metadatav1.Dependency{{Id: "not-a-uuid"}}
+	_, err = client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: metadata})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMetadataServer_Lifecycle(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	replacement, err := client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: newTestMetadata("App 2")})
+	assert.Nil(t, err)
+	assert.Equal(t, "published", replacement.Lifecycle)
+
+	metadata := newTestMetadata("App")
+	metadata.Lifecycle = "draft"
+	draft, err := client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: metadata})
+	assert.Nil(t, err)
+	assert.Equal(t, "draft", draft.Lifecycle)
+	page, err := client.ListMetadata(ctx, &metadatav1.ListMetadataRequest{Filters: map[string]string{"lifecycle": "draft"}})
+	assert.Nil(t, err)
+	assert.Len(t, page.Metadata, 1)
+
+	// A draft can not be deprecated before it is published
+	draft.Lifecycle = "deprecated"
+	draft.Deprecation = &metadatav1.Deprecation{Reason: "Replaced"}
+	_, err = client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: draft})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	draft.Lifecycle = "published"
+	draft.Deprecation = nil
+	_, err = client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: draft})
+	assert.Nil(t, err)
+
+	sunset := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	draft.Lifecycle = "deprecated"
+	draft.Deprecation = &metadatav1.Deprecation{
+		Reason:       "Replaced by App 2",
+		Replacement:  replacement.Id,
+		Sunset:       timestamppb.New(sunset),
+		DeprecatedAt: timestamppb.New(sunset),
+	}
+	deprecated, err := client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: draft})
+	assert.Nil(t, err)
+
+	got, err := client.GetMetadata(ctx, &metadatav1.GetMetadataRequest{Id: deprecated.Id})
+	assert.Nil(t, err)
+	assert.Equal(t, "deprecated", got.Lifecycle)
+	assert.Equal(t, "Replaced by App 2", got.Deprecation.Reason)
+	assert.Equal(t, replacement.Id, got.Deprecation.Replacement)
+	assert.Equal(t, sunset, got.Deprecation.Sunset.AsTime())
+	// Set when saving, not taken from the request
+	assert.NotEqual(t, sunset, got.Deprecation.DeprecatedAt.AsTime())
+	assert.False(t, got.Deprecation.DeprecatedAt.AsTime().IsZero())
+
+	// The deprecation is kept when the lifecycle changes without one
+	got.Lifecycle = "retired"
+	got.Deprecation = nil
+	retired, err := client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: got})
+	assert.Nil(t, err)
+	assert.Equal(t, "retired", retired.Lifecycle)
+	assert.Equal(t, "Replaced by App 2", retired.Deprecation.Reason)
+}
+
+func TestMetadataServer_InvalidArguments(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	_, err := client.GetMetadata(ctx, &metadatav1.GetMetadataRequest{Id: "not-a-uuid"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	invalid := newTestMetadata("App")
+	invalid.Maintainers[0].Email = "not an email"
+	_, err = client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: invalid})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.ListMetadata(ctx, &metadatav1.ListMetadataRequest{PageSize: 51})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.ListMetadata(ctx, &metadatav1.ListMetadataRequest{PageToken: "-1"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMetadataServer_LicensePolicy(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	metadata := newTestMetadata("App")
+	metadata.License = "GPL-3.0-only OR AGPL-3.0-only"
+	var header grpcmetadata.MD
+	_, err := client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: metadata}, grpc.Header(&header))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"license GPL-3.0-only OR AGPL-3.0-only requires a legal review: GPL-3.0-only"},
+		header.Get(WarningHeader))
+
+	metadata.License = "MIT AND AGPL-3.0-only"
+	_, err = client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: metadata})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, "license MIT AND AGPL-3.0-only is denied by the license policy: AGPL-3.0-only", status.Convert(err).Message())
+}
+
+func TestMetadataServer_Conflict(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	existing, err := client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: newTestMetadata("App")})
+	assert.Nil(t, err)
+
+	_, err = client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: newTestMetadata("APP")})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	assert.Equal(t, `title and version must be unique, metadata `+existing.Id+` already has title "App" and version "0.0.1"`,
+		status.Convert(err).Message())
+}
+
+func TestMetadataServer_List(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	for _, title := range []string{"App 1", "App 2", "App 3"} {
+		_, err := client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: newTestMetadata(title)})
+		assert.Nil(t, err)
+	}
+
+	page, err := client.ListMetadata(ctx, &metadatav1.ListMetadataRequest{PageSize: 2})
+	assert.Nil(t, err)
+	assert.Len(t, page.Metadata, 2)
+	assert.Equal(t, "2", page.NextPageToken)
+
+	page, err = client.ListMetadata(ctx, &metadatav1.ListMetadataRequest{PageSize: 2, PageToken: page.NextPageToken})
+	assert.Nil(t, err)
+	assert.Len(t, page.Metadata, 1)
+	assert.Empty(t, page.NextPageToken)
+
+	page, err = client.ListMetadata(ctx, &metadatav1.ListMetadataRequest{Filters: map[string]string{"title": "App 2"}})
+	assert.Nil(t, err)
+	assert.Len(t, page.Metadata, 1)
+	assert.Equal(t, "App 2", page.Metadata[0].Title)
+}
+
+func TestMetadataServer_Watch(t *testing.T) {
+	client := newClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.WatchMetadata(ctx, &metadatav1.WatchMetadataRequest{})
+	assert.Nil(t, err)
+	// The headers are sent once subscribed
+	_, err = stream.Header()
+	assert.Nil(t, err)
+
+	created, err := client.PutMetadata(ctx, &metadatav1.PutMetadataRequest{Metadata: newTestMetadata("App")})
+	assert.Nil(t, err)
+
+	event, err := stream.Recv()
+	assert.Nil(t, err)
+	assert.Equal(t, metadatav1.WatchMetadataResponse_EVENT_TYPE_ADDED, event.Type)
+	assert.Equal(t, created.Id, event.Metadata.Id)
+
+	_, err = client.DeleteMetadata(ctx, &metadatav1.DeleteMetadataRequest{Id: created.Id})
+	assert.Nil(t, err)
+	event, err = stream.Recv()
+	assert.Nil(t, err)
+	assert.Equal(t, metadatav1.WatchMetadataResponse_EVENT_TYPE_DELETED, event.Type)
+}
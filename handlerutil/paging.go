@@ -0,0 +1,75 @@
+package handlerutil
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	OffsetParameter   = "offset"
+	PageSizeParameter = "pageSize"
+	DefaultOffset     = 0
+	DefaultPageSize   = 10
+	// Hardcoded http as we are using http.ListenAndServe and NOT http.ListenAndServeTLS
+	// Will need to update this if we use https
+	scheme = "http://"
+)
+
+// Extract and validate offset and pageSize from the query parameters and return
+// defaultPageSize is used when the query has no pageSize, DefaultPageSize if 0. The page size can not be larger than
+// maxPageSize, unlimited if 0.
+func ParsePaging(query map[string][]string, defaultPageSize int, maxPageSize int) (int, int, error) {
+	var err error
+	offset := DefaultOffset
+	pageSize := DefaultPageSize
+	if defaultPageSize > 0 {
+		pageSize = defaultPageSize
+	}
+
+	if o, ok := query[OffsetParameter]; ok {
+		offset, err = strconv.Atoi(o[0])
+		if err != nil {
+			return 0, 0, fmt.Errorf("offset must be numeric")
+		}
+	}
+	if o, ok := query[PageSizeParameter]; ok {
+		pageSize, err = strconv.Atoi(o[0])
+		if err != nil {
+			return 0, 0, fmt.Errorf("pageSize must be numeric")
+		}
+	}
+
+	if offset < 0 {
+		return 0, 0, fmt.Errorf("offset must be greater than or equal to 0")
+	}
+	if pageSize < 1 {
+		return 0, 0, fmt.Errorf("pageSize must be greater than 0")
+	}
+	if maxPageSize > 0 && pageSize > maxPageSize {
+		return 0, 0, fmt.Errorf("pageSize must be less than or equal to %d", maxPageSize)
+	}
+
+	return offset, pageSize, nil
+}
+
+// Returns the bounds of the page in a list of count results, and the link to the next page
+// The link is empty on the last page, IE: http://localhost:8080/metadata?offset=10&pageSize=10
+func Page(count int, offset int, pageSize int, req *http.Request) (int, int, string) {
+	begin := offset
+	end := offset + pageSize
+	if begin > count {
+		begin = count
+	}
+	// Add the link only if there is going to be a next page
+	if end >= count {
+		return begin, count, ""
+	}
+
+	originalUrl := req.URL
+	query := originalUrl.Query()
+	query.Set(OffsetParameter, fmt.Sprintf("%d", end))
+	query.Set(PageSizeParameter, fmt.Sprintf("%d", pageSize))
+	originalUrl.RawQuery = query.Encode()
+	return begin, end, fmt.Sprintf("%s%s%s", scheme, req.Host, originalUrl.String())
+}
@@ -0,0 +1,43 @@
+package handlerutil
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePaging(t *testing.T) {
+	offset, pageSize, err := ParsePaging(map[string][]string{}, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultOffset, offset)
+	assert.Equal(t, DefaultPageSize, pageSize)
+
+	offset, pageSize, err = ParsePaging(map[string][]string{OffsetParameter: {"4"}}, 25, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, offset)
+	assert.Equal(t, 25, pageSize)
+
+	_, _, err = ParsePaging(map[string][]string{PageSizeParameter: {"101"}}, 0, 100)
+	assert.EqualError(t, err, "pageSize must be less than or equal to 100")
+}
+
+// The link to the next page keeps the other query parameters
+func TestPage(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/kinds/plugins/resources?name=scanner", nil)
+
+	begin, end, nextLink := Page(5, 2, 2, request)
+	assert.Equal(t, 2, begin)
+	assert.Equal(t, 4, end)
+	assert.Equal(t, "http://example.com/kinds/plugins/resources?name=scanner&offset=4&pageSize=2", nextLink)
+
+	begin, end, nextLink = Page(5, 4, 2, request)
+	assert.Equal(t, 4, begin)
+	assert.Equal(t, 5, end)
+	assert.Empty(t, nextLink)
+
+	begin, end, nextLink = Page(5, 7, 2, request)
+	assert.Equal(t, 5, begin)
+	assert.Equal(t, 5, end)
+	assert.Empty(t, nextLink)
+}
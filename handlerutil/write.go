@@ -0,0 +1,36 @@
+package handlerutil
+
+import (
+	"APIServerExercise/logging"
+	"APIServerExercise/tracing"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"strings"
+)
+
+// Writes the value as a YAML response, or an error response when it can not be marshalled
+func WriteYaml(w http.ResponseWriter, req *http.Request, status int, value interface{}) {
+	_, span := tracing.Start(req.Context(), "yaml.Marshal")
+	r, err := yaml.Marshal(value)
+	span.SetError(err)
+	span.End()
+	if err != nil {
+		WriteError(w, req, http.StatusInternalServerError, fmt.Sprintf("Error marshalling %T: Error: %v", value, err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.WriteHeader(status)
+	w.Write(r)
+}
+
+// Writes an error response
+// Includes the request id so a failing request can be found in the access logs
+func WriteError(w http.ResponseWriter, req *http.Request, status int, message string) {
+	w.WriteHeader(status)
+	message = strings.TrimSuffix(message, "\n")
+	if requestId := logging.RequestId(req.Context()); requestId != "" {
+		message = fmt.Sprintf("%s\nRequest ID: %s", message, requestId)
+	}
+	w.Write([]byte(message + "\n"))
+}
@@ -0,0 +1,45 @@
+package handlerutil
+
+import (
+	"APIServerExercise/logging"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteYaml(t *testing.T) {
+	responseRecorder := httptest.NewRecorder()
+	WriteYaml(responseRecorder, httptest.NewRequest(http.MethodGet, "/metadata", nil), http.StatusCreated,
+		map[string]string{"title": "App"})
+
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	assert.Equal(t, "application/x-yaml", responseRecorder.Header().Get("Content-Type"))
+	assert.Equal(t, "title: App\n", responseRecorder.Body.String())
+}
+
+func TestWriteError(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/metadata", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	WriteError(responseRecorder, request, http.StatusBadRequest, "Something failed\n")
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, "Something failed\n", responseRecorder.Body.String())
+}
+
+func TestWriteError_WithRequestId(t *testing.T) {
+	accessLog := logging.AccessLog{Logger: logging.New(&bytes.Buffer{})}
+	handler := accessLog.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		WriteError(w, req, http.StatusBadRequest, "Something failed")
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/metadata", nil)
+	request.Header.Set(logging.RequestIdHeader, "test-id")
+	responseRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, "Something failed\nRequest ID: test-id\n", responseRecorder.Body.String())
+}
@@ -0,0 +1,164 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"sync"
+)
+
+const verboseParameter = "verbose"
+
+type Check struct {
+	Name  string
+	Check func() error
+}
+
+// Keeps track of the checks backing the /livez, /readyz and /healthz endpoints
+// Liveness checks are also part of readiness, a server that is not alive is not ready either.
+type Checker struct {
+	mutex     sync.RWMutex
+	liveness  []Check
+	readiness []Check
+}
+
+func (c *Checker) AddLivenessCheck(name string, check func() error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.liveness = append(c.liveness, Check{Name: name, Check: check})
+}
+
+func (c *Checker) AddReadinessCheck(name string, check func() error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.readiness = append(c.readiness, Check{Name: name, Check: check})
+}
+
+// GET /livez
+func (c *Checker) HandleLivez(w http.ResponseWriter, req *http.Request) {
+	c.mutex.RLock()
+	checks := append([]Check{}, c.liveness...)
+	c.mutex.RUnlock()
+	serveChecks(w, req, "livez", checks)
+}
+
+// GET /readyz
+func (c *Checker) HandleReadyz(w http.ResponseWriter, req *http.Request) {
+	c.mutex.RLock()
+	checks := append(append([]Check{}, c.liveness...), c.readiness...)
+	c.mutex.RUnlock()
+	serveChecks(w, req, "readyz", checks)
+}
+
+// GET /healthz
+// Same as /readyz, kept for clients that only know the older endpoint
+func (c *Checker) HandleHealthz(w http.ResponseWriter, req *http.Request) {
+	c.mutex.RLock()
+	checks := append(append([]Check{}, c.liveness...), c.readiness...)
+	c.mutex.RUnlock()
+	serveChecks(w, req, "healthz", checks)
+}
+
+// Runs the checks and writes the result
+// With ?verbose the result of every check is listed, like the Kubernetes API server does
+func serveChecks(w http.ResponseWriter, req *http.Request, endpoint string, checks []Check) {
+	_, verbose := req.URL.Query()[verboseParameter]
+
+	var output bytes.Buffer
+	failed := false
+	for _, check := range checks {
+		if err := check.Check(); err != nil {
+			failed = true
+			fmt.Fprintf(&output, "[-]%s failed: %v\n", check.Name, err)
+		} else {
+			fmt.Fprintf(&output, "[+]%s ok\n", check.Name)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if failed {
+		// Always list checks on failure so it is clear what is failing
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write(output.Bytes())
+		w.Write([]byte(fmt.Sprintf("%s check failed\n", endpoint)))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if verbose {
+		w.Write(output.Bytes())
+		w.Write([]byte(fmt.Sprintf("%s check passed\n", endpoint)))
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+// Check that fails until Set is called
+// Used for one time startup work, IE: rebuilding the index
+type Flag struct {
+	mutex   sync.RWMutex
+	done    bool
+	message string
+}
+
+func NewFlag(message string) *Flag {
+	return &Flag{message: message}
+}
+
+func (f *Flag) Set() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.done = true
+}
+
+func (f *Flag) Check() error {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	if !f.done {
+		return errors.New(f.message)
+	}
+	return nil
+}
+
+// Responds with 503 until the flag is set
+// Keeps requests away from state that is still being prepared during startup
+func (f *Flag) Gate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := f.Check(); err != nil {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(fmt.Sprintf("Server is not ready: %v\n", err.Error())))
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// Fails calls with UNAVAILABLE until the flag is set, the gRPC counterpart of Gate
+func (f *Flag) UnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if err := f.Check(); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "Server is not ready: %v", err)
+	}
+	return handler(ctx, req)
+}
+
+func (f *Flag) StreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	if err := f.Check(); err != nil {
+		return status.Errorf(codes.Unavailable, "Server is not ready: %v", err)
+	}
+	return handler(srv, ss)
+}
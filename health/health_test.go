@@ -0,0 +1,101 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupTest() (*Checker, *Flag) {
+	checker := &Checker{}
+	flag := NewFlag("index not rebuilt yet")
+	checker.AddLivenessCheck("ping", func() error { return nil })
+	checker.AddReadinessCheck("index", flag.Check)
+	return checker, flag
+}
+
+func TestChecker_HandleLivez(t *testing.T) {
+	checker, _ := setupTest()
+
+	responseRecorder := httptest.NewRecorder()
+	checker.HandleLivez(responseRecorder, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "ok", responseRecorder.Body.String())
+}
+
+func TestChecker_HandleReadyz(t *testing.T) {
+	checker, flag := setupTest()
+
+	responseRecorder := httptest.NewRecorder()
+	checker.HandleReadyz(responseRecorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, responseRecorder.Code)
+	assert.Equal(t, "[+]ping ok\n[-]index failed: index not rebuilt yet\nreadyz check failed\n", responseRecorder.Body.String())
+
+	flag.Set()
+	responseRecorder = httptest.NewRecorder()
+	checker.HandleReadyz(responseRecorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "ok", responseRecorder.Body.String())
+}
+
+func TestChecker_HandleHealthz_Verbose(t *testing.T) {
+	checker, flag := setupTest()
+	flag.Set()
+
+	responseRecorder := httptest.NewRecorder()
+	checker.HandleHealthz(responseRecorder, httptest.NewRequest(http.MethodGet, "/healthz?verbose", nil))
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "[+]ping ok\n[+]index ok\nhealthz check passed\n", responseRecorder.Body.String())
+}
+
+func TestChecker_HandleLivez_IgnoresReadiness(t *testing.T) {
+	checker := &Checker{}
+	checker.AddReadinessCheck("database", func() error { return fmt.Errorf("unavailable") })
+
+	responseRecorder := httptest.NewRecorder()
+	checker.HandleLivez(responseRecorder, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+}
+
+func TestFlag_Gate(t *testing.T) {
+	flag := NewFlag("not ready")
+	handler := flag.Gate(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	responseRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/metadata", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, responseRecorder.Code)
+	assert.Equal(t, "1", responseRecorder.Header().Get("Retry-After"))
+
+	flag.Set()
+	responseRecorder = httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/metadata", nil))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+}
+
+func TestFlag_UnaryInterceptor(t *testing.T) {
+	flag := NewFlag("not ready")
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "response", nil }
+	info := &grpc.UnaryServerInfo{FullMethod: "/metadata.v1.MetadataService/GetMetadata"}
+
+	_, err := flag.UnaryInterceptor(context.Background(), "request", info, handler)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, "Server is not ready: not ready", status.Convert(err).Message())
+
+	flag.Set()
+	response, err := flag.UnaryInterceptor(context.Background(), "request", info, handler)
+	assert.Nil(t, err)
+	assert.Equal(t, "response", response)
+}
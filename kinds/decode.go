@@ -0,0 +1,64 @@
+package kinds
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+)
+
+// Decodes a YAML or JSON document into the values JSON Schema describes
+// Objects are map[string]interface{}, arrays []interface{}, numbers int or float64.
+// Timestamps and other scalars YAML would convert are kept as the strings they were written as.
+func decode(r io.Reader) (interface{}, error) {
+	var node yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&node); err != nil {
+		return nil, err
+	}
+	return decodeNode(&node)
+}
+
+func decodeNode(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return decodeNode(node.Content[0])
+	case yaml.AliasNode:
+		return decodeNode(node.Alias)
+	case yaml.MappingNode:
+		object := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode || key.ShortTag() != "!!str" {
+				return nil, fmt.Errorf("line %d: object keys must be strings", key.Line)
+			}
+			if _, ok := object[key.Value]; ok {
+				return nil, fmt.Errorf("line %d: duplicate key %q", key.Line, key.Value)
+			}
+			value, err := decodeNode(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			object[key.Value] = value
+		}
+		return object, nil
+	case yaml.SequenceNode:
+		array := make([]interface{}, 0, len(node.Content))
+		for _, element := range node.Content {
+			value, err := decodeNode(element)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	}
+
+	switch node.ShortTag() {
+	case "!!int", "!!float", "!!bool", "!!null":
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+	return node.Value, nil
+}
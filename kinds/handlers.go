@@ -0,0 +1,237 @@
+package kinds
+
+import (
+	"APIServerExercise/config"
+	"APIServerExercise/handlerutil"
+	"APIServerExercise/tracing"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// HTTP handlers of the kinds and their resources
+// Registering, replacing and unregistering a kind requires an admin API key.
+type Handler struct {
+	Registry *Registry
+	Auth     *config.AuthConfig
+	// Page size used when the request does not specify one, uses handlerutil.DefaultPageSize if 0
+	DefaultPageSize int
+	// Largest page size a request can ask for, unlimited if 0
+	MaxPageSize int
+}
+
+// GET /kinds
+func (h *Handler) HandleKindsGet(w http.ResponseWriter, req *http.Request) {
+	handlerutil.WriteYaml(w, req, http.StatusOK, h.Registry.Summaries())
+}
+
+// GET /kinds/{kind}
+func (h *Handler) HandleKindGet(w http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["kind"]
+	kind, ok := h.Registry.Kind(name)
+	if !ok {
+		handlerutil.WriteError(w, req, http.StatusNotFound, fmt.Sprintf("Unknown kind %q", name))
+		return
+	}
+	handlerutil.WriteYaml(w, req, http.StatusOK, kind)
+}
+
+// PUT /kinds/{kind}
+func (h *Handler) HandleKindPut(w http.ResponseWriter, req *http.Request) {
+	if !h.Auth.RequireAdmin(w, req, "register kinds") {
+		return
+	}
+
+	_, span := tracing.Start(req.Context(), "kinds.Decode")
+	document, err := decode(req.Body)
+	span.SetError(err)
+	span.End()
+	if err != nil {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("Failed to decode body: %v", err.Error()))
+		return
+	}
+
+	name := mux.Vars(req)["kind"]
+	_, span = tracing.Start(req.Context(), "kinds.Register")
+	_, err = h.Registry.Register(name, document)
+	span.SetError(err)
+	span.End()
+	if err != nil {
+		// The schema is valid but rejects existing resources
+		if _, ok := err.(*IncompatibleSchemaError); ok {
+			handlerutil.WriteError(w, req, http.StatusConflict, err.Error())
+			return
+		}
+		handlerutil.WriteError(w, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	kind, _ := h.Registry.Kind(name)
+	handlerutil.WriteYaml(w, req, http.StatusCreated, kind)
+}
+
+// DELETE /kinds/{kind}
+func (h *Handler) HandleKindDelete(w http.ResponseWriter, req *http.Request) {
+	if !h.Auth.RequireAdmin(w, req, "unregister kinds") {
+		return
+	}
+
+	name := mux.Vars(req)["kind"]
+	switch err := h.Registry.Unregister(name); err {
+	case nil:
+		w.WriteHeader(http.StatusOK)
+	case ErrUnknownKind:
+		handlerutil.WriteError(w, req, http.StatusNotFound, fmt.Sprintf("Unknown kind %q", name))
+	default:
+		handlerutil.WriteError(w, req, http.StatusConflict, fmt.Sprintf("Kind %q has resources, delete them first", name))
+	}
+}
+
+// GET /kinds/{kind}/resources
+func (h *Handler) HandleResourcesGet(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	offset, pageSize, err := handlerutil.ParsePaging(query, h.DefaultPageSize, h.MaxPageSize)
+	if err != nil {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Remove the paging parameters as they are not going to be in the index
+	delete(query, handlerutil.OffsetParameter)
+	delete(query, handlerutil.PageSizeParameter)
+
+	name := mux.Vars(req)["kind"]
+	results, err := h.Registry.Filter(req.Context(), name, query)
+	if err == ErrUnknownKind {
+		handlerutil.WriteError(w, req, http.StatusNotFound, fmt.Sprintf("Unknown kind %q", name))
+		return
+	}
+	if err != nil {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	handlerutil.WriteYaml(w, req, http.StatusOK, pageResults(results, offset, pageSize, req))
+}
+
+// GET /kinds/{kind}/resources/{id}
+func (h *Handler) HandleResourceGetWithId(w http.ResponseWriter, req *http.Request) {
+	name, id, ok := parseResourcePath(w, req)
+	if !ok {
+		return
+	}
+
+	resource, found, err := h.Registry.Get(name, id)
+	if err == ErrUnknownKind {
+		handlerutil.WriteError(w, req, http.StatusNotFound, fmt.Sprintf("Unknown kind %q", name))
+		return
+	}
+	if !found {
+		handlerutil.WriteError(w, req, http.StatusNotFound, fmt.Sprintf("No %s resource with id %s", name, id))
+		return
+	}
+	handlerutil.WriteYaml(w, req, http.StatusOK, resource)
+}
+
+// PUT /kinds/{kind}/resources
+func (h *Handler) HandleResourcePut(w http.ResponseWriter, req *http.Request) {
+	h.handleResourcePutInner(w, req, mux.Vars(req)["kind"], uuid.UUID{})
+}
+
+// PUT /kinds/{kind}/resources/{id}
+func (h *Handler) HandleResourcePutWithId(w http.ResponseWriter, req *http.Request) {
+	name, id, ok := parseResourcePath(w, req)
+	if !ok {
+		return
+	}
+	h.handleResourcePutInner(w, req, name, id)
+}
+
+func (h *Handler) handleResourcePutInner(w http.ResponseWriter, req *http.Request, name string, id uuid.UUID) {
+	_, span := tracing.Start(req.Context(), "kinds.Decode")
+	document, err := decode(req.Body)
+	span.SetError(err)
+	span.End()
+	if err != nil {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("Failed to decode body: %v", err.Error()))
+		return
+	}
+	resource, err := newResource(document)
+	if err != nil {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("Failed to decode body: %v", err.Error()))
+		return
+	}
+	if id != (uuid.UUID{}) {
+		// Id was passed in from url, takes precedence
+		resource.Id = id
+	}
+
+	_, span = tracing.Start(req.Context(), "kinds.Put")
+	_, err = h.Registry.Put(name, resource)
+	span.SetError(err)
+	span.End()
+	if err == ErrUnknownKind {
+		handlerutil.WriteError(w, req, http.StatusNotFound, fmt.Sprintf("Unknown kind %q", name))
+		return
+	}
+	if err != nil {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("Validation failed: %v", err.Error()))
+		return
+	}
+	handlerutil.WriteYaml(w, req, http.StatusCreated, resource)
+}
+
+// DELETE /kinds/{kind}/resources/{id}
+func (h *Handler) HandleResourceDeleteWithId(w http.ResponseWriter, req *http.Request) {
+	name, id, ok := parseResourcePath(w, req)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.Registry.Delete(name, id)
+	if err == ErrUnknownKind {
+		handlerutil.WriteError(w, req, http.StatusNotFound, fmt.Sprintf("Unknown kind %q", name))
+		return
+	}
+	if !deleted {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Splits the id out of a decoded resource, the other properties are validated against the schema of the kind
+func newResource(document interface{}) (*Resource, error) {
+	properties, ok := document.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("resource must be an object")
+	}
+	resource := &Resource{Properties: properties}
+	if value, ok := properties[idProperty]; ok {
+		s, _ := value.(string)
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("id %v is not a valid UUID", value)
+		}
+		resource.Id = id
+		delete(properties, idProperty)
+	}
+	return resource, nil
+}
+
+func parseResourcePath(w http.ResponseWriter, req *http.Request) (string, uuid.UUID, bool) {
+	vars := mux.Vars(req)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("Error parsing ID: %v", err.Error()))
+		return "", uuid.UUID{}, false
+	}
+	return vars["kind"], id, true
+}
+
+// Returns the page based on paging parameters, the same as the metadata pages
+func pageResults(results []*Resource, offset int, pageSize int, req *http.Request) *ResultPage {
+	begin, end, nextLink := handlerutil.Page(len(results), offset, pageSize, req)
+	return &ResultPage{Resources: results[begin:end], NextLink: nextLink}
+}
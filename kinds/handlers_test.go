@@ -0,0 +1,171 @@
+package kinds
+
+import (
+	"APIServerExercise/config"
+	"APIServerExercise/ratelimit"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestRouter() *mux.Router {
+	auth := config.Defaults().Auth
+	auth.ApiKeys["adminkey"] = "admin"
+	auth.ApiKeys["readerkey"] = "reader"
+	handler := &Handler{Registry: NewRegistry(false), Auth: &auth, MaxPageSize: 50}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/kinds", handler.HandleKindsGet).Methods(http.MethodGet)
+	r.HandleFunc("/kinds/{kind}", handler.HandleKindGet).Methods(http.MethodGet)
+	r.HandleFunc("/kinds/{kind}", handler.HandleKindPut).Methods(http.MethodPut)
+	r.HandleFunc("/kinds/{kind}", handler.HandleKindDelete).Methods(http.MethodDelete)
+	r.HandleFunc("/kinds/{kind}/resources", handler.HandleResourcesGet).Methods(http.MethodGet)
+	r.HandleFunc("/kinds/{kind}/resources", handler.HandleResourcePut).Methods(http.MethodPut)
+	r.HandleFunc("/kinds/{kind}/resources/{id}", handler.HandleResourceGetWithId).Methods(http.MethodGet)
+	r.HandleFunc("/kinds/{kind}/resources/{id}", handler.HandleResourcePutWithId).Methods(http.MethodPut)
+	r.HandleFunc("/kinds/{kind}/resources/{id}", handler.HandleResourceDeleteWithId).Methods(http.MethodDelete)
+	return r
+}
+
+func serve(r *mux.Router, method string, path string, apiKey string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if apiKey != "" {
+		request.Header.Set(ratelimit.ApiKeyHeader, apiKey)
+	}
+	responseRecorder := httptest.NewRecorder()
+	r.ServeHTTP(responseRecorder, request)
+	return responseRecorder
+}
+
+func putResource(t *testing.T, r *mux.Router, body string) map[string]interface{} {
+	responseRecorder := serve(r, http.MethodPut, "/kinds/plugins/resources", "", body)
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code, responseRecorder.Body.String())
+	var resource map[string]interface{}
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), &resource))
+	return resource
+}
+
+func TestHandler_Kinds(t *testing.T) {
+	r := newTestRouter()
+
+	responseRecorder := serve(r, http.MethodPut, "/kinds/plugins", "", pluginSchema)
+	assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+	responseRecorder = serve(r, http.MethodPut, "/kinds/plugins", "readerkey", pluginSchema)
+	assert.Equal(t, http.StatusForbidden, responseRecorder.Code)
+	assert.Equal(t, "Role reader is not allowed to register kinds\n", responseRecorder.Body.String())
+
+	responseRecorder = serve(r, http.MethodPut, "/kinds/plugins", "adminkey", pluginSchema)
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	var kind Kind
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), &kind))
+	assert.Equal(t, "plugins", kind.Name)
+	assert.Equal(t, "object", kind.Schema["type"])
+
+	responseRecorder = serve(r, http.MethodGet, "/kinds/plugins", "", "")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	responseRecorder = serve(r, http.MethodGet, "/kinds/datasets", "", "")
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+
+	responseRecorder = serve(r, http.MethodPut, "/kinds/Plugins", "adminkey", pluginSchema)
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	responseRecorder = serve(r, http.MethodPut, "/kinds/datasets", "adminkey", "type: object\nproperties: {id: {type: string}}")
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, "invalid schema: property \"id\" is reserved for the resource id\n", responseRecorder.Body.String())
+	responseRecorder = serve(r, http.MethodPut, "/kinds/datasets", "adminkey", "type: object\nallOf: []")
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+
+	putResource(t, r, "name: scanner\nversion: 1.0.0\nowner: {email: a@hotmail.com}")
+
+	// The new schema rejects the existing resource
+	responseRecorder = serve(r, http.MethodPut, "/kinds/plugins", "adminkey", "type: object\nrequired: [stage]")
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+	responseRecorder = serve(r, http.MethodDelete, "/kinds/plugins", "adminkey", "")
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+
+	responseRecorder = serve(r, http.MethodGet, "/kinds", "", "")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var summaries []Summary
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), &summaries))
+	assert.Equal(t, []Summary{{Name: "plugins", Resources: 1}}, summaries)
+}
+
+func TestHandler_Resources(t *testing.T) {
+	r := newTestRouter()
+	serve(r, http.MethodPut, "/kinds/plugins", "adminkey", pluginSchema)
+
+	scanner := putResource(t, r, `{"name": "scanner", "version": "1.0.0", "owner": {"email": "a@hotmail.com"},
+		"tags": ["security", "ci"], "labels": {"tier": "critical"}}`)
+	linter := putResource(t, r, "name: linter\nversion: 2.0.0\nowner: {email: b@hotmail.com}\ntags: [ci]")
+	assert.Equal(t, "scanner", scanner["name"])
+
+	responseRecorder := serve(r, http.MethodGet, fmt.Sprintf("/kinds/plugins/resources/%s", scanner["id"]), "", "")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.True(t, strings.HasPrefix(responseRecorder.Body.String(), fmt.Sprintf("id: %s\n", scanner["id"])))
+
+	for query, expected := range map[string][]interface{}{
+		"tags=ci":                     {"scanner", "linter"},
+		"owner.email=b@hotmail.com":   {"linter"},
+		"labels.tier=critical":        {"scanner"},
+		"labelSelector=!tier":         {"linter"},
+		"tags[ne]=security":           {"linter"},
+		"id=" + linter["id"].(string): {"linter"},
+		"tags=ci&pageSize=1":          {"scanner"},
+	} {
+		responseRecorder = serve(r, http.MethodGet, "/kinds/plugins/resources?"+query, "", "")
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, query)
+		var page struct {
+			Resources []map[string]interface{} `yaml:"resources"`
+		}
+		assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), &page))
+		var names []interface{}
+		for _, resource := range page.Resources {
+			names = append(names, resource["name"])
+		}
+		assert.Equal(t, expected, names, query)
+	}
+
+	responseRecorder = serve(r, http.MethodGet, "/kinds/plugins/resources?owner.mail=a@hotmail.com", "", "")
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, "unknown field \"owner.mail\", did you mean \"owner.email\"?\n", responseRecorder.Body.String())
+
+	// Replacing the resource updates the index
+	responseRecorder = serve(r, http.MethodPut, fmt.Sprintf("/kinds/plugins/resources/%s", linter["id"]), "",
+		"name: linter\nversion: 2.1.0\nowner: {email: b@hotmail.com}")
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	responseRecorder = serve(r, http.MethodGet, "/kinds/plugins/resources?tags=ci", "", "")
+	assert.NotContains(t, responseRecorder.Body.String(), "linter")
+
+	responseRecorder = serve(r, http.MethodDelete, fmt.Sprintf("/kinds/plugins/resources/%s", linter["id"]), "", "")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	responseRecorder = serve(r, http.MethodGet, fmt.Sprintf("/kinds/plugins/resources/%s", linter["id"]), "", "")
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+}
+
+func TestHandler_Resources_Invalid(t *testing.T) {
+	r := newTestRouter()
+	serve(r, http.MethodPut, "/kinds/plugins", "adminkey", pluginSchema)
+
+	responseRecorder := serve(r, http.MethodPut, "/kinds/plugins/resources", "", "name: scanner\nversion: 1")
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, "Validation failed: resource: missing properties: 'owner'\nversion: expected string, but got number\n", responseRecorder.Body.String())
+
+	responseRecorder = serve(r, http.MethodPut, "/kinds/plugins/resources", "", "[a]")
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	responseRecorder = serve(r, http.MethodPut, "/kinds/plugins/resources", "", "id: 1\nname: scanner")
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, "Failed to decode body: id 1 is not a valid UUID\n", responseRecorder.Body.String())
+
+	responseRecorder = serve(r, http.MethodPut, "/kinds/datasets/resources", "", "name: a")
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+	responseRecorder = serve(r, http.MethodGet, "/kinds/datasets/resources", "", "")
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+	responseRecorder = serve(r, http.MethodGet, "/kinds/plugins/resources/badId", "", "")
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	responseRecorder = serve(r, http.MethodGet, "/kinds/plugins/resources?pageSize=51", "", "")
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+}
@@ -0,0 +1,278 @@
+package kinds
+
+import (
+	"APIServerExercise/search"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Property holding the id of a resource, it is not part of the schema of a kind
+const idProperty = "id"
+
+// Names of kinds are used in paths, IE: /kinds/plugins/resources
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,62}$`)
+
+var (
+	// The kind is not registered
+	ErrUnknownKind = errors.New("unknown kind")
+	// The kind still has resources and can not be unregistered
+	ErrKindInUse = errors.New("kind has resources")
+)
+
+// A new schema of a kind rejects one of its existing resources
+type IncompatibleSchemaError struct {
+	Id  uuid.UUID
+	Err error
+}
+
+func (e *IncompatibleSchemaError) Error() string {
+	return fmt.Sprintf("schema rejects resource %s: %v", e.Id, e.Err)
+}
+
+// A resource of a registered kind, its id and the properties described by the schema of the kind
+type Resource struct {
+	Id         uuid.UUID
+	Properties map[string]interface{}
+}
+
+// Writes the id first, then the properties sorted by name
+func (r *Resource) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: idProperty},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: r.Id.String()})
+
+	names := make([]string, 0, len(r.Properties))
+	for name := range r.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := &yaml.Node{}
+		if err := value.Encode(r.Properties[name]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, value)
+	}
+	return node, nil
+}
+
+// Page of the resources of a kind, the same as core.ResultPage for the metadata
+type ResultPage struct {
+	Resources []*Resource `yaml:"resources"`
+	NextLink  string      `yaml:"nextLink"`
+}
+
+// A kind registered at runtime with its schema, resources and index
+type Kind struct {
+	Name string `yaml:"name"`
+	// Schema document as registered, IE: {type: object, required: [name], properties: {...}}
+	Schema    map[string]interface{} `yaml:"schema"`
+	schema    *Schema
+	fields    search.FieldSet
+	resources map[uuid.UUID]*Resource
+	ordering  []uuid.UUID // to keep default ordering
+	searcher  *search.Searcher
+}
+
+// Name of a kind and its number of resources, listed by GET /kinds
+type Summary struct {
+	Name      string `yaml:"name"`
+	Resources int    `yaml:"resources"`
+}
+
+// Kinds registered at runtime and their resources
+// Safe for concurrent use, every resource is validated against the schema of its kind.
+type Registry struct {
+	// Same as the index of the metadata, only whole values are indexed when true
+	DisableIndexWords bool
+	mutex             sync.RWMutex
+	kinds             map[string]*Kind
+}
+
+func NewRegistry(disableIndexWords bool) *Registry {
+	return &Registry{DisableIndexWords: disableIndexWords, kinds: map[string]*Kind{}}
+}
+
+// Registers the kind, or replaces its schema
+// A new schema must accept every existing resource of the kind. Returns whether the kind was created.
+func (r *Registry) Register(name string, document interface{}) (bool, error) {
+	if !namePattern.MatchString(name) {
+		return false, fmt.Errorf("invalid kind name %q, must be lower case letters, digits and dashes, "+
+			"starting with a letter and at most 63 characters", name)
+	}
+	object, ok := document.(map[string]interface{})
+	if !ok {
+		return false, fmt.Errorf("invalid schema: must be an object")
+	}
+	schema, err := Compile(object)
+	if err != nil {
+		return false, err
+	}
+	if !schema.isObject() {
+		return false, fmt.Errorf("invalid schema: type must be object")
+	}
+	if schema.hasProperty(idProperty) {
+		return false, fmt.Errorf("invalid schema: property %q is reserved for the resource id", idProperty)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	kind, exists := r.kinds[name]
+	if !exists {
+		kind = &Kind{
+			Name:      name,
+			resources: map[uuid.UUID]*Resource{},
+			searcher: &search.Searcher{
+				Index:             map[string]map[string]map[uuid.UUID]bool{},
+				DisableIndexWords: r.DisableIndexWords,
+			},
+		}
+	}
+	for _, id := range kind.ordering {
+		if err := schema.Validate(kind.resources[id].Properties); err != nil {
+			return false, &IncompatibleSchemaError{Id: id, Err: err}
+		}
+	}
+
+	kind.Schema = object
+	kind.schema = schema
+	kind.fields = schema.Fields()
+	r.kinds[name] = kind
+	return !exists, nil
+}
+
+// Removes the kind, only once it has no resources
+func (r *Registry) Unregister(name string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	kind, ok := r.kinds[name]
+	if !ok {
+		return ErrUnknownKind
+	}
+	if len(kind.resources) > 0 {
+		return ErrKindInUse
+	}
+	delete(r.kinds, name)
+	return nil
+}
+
+// Returns the name and schema of the kind
+func (r *Registry) Kind(name string) (*Kind, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	kind, ok := r.kinds[name]
+	if !ok {
+		return nil, false
+	}
+	return &Kind{Name: kind.Name, Schema: kind.Schema}, true
+}
+
+// Returns every kind, sorted by name
+func (r *Registry) Summaries() []Summary {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	summaries := make([]Summary, 0, len(r.kinds))
+	for name, kind := range r.kinds {
+		summaries = append(summaries, Summary{Name: name, Resources: len(kind.resources)})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries
+}
+
+// Validates the resource against the schema of its kind and saves it, replacing the resource with the same id
+// A new id is generated when the resource has none. Returns whether the resource was created.
+func (r *Registry) Put(name string, resource *Resource) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	kind, ok := r.kinds[name]
+	if !ok {
+		return false, ErrUnknownKind
+	}
+	if err := kind.schema.Validate(resource.Properties); err != nil {
+		return false, err
+	}
+	if resource.Id == (uuid.UUID{}) {
+		resource.Id = uuid.New()
+	}
+
+	_, exists := kind.resources[resource.Id]
+	if exists {
+		kind.searcher.RemoveFromIndex(resource.Id)
+	} else {
+		kind.ordering = append(kind.ordering, resource.Id)
+	}
+	kind.resources[resource.Id] = resource
+	kind.searcher.AddDocumentToIndex(resource.Properties, resource.Id, "")
+	kind.searcher.AddDocumentToIndex(map[string]interface{}{idProperty: resource.Id.String()}, resource.Id, "")
+	return !exists, nil
+}
+
+// Returns the resource, ErrUnknownKind when the kind is not registered
+func (r *Registry) Get(name string, id uuid.UUID) (*Resource, bool, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	kind, ok := r.kinds[name]
+	if !ok {
+		return nil, false, ErrUnknownKind
+	}
+	resource, ok := kind.resources[id]
+	return resource, ok, nil
+}
+
+// Removes the resource, returns false if it does not exist
+func (r *Registry) Delete(name string, id uuid.UUID) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	kind, ok := r.kinds[name]
+	if !ok {
+		return false, ErrUnknownKind
+	}
+	if _, ok := kind.resources[id]; !ok {
+		return false, nil
+	}
+
+	kind.searcher.RemoveFromIndex(id)
+	delete(kind.resources, id)
+	for index, existingId := range kind.ordering {
+		if id == existingId {
+			kind.ordering = append(kind.ordering[:index], kind.ordering[index+1:]...)
+			break
+		}
+	}
+	return true, nil
+}
+
+// Returns the resources of the kind matching the query, in the default ordering
+// Queries are the same as for the metadata, validated against the fields of the schema.
+func (r *Registry) Filter(ctx context.Context, name string, query map[string][]string) ([]*Resource, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	kind, ok := r.kinds[name]
+	if !ok {
+		return nil, ErrUnknownKind
+	}
+	ids, err := kind.searcher.FilterDocuments(ctx, query, kind.fields, kind.ordering)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*Resource, 0, len(ids))
+	for _, id := range ids {
+		results = append(results, kind.resources[id])
+	}
+	return results, nil
+}
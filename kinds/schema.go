@@ -0,0 +1,254 @@
+package kinds
+
+import (
+	"APIServerExercise/search"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Location the schema of a kind is compiled at, references inside the schema are resolved against it
+const schemaURL = "urn:apiserverexercise:kind"
+
+// Compiled JSON Schema describing the resources of a kind
+// Every keyword of the drafts 4 to 2020-12 is supported, 2020-12 unless $schema names another draft. format is
+// asserted, and $ref can only refer to the schema itself, IE: #/$defs/owner.
+type Schema struct {
+	schema *jsonschema.Schema
+}
+
+// Every value of a resource that does not match the schema of its kind
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "\n")
+}
+
+// Compiles a JSON Schema document decoded from YAML or JSON
+func Compile(document interface{}) (*Schema, error) {
+	content, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat = true
+	compiler.LoadURL = func(url string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("can not load %s, $ref can only refer to the schema itself", url)
+	}
+	if err := compiler.AddResource(schemaURL, bytes.NewReader(content)); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	schema, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, schemaError(err)
+	}
+	return &Schema{schema: schema}, nil
+}
+
+// Describes why the schema does not compile, IE: invalid schema at properties.name.type: value must be one of ...
+func schemaError(err error) error {
+	var schemaErr *jsonschema.SchemaError
+	if errors.As(err, &schemaErr) {
+		err = schemaErr.Err
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		// References are relative to the schema, IE: #/$defs/missing not found
+		message := strings.ReplaceAll(strings.TrimPrefix(err.Error(), "jsonschema: "), schemaURL, "")
+		return fmt.Errorf("invalid schema: %s", message)
+	}
+	messages := problems(validationErr)
+	for i, message := range messages {
+		messages[i] = "invalid schema at " + message
+	}
+	return errors.New(strings.Join(messages, "\n"))
+}
+
+// Returns a ValidationError listing every value that does not match the schema
+func (s *Schema) Validate(value interface{}) error {
+	err := s.schema.Validate(value)
+	var validationErr *jsonschema.ValidationError
+	if errors.As(err, &validationErr) {
+		return &ValidationError{Problems: problems(validationErr)}
+	}
+	return err
+}
+
+// The causes of the error that have no causes themselves, IE: owner: missing properties: 'email'
+// Sorted by the location of the value, each once.
+func problems(err *jsonschema.ValidationError) []string {
+	seen := map[string]bool{}
+	var result []string
+	var add func(err *jsonschema.ValidationError)
+	add = func(err *jsonschema.ValidationError) {
+		if len(err.Causes) > 0 {
+			for _, cause := range err.Causes {
+				add(cause)
+			}
+			return
+		}
+		problem := fmt.Sprintf("%s: %s", dottedPath(err.InstanceLocation), err.Message)
+		if !seen[problem] {
+			seen[problem] = true
+			result = append(result, problem)
+		}
+	}
+	add(err)
+	sort.Strings(result)
+	return result
+}
+
+// JSON pointer as a dotted path, IE: /owner/email -> owner.email, resource for the root
+func dottedPath(pointer string) string {
+	if pointer == "" {
+		return "resource"
+	}
+	segments := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+	}
+	return strings.Join(segments, ".")
+}
+
+// Whether the schema only accepts objects
+func (s *Schema) isObject() bool {
+	types := typesOf(s.schema)
+	return len(types) == 1 && types[0] == "object"
+}
+
+// Whether the schema defines the property, directly or through the schemas it refers to or combines
+func (s *Schema) hasProperty(name string) bool {
+	_, ok := propertiesOf(s.schema)[name]
+	return ok
+}
+
+// Returns the fields of the resources that can be filtered on, in the form AddDocumentToIndex indexes them
+// Objects without properties, IE: labels, have a field per key unless additionalProperties is false.
+// Arrays are not fields themselves, their elements are indexed under the name of the array. The properties of the
+// schemas referred to with $ref or combined with allOf, anyOf and oneOf are fields too.
+func (s *Schema) Fields() search.FieldSet {
+	fields := search.FieldSet{Names: []string{idProperty}}
+	addFields(s.schema, &fields, "", map[*jsonschema.Schema]bool{})
+	sort.Strings(fields.Names)
+	sort.Strings(fields.Maps)
+	return fields
+}
+
+// Adds the fields of the properties of the schema, visiting recursive schemas once per path
+func addFields(schema *jsonschema.Schema, fields *search.FieldSet, prefix string, visiting map[*jsonschema.Schema]bool) {
+	if visiting[schema] {
+		return
+	}
+	visiting[schema] = true
+	defer delete(visiting, schema)
+
+	for name, property := range propertiesOf(schema) {
+		name = join(prefix, name)
+		property = elementOf(property)
+		types := typesOf(property)
+		switch {
+		case len(propertiesOf(property)) > 0:
+			addFields(property, fields, name, visiting)
+		case len(types) == 1 && types[0] == "object":
+			if allowsAdditionalProperties(property) {
+				fields.Maps = append(fields.Maps, name)
+			}
+		default:
+			fields.Names = append(fields.Names, name)
+		}
+	}
+}
+
+// The schemas applying to the values of the schema: itself, those it refers to and those it combines
+func appliedSchemas(schema *jsonschema.Schema) []*jsonschema.Schema {
+	var applied []*jsonschema.Schema
+	seen := map[*jsonschema.Schema]bool{}
+	var add func(schema *jsonschema.Schema)
+	add = func(schema *jsonschema.Schema) {
+		if schema == nil || seen[schema] {
+			return
+		}
+		seen[schema] = true
+		applied = append(applied, schema)
+		add(schema.Ref)
+		for _, combined := range [][]*jsonschema.Schema{schema.AllOf, schema.AnyOf, schema.OneOf} {
+			for _, s := range combined {
+				add(s)
+			}
+		}
+	}
+	add(schema)
+	return applied
+}
+
+func propertiesOf(schema *jsonschema.Schema) map[string]*jsonschema.Schema {
+	properties := map[string]*jsonschema.Schema{}
+	for _, applied := range appliedSchemas(schema) {
+		for name, property := range applied.Properties {
+			if _, ok := properties[name]; !ok {
+				properties[name] = property
+			}
+		}
+	}
+	return properties
+}
+
+// The types the schema accepts, the first ones declared by the schema or the schemas it refers to, any when empty
+func typesOf(schema *jsonschema.Schema) []string {
+	for _, applied := range appliedSchemas(schema) {
+		if len(applied.Types) > 0 {
+			return applied.Types
+		}
+	}
+	return nil
+}
+
+func allowsAdditionalProperties(schema *jsonschema.Schema) bool {
+	for _, applied := range appliedSchemas(schema) {
+		if allowed, ok := applied.AdditionalProperties.(bool); ok && !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// The schema of the elements of the arrays the schema describes, of their elements for nested arrays, the schema
+// itself when it does not describe arrays
+func elementOf(schema *jsonschema.Schema) *jsonschema.Schema {
+	seen := map[*jsonschema.Schema]bool{}
+	for !seen[schema] {
+		seen[schema] = true
+		items := itemsOf(schema)
+		if items == nil {
+			return schema
+		}
+		schema = items
+	}
+	return schema
+}
+
+func itemsOf(schema *jsonschema.Schema) *jsonschema.Schema {
+	for _, applied := range appliedSchemas(schema) {
+		if applied.Items2020 != nil {
+			return applied.Items2020
+		}
+		if items, ok := applied.Items.(*jsonschema.Schema); ok {
+			return items
+		}
+	}
+	return nil
+}
+
+func join(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,178 @@
+package kinds
+
+import (
+	"APIServerExercise/search"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const pluginSchema = `
+type: object
+required: [name, version, owner]
+additionalProperties: false
+properties:
+  name: {type: string, minLength: 1, maxLength: 20}
+  version: {type: string, pattern: '^\d+\.\d+\.\d+$'}
+  released: {type: string, format: date}
+  downloads: {type: integer, minimum: 0}
+  stage: {enum: [alpha, beta, stable]}
+  owner:
+    type: object
+    required: [email]
+    properties:
+      email: {type: string, format: email}
+  tags: {type: array, items: {type: string}, uniqueItems: true, maxItems: 3}
+  labels: {type: object, additionalProperties: {type: string}}
+`
+
+func mustDecode(t *testing.T, document string) interface{} {
+	value, err := decode(strings.NewReader(document))
+	assert.Nil(t, err)
+	return value
+}
+
+func mustCompile(t *testing.T, document string) *Schema {
+	schema, err := Compile(mustDecode(t, document))
+	assert.Nil(t, err)
+	return schema
+}
+
+func TestDecode(t *testing.T) {
+	value := mustDecode(t, `{"name": "a", "count": 2, "ratio": 0.5, "released": 2001-12-14, "tags": [null, true]}`)
+	assert.Equal(t, map[string]interface{}{
+		"name":     "a",
+		"count":    2,
+		"ratio":    0.5,
+		"released": "2001-12-14",
+		"tags":     []interface{}{nil, true},
+	}, value)
+
+	_, err := decode(strings.NewReader("a: 1\na: 2"))
+	assert.Error(t, err)
+	_, err = decode(strings.NewReader("1: a"))
+	assert.Error(t, err)
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema := mustCompile(t, pluginSchema)
+
+	valid := mustDecode(t, `
+name: scanner
+version: 1.2.0
+released: 2023-01-31
+downloads: 12
+stage: beta
+owner: {email: a@hotmail.com}
+tags: [security, ci]
+labels: {tier: critical}
+`)
+	assert.Nil(t, schema.Validate(valid))
+
+	invalid := mustDecode(t, `
+name: ""
+version: latest
+released: yesterday
+downloads: 1.5
+stage: gamma
+owner: {}
+tags: [a, a, b, c]
+labels: {tier: 1}
+color: red
+`)
+	err := schema.Validate(invalid)
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"downloads: expected integer, but got number",
+		"labels.tier: expected string, but got number",
+		"name: length must be >= 1, but got 0",
+		"owner: missing properties: 'email'",
+		"released: 'yesterday' is not valid 'date'",
+		"resource: additionalProperties 'color' not allowed",
+		`stage: value must be one of "alpha", "beta", "stable"`,
+		"tags: items at index 0 and 1 are equal",
+		"tags: maximum 3 items required, but found 4 items",
+		`version: does not match pattern '^\\d+\\.\\d+\\.\\d+$'`,
+	}, err.(*ValidationError).Problems)
+}
+
+// References and combinations are supported, references only inside the schema
+func TestSchema_Validate_WithReferences(t *testing.T) {
+	schema := mustCompile(t, `
+type: object
+$defs:
+  person:
+    type: object
+    required: [email]
+    properties:
+      email: {type: string, format: email}
+properties:
+  owner: {$ref: "#/$defs/person"}
+  contact:
+    oneOf:
+      - {$ref: "#/$defs/person"}
+      - {type: string, format: uri}
+  version:
+    allOf:
+      - {type: string}
+      - {pattern: '^\d+'}
+`)
+
+	assert.Nil(t, schema.Validate(mustDecode(t, `{owner: {email: a@hotmail.com}, contact: https://website.com, version: 1.0.0}`)))
+	err := schema.Validate(mustDecode(t, `{owner: {}, contact: {email: a@hotmail.com}, version: v1}`))
+	assert.Equal(t, []string{
+		"owner: missing properties: 'email'",
+		`version: does not match pattern '^\\d+'`,
+	}, err.(*ValidationError).Problems)
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for document, expected := range map[string]string{
+		`{type: thing}`: "invalid schema at type: expected array, but got string\n" +
+			`invalid schema at type: value must be one of "array", "boolean", "integer", "null", "number", "object", "string"`,
+		`{properties: {a: {pattern: "("}}}`:  "invalid schema at properties.a.pattern: '(' is not valid 'regex'",
+		`{properties: {a: {minLength: -1}}}`: "invalid schema at properties.a.minLength: must be >= 0 but found -1",
+		`{properties: {a: {items: {type: 1}}}}`: "invalid schema at properties.a.items.type: expected array, but got number\n" +
+			`invalid schema at properties.a.items.type: value must be one of "array", "boolean", "integer", "null", "number", "object", "string"`,
+		`{properties: {a: {required: [1]}}}`:                      "invalid schema at properties.a.required.0: expected string, but got number",
+		`{additionalProperties: {maximum: "1"}}`:                  "invalid schema at additionalProperties.maximum: expected number, but got string",
+		`{properties: {a: {$ref: "#/$defs/missing"}}}`:            "invalid schema: #/$defs/missing not found",
+		`{properties: {a: {$ref: "https://example.com/a.json"}}}`: "invalid schema: can not load https://example.com/a.json, $ref can only refer to the schema itself",
+	} {
+		_, err := Compile(mustDecode(t, document))
+		if assert.Error(t, err, document) {
+			assert.Equal(t, expected, err.Error(), document)
+		}
+	}
+}
+
+func TestSchema_Fields(t *testing.T) {
+	schema := mustCompile(t, pluginSchema)
+
+	assert.Equal(t, search.FieldSet{
+		Names: []string{"downloads", "id", "name", "owner.email", "released", "stage", "tags", "version"},
+		Maps:  []string{"labels"},
+	}, schema.Fields())
+}
+
+func TestSchema_Fields_WithReferences(t *testing.T) {
+	schema := mustCompile(t, `
+type: object
+$defs:
+  person:
+    type: object
+    properties:
+      email: {type: string}
+      manager: {$ref: "#/$defs/person"}
+properties:
+  owner: {$ref: "#/$defs/person"}
+  reviewers: {type: array, items: {$ref: "#/$defs/person"}}
+allOf:
+  - properties:
+      name: {type: string}
+`)
+
+	assert.Equal(t, search.FieldSet{
+		Names: []string{"id", "name", "owner.email", "owner.manager.email", "reviewers.email", "reviewers.manager.email"},
+	}, schema.Fields())
+}
@@ -0,0 +1,194 @@
+package licensepolicy
+
+import (
+	"APIServerExercise/core"
+	"fmt"
+	"strings"
+)
+
+// Category of a license, from the most to the least permissive
+type Category string
+
+const (
+	Allowed Category = "allow"
+	Review  Category = "review"
+	Denied  Category = "deny"
+
+	// Denied licenses fail the PUT
+	Reject = "reject"
+	// Denied licenses are saved with a warning
+	Warn = "warn"
+)
+
+// Categories in report order, from the most to the least permissive
+var Categories = []Category{Allowed, Review, Denied}
+
+// License lists of the policy
+// Entries are SPDX license identifiers, IE: GPL-3.0-only, matched case insensitively.
+type Config struct {
+	Allow  []string `yaml:"allow"`
+	Review []string `yaml:"review"`
+	Deny   []string `yaml:"deny"`
+	// Category of the licenses in none of the lists
+	Unlisted Category `yaml:"unlisted"`
+	// What a PUT does with a denied license, reject or warn
+	Enforcement string `yaml:"enforcement"`
+}
+
+// No license is listed and every license is allowed
+func DefaultConfig() Config {
+	return Config{Unlisted: Allowed, Enforcement: Warn}
+}
+
+func (c *Config) Validate() error {
+	if c.rank(c.Unlisted) < 0 {
+		return fmt.Errorf("licenses.unlisted must be %s, %s or %s", Allowed, Review, Denied)
+	}
+	if c.Enforcement != Reject && c.Enforcement != Warn {
+		return fmt.Errorf("licenses.enforcement must be %s or %s", Reject, Warn)
+	}
+	seen := map[string]Category{}
+	for _, category := range Categories {
+		for _, license := range c.list(category) {
+			parsed, err := core.ParseLicenseExpression(license)
+			if err != nil || parsed.Operator != "" || parsed.Exception != "" || parsed.OrLater {
+				return fmt.Errorf("licenses.%s: %q is not an SPDX license identifier", category, license)
+			}
+			if previous, ok := seen[parsed.License]; ok {
+				return fmt.Errorf("licenses.%s: %s is already in licenses.%s", category, parsed.License, previous)
+			}
+			seen[parsed.License] = category
+		}
+	}
+	return nil
+}
+
+func (c *Config) list(category Category) []string {
+	switch category {
+	case Allowed:
+		return c.Allow
+	case Review:
+		return c.Review
+	case Denied:
+		return c.Deny
+	}
+	return nil
+}
+
+// Position of the category in Categories, -1 for an unknown category
+func (c *Config) rank(category Category) int {
+	for i, known := range Categories {
+		if category == known {
+			return i
+		}
+	}
+	return -1
+}
+
+// Category of a license expression and the licenses responsible for it
+type Evaluation struct {
+	Category Category
+	// Licenses that are not allowed, IE: GPL-3.0-only for MIT AND GPL-3.0-only
+	Offending []string
+}
+
+// Sorts license expressions into categories
+// The config must be valid.
+type Policy struct {
+	Config Config
+	// Canonical license identifier -> category
+	categories map[string]Category
+}
+
+func New(config Config) *Policy {
+	p := &Policy{Config: config, categories: map[string]Category{}}
+	for _, category := range Categories {
+		for _, license := range config.list(category) {
+			p.categories[core.NormalizeLicense(license)] = category
+		}
+	}
+	return p
+}
+
+// Returns the category of the license expression
+// A choice (OR) takes its most permissive operand, a conjunction (AND) its least permissive one.
+// Exceptions and later versions take the category of their license. An invalid expression needs a review.
+func (p *Policy) Evaluate(expression string) Evaluation {
+	parsed, err := core.ParseLicenseExpression(expression)
+	if err != nil {
+		return Evaluation{Category: Review, Offending: []string{expression}}
+	}
+	return p.evaluate(parsed)
+}
+
+func (p *Policy) evaluate(expression *core.LicenseExpression) Evaluation {
+	if expression.Operator == "" {
+		category, ok := p.categories[expression.License]
+		if !ok {
+			category = p.Config.Unlisted
+		}
+		if category == Allowed {
+			return Evaluation{Category: Allowed}
+		}
+		return Evaluation{Category: category, Offending: []string{expression.License}}
+	}
+
+	operands := make([]Evaluation, 0, len(expression.Operands))
+	for _, operand := range expression.Operands {
+		operands = append(operands, p.evaluate(operand))
+	}
+
+	result := operands[0]
+	for _, operand := range operands[1:] {
+		better := p.Config.rank(operand.Category) < p.Config.rank(result.Category)
+		if expression.Operator == core.LicenseOr && better || expression.Operator == core.LicenseAnd && !better {
+			if operand.Category != result.Category {
+				result = Evaluation{Category: operand.Category}
+			}
+		}
+	}
+	// Every operand that is not allowed is responsible for a conjunction, only the chosen ones for a choice
+	for _, operand := range operands {
+		if operand.Category == result.Category || expression.Operator == core.LicenseAnd {
+			result.Offending = appendMissing(result.Offending, operand.Offending...)
+		}
+	}
+	return result
+}
+
+// Checks the license of a metadata being saved
+// Returns warnings for the licenses needing a review and, unless the policy rejects them, the denied licenses.
+// A nil policy allows every license.
+func (p *Policy) Check(license string) ([]string, error) {
+	if p == nil {
+		return nil, nil
+	}
+	evaluation := p.Evaluate(license)
+	offending := strings.Join(evaluation.Offending, ", ")
+	switch evaluation.Category {
+	case Denied:
+		if p.Config.Enforcement == Reject {
+			return nil, fmt.Errorf("license %s is denied by the license policy: %s", license, offending)
+		}
+		return []string{fmt.Sprintf("license %s is denied by the license policy: %s", license, offending)}, nil
+	case Review:
+		return []string{fmt.Sprintf("license %s requires a legal review: %s", license, offending)}, nil
+	}
+	return nil, nil
+}
+
+func appendMissing(values []string, others ...string) []string {
+	for _, other := range others {
+		found := false
+		for _, value := range values {
+			if value == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values = append(values, other)
+		}
+	}
+	return values
+}
@@ -0,0 +1,82 @@
+package licensepolicy
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestPolicy(enforcement string) *Policy {
+	return New(Config{
+		Allow:       []string{"MIT", "Apache-2.0", "BSD-3-Clause"},
+		Review:      []string{"LGPL-2.1-only", "gpl-3.0-only"},
+		Deny:        []string{"AGPL-3.0-only"},
+		Unlisted:    Review,
+		Enforcement: enforcement,
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := DefaultConfig()
+	assert.Nil(t, c.Validate())
+
+	c = Config{Allow: []string{"MIT"}, Deny: []string{"AGPL-3.0-only"}, Unlisted: Denied, Enforcement: Reject}
+	assert.Nil(t, c.Validate())
+
+	for config, expected := range map[*Config]string{
+		{Unlisted: "maybe", Enforcement: Warn}:                                                "licenses.unlisted must be allow, review or deny",
+		{Unlisted: Allowed, Enforcement: "block"}:                                             "licenses.enforcement must be reject or warn",
+		{Deny: []string{"MIT OR GPL-3.0-only"}, Unlisted: Allowed, Enforcement: Warn}:         `licenses.deny: "MIT OR GPL-3.0-only" is not an SPDX license identifier`,
+		{Review: []string{"Apache-5.0"}, Unlisted: Allowed, Enforcement: Warn}:                `licenses.review: "Apache-5.0" is not an SPDX license identifier`,
+		{Allow: []string{"MIT"}, Deny: []string{"mit"}, Unlisted: Allowed, Enforcement: Warn}: "licenses.deny: MIT is already in licenses.allow",
+	} {
+		err := config.Validate()
+		if assert.Error(t, err) {
+			assert.Equal(t, expected, err.Error())
+		}
+	}
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	policy := newTestPolicy(Warn)
+
+	for expression, expected := range map[string]Evaluation{
+		"MIT":                                    {Category: Allowed},
+		"gpl-3.0-only":                           {Category: Review, Offending: []string{"GPL-3.0-only"}},
+		"AGPL-3.0-only":                          {Category: Denied, Offending: []string{"AGPL-3.0-only"}},
+		"Zlib":                                   {Category: Review, Offending: []string{"Zlib"}},
+		"MIT OR AGPL-3.0-only":                   {Category: Allowed},
+		"GPL-3.0-only OR AGPL-3.0-only":          {Category: Review, Offending: []string{"GPL-3.0-only"}},
+		"MIT AND AGPL-3.0-only":                  {Category: Denied, Offending: []string{"AGPL-3.0-only"}},
+		"LGPL-2.1-only AND AGPL-3.0-only":        {Category: Denied, Offending: []string{"LGPL-2.1-only", "AGPL-3.0-only"}},
+		"(MIT OR GPL-3.0-only) AND BSD-3-Clause": {Category: Allowed},
+		"(Zlib OR GPL-3.0-only) AND AGPL-3.0-only": {Category: Denied, Offending: []string{"Zlib", "GPL-3.0-only", "AGPL-3.0-only"}},
+		"Apache-2.0 WITH LLVM-exception":           {Category: Allowed},
+		"not a license":                            {Category: Review, Offending: []string{"not a license"}},
+	} {
+		assert.Equal(t, expected, policy.Evaluate(expression), expression)
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	warnings, err := newTestPolicy(Warn).Check("MIT AND AGPL-3.0-only")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"license MIT AND AGPL-3.0-only is denied by the license policy: AGPL-3.0-only"}, warnings)
+
+	warnings, err = newTestPolicy(Reject).Check("MIT AND AGPL-3.0-only")
+	assert.Nil(t, warnings)
+	assert.Equal(t, "license MIT AND AGPL-3.0-only is denied by the license policy: AGPL-3.0-only", err.Error())
+
+	warnings, err = newTestPolicy(Reject).Check("GPL-3.0-only")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"license GPL-3.0-only requires a legal review: GPL-3.0-only"}, warnings)
+
+	warnings, err = newTestPolicy(Reject).Check("MIT")
+	assert.Nil(t, err)
+	assert.Empty(t, warnings)
+
+	// Without a policy every license is allowed
+	var policy *Policy
+	warnings, err = policy.Check("AGPL-3.0-only")
+	assert.Nil(t, err)
+	assert.Empty(t, warnings)
+}
@@ -0,0 +1,156 @@
+package licensepolicy
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/handlerutil"
+	"APIServerExercise/storage"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	formatParameter = "format"
+	csvFormat       = "csv"
+	csvContentType  = "text/csv"
+)
+
+// Header row of the CSV report
+var csvHeader = []string{"id", "title", "version", "company", "license", "category", "offending"}
+
+// Summary of the licenses of every metadata
+type Report struct {
+	Total      int              `yaml:"total"`
+	Categories []CategoryReport `yaml:"categories"`
+	// Metadata whose license is not allowed, in the default ordering
+	Offending []Entry `yaml:"offending"`
+}
+
+type CategoryReport struct {
+	Category Category `yaml:"category"`
+	Count    int      `yaml:"count"`
+	// Number of metadata per license expression, in the order they are first found
+	Licenses []LicenseCount `yaml:"licenses"`
+}
+
+type LicenseCount struct {
+	License string `yaml:"license"`
+	Count   int    `yaml:"count"`
+}
+
+// A metadata and the category of its license
+type Entry struct {
+	Id        string   `yaml:"id"`
+	Title     string   `yaml:"title"`
+	Version   string   `yaml:"version"`
+	Company   string   `yaml:"company"`
+	License   string   `yaml:"license"`
+	Category  Category `yaml:"category"`
+	Offending []string `yaml:"offending"`
+}
+
+// Returns an entry per metadata of the database, in the default ordering
+func (p *Policy) Entries(database *core.Database) []Entry {
+	entries := make([]Entry, 0, len(database.Ordering))
+	for _, id := range database.Ordering {
+		metadata, ok := database.Metadatas[id]
+		if !ok {
+			continue
+		}
+		evaluation := p.Evaluate(metadata.License)
+		entries = append(entries, Entry{
+			Id:        metadata.Id.String(),
+			Title:     metadata.Title,
+			Version:   metadata.Version,
+			Company:   metadata.Company,
+			License:   metadata.License,
+			Category:  evaluation.Category,
+			Offending: evaluation.Offending,
+		})
+	}
+	return entries
+}
+
+// Sorts the metadata of the database by license category
+// Every category is reported, even without metadata.
+func (p *Policy) Report(database *core.Database) *Report {
+	report := &Report{Categories: make([]CategoryReport, len(Categories)), Offending: []Entry{}}
+	for i, category := range Categories {
+		report.Categories[i] = CategoryReport{Category: category, Licenses: []LicenseCount{}}
+	}
+
+	for _, entry := range p.Entries(database) {
+		report.Total++
+		categoryReport := &report.Categories[p.Config.rank(entry.Category)]
+		categoryReport.Count++
+		found := false
+		for i := range categoryReport.Licenses {
+			if categoryReport.Licenses[i].License == entry.License {
+				categoryReport.Licenses[i].Count++
+				found = true
+				break
+			}
+		}
+		if !found {
+			categoryReport.Licenses = append(categoryReport.Licenses, LicenseCount{License: entry.License, Count: 1})
+		}
+		if entry.Category != Allowed {
+			report.Offending = append(report.Offending, entry)
+		}
+	}
+	return report
+}
+
+// Serves the license report of a database
+type ReportHandler struct {
+	Policy *Policy
+	Store  *storage.MetadataStore
+}
+
+// GET /reports/licenses
+// Returns the YAML summary, or one CSV row per metadata with format=csv or an Accept: text/csv header.
+func (h *ReportHandler) HandleReport(w http.ResponseWriter, req *http.Request) {
+	format := req.URL.Query().Get(formatParameter)
+	if format == "" && strings.Contains(req.Header.Get("Accept"), csvContentType) {
+		format = csvFormat
+	}
+
+	switch format {
+	case csvFormat:
+		w.Header().Set("Content-Type", csvContentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="licenses.csv"`)
+		w.WriteHeader(http.StatusOK)
+		writer := csv.NewWriter(w)
+		writer.Write(csvHeader)
+		var entries []Entry
+		h.Store.Read(func(database *core.Database) { entries = h.Policy.Entries(database) })
+		for _, entry := range entries {
+			writer.Write(csvRow(entry.Id, entry.Title, entry.Version, entry.Company, entry.License,
+				string(entry.Category), strings.Join(entry.Offending, " ")))
+		}
+		writer.Flush()
+	case "", "yaml":
+		var report *Report
+		h.Store.Read(func(database *core.Database) { report = h.Policy.Report(database) })
+		handlerutil.WriteYaml(w, req, http.StatusOK, report)
+	default:
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("unknown format %q, must be yaml or csv", format))
+	}
+}
+
+// Cells starting with one of these characters are run as formulas by spreadsheets
+const formulaPrefixes = "=+-@\t\r"
+
+// Returns the cells of a CSV row, a value that would run as a formula is prefixed with ' so it is shown as text
+// IE: =HYPERLINK("http://attacker.com") -> '=HYPERLINK("http://attacker.com")
+func csvRow(values ...string) []string {
+	row := make([]string, len(values))
+	for i, value := range values {
+		if value != "" && strings.ContainsAny(value[:1], formulaPrefixes) {
+			value = "'" + value
+		}
+		row[i] = value
+	}
+	return row
+}
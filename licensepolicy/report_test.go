@@ -0,0 +1,89 @@
+package licensepolicy
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/storage"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestDatabase(licenses ...string) *core.Database {
+	database := &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}}
+	for i, license := range licenses {
+		metadata := &core.Metadata{
+			Id:      uuid.New(),
+			Title:   fmt.Sprintf("App %d", i+1),
+			Version: "1.0.0",
+			Company: "Random Inc.",
+			License: license,
+		}
+		database.Metadatas[metadata.Id] = metadata
+		database.Ordering = append(database.Ordering, metadata.Id)
+	}
+	return database
+}
+
+func TestPolicy_Report(t *testing.T) {
+	database := newTestDatabase("MIT", "AGPL-3.0-only", "MIT", "GPL-3.0-only OR AGPL-3.0-only")
+
+	report := newTestPolicy(Warn).Report(database)
+
+	assert.Equal(t, 4, report.Total)
+	assert.Equal(t, []CategoryReport{
+		{Category: Allowed, Count: 2, Licenses: []LicenseCount{{License: "MIT", Count: 2}}},
+		{Category: Review, Count: 1, Licenses: []LicenseCount{{License: "GPL-3.0-only OR AGPL-3.0-only", Count: 1}}},
+		{Category: Denied, Count: 1, Licenses: []LicenseCount{{License: "AGPL-3.0-only", Count: 1}}},
+	}, report.Categories)
+	assert.Len(t, report.Offending, 2)
+	assert.Equal(t, "App 2", report.Offending[0].Title)
+	assert.Equal(t, []string{"AGPL-3.0-only"}, report.Offending[0].Offending)
+	assert.Equal(t, "App 4", report.Offending[1].Title)
+	assert.Equal(t, Review, report.Offending[1].Category)
+}
+
+func TestReportHandler_HandleReport(t *testing.T) {
+	database := newTestDatabase("MIT", "MIT AND AGPL-3.0-only")
+	handler := &ReportHandler{Policy: newTestPolicy(Warn), Store: &storage.MetadataStore{Database: database}}
+
+	responseRecorder := httptest.NewRecorder()
+	handler.HandleReport(responseRecorder, httptest.NewRequest(http.MethodGet, "/reports/licenses", nil))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "application/x-yaml", responseRecorder.Header().Get("Content-Type"))
+	var report Report
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), &report))
+	assert.Equal(t, 2, report.Total)
+	assert.Len(t, report.Categories, 3)
+	assert.Equal(t, "App 2", report.Offending[0].Title)
+
+	expected := "id,title,version,company,license,category,offending\n" +
+		fmt.Sprintf("%s,App 1,1.0.0,Random Inc.,MIT,allow,\n", database.Ordering[0]) +
+		fmt.Sprintf("%s,App 2,1.0.0,Random Inc.,MIT AND AGPL-3.0-only,deny,AGPL-3.0-only\n", database.Ordering[1])
+	responseRecorder = httptest.NewRecorder()
+	handler.HandleReport(responseRecorder, httptest.NewRequest(http.MethodGet, "/reports/licenses?format=csv", nil))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "text/csv", responseRecorder.Header().Get("Content-Type"))
+	assert.Equal(t, expected, responseRecorder.Body.String())
+
+	request := httptest.NewRequest(http.MethodGet, "/reports/licenses", nil)
+	request.Header.Set("Accept", "text/csv")
+	responseRecorder = httptest.NewRecorder()
+	handler.HandleReport(responseRecorder, request)
+	assert.Equal(t, expected, responseRecorder.Body.String())
+
+	responseRecorder = httptest.NewRecorder()
+	handler.HandleReport(responseRecorder, httptest.NewRequest(http.MethodGet, "/reports/licenses?format=pdf", nil))
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, "unknown format \"pdf\", must be yaml or csv\n", responseRecorder.Body.String())
+}
+
+// Values that spreadsheets would run as formulas are written as text
+func TestCsvRow(t *testing.T) {
+	assert.Equal(t,
+		[]string{"'=HYPERLINK(\"http://attacker.com\")", "'+1", "'-1", "'@SUM(A1)", "'\tcmd", "App 1", "", "a=b"},
+		csvRow("=HYPERLINK(\"http://attacker.com\")", "+1", "-1", "@SUM(A1)", "\tcmd", "App 1", "", "a=b"))
+}
@@ -0,0 +1,141 @@
+package lifecycle
+
+import (
+	"APIServerExercise/core"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Lifecycle states of a metadata
+const (
+	// Being written, hidden from default searches
+	Draft = "draft"
+	// The default
+	Published = "published"
+	// Still served, with a reason and optionally a replacement
+	Deprecated = "deprecated"
+	// No longer supported, hidden from default searches
+	Retired = "retired"
+)
+
+// Matches every lifecycle state in a query, IE: lifecycle=any
+const Any = "any"
+
+// States hidden from searches that do not filter on the lifecycle
+var Hidden = []string{Draft, Retired}
+
+// States each state can change to, besides itself
+// Retiring requires deprecating first and a retired metadata stays retired.
+var Transitions = map[string][]string{
+	Draft:      {Published},
+	Published:  {Deprecated},
+	Deprecated: {Published, Retired},
+	Retired:    {},
+}
+
+// The lifecycle of a metadata can not change from a state to the other
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	allowed := Transitions[e.From]
+	if len(allowed) == 0 {
+		return fmt.Sprintf("can not change the lifecycle from %s to %s, a %s metadata stays %s", e.From, e.To, e.From, e.From)
+	}
+	return fmt.Sprintf("can not change the lifecycle from %s to %s, only to %s", e.From, e.To, strings.Join(allowed, " or "))
+}
+
+// The deprecation of a metadata is missing or invalid
+type DeprecationError struct {
+	Reason string
+}
+
+func (e *DeprecationError) Error() string {
+	return e.Reason
+}
+
+// Returns the lifecycle of the metadata, published when it has none
+func Of(metadata *core.Metadata) string {
+	if metadata.Lifecycle == "" {
+		return Published
+	}
+	return metadata.Lifecycle
+}
+
+// Sets the lifecycle and the deprecation of a metadata about to replace existing, nil for a new metadata
+// Without a lifecycle, the metadata keeps the lifecycle of the metadata it replaces, or is published. Without a
+// deprecation, a deprecated or retired metadata keeps the deprecation it had. The deprecation date is set when the
+// metadata is deprecated and then kept. Returns a *TransitionError when the lifecycle can not change to the new one and
+// a *DeprecationError when a deprecated or retired metadata has no deprecation, when another metadata has one or when
+// the replacement does not exist.
+func Apply(metadata *core.Metadata, existing *core.Metadata, exists func(id uuid.UUID) bool, now time.Time) error {
+	if metadata.Lifecycle == "" {
+		metadata.Lifecycle = Published
+		if existing != nil {
+			metadata.Lifecycle = Of(existing)
+		}
+	}
+	if existing != nil && Of(existing) != metadata.Lifecycle && !contains(Transitions[Of(existing)], metadata.Lifecycle) {
+		return &TransitionError{From: Of(existing), To: metadata.Lifecycle}
+	}
+
+	if metadata.Lifecycle != Deprecated && metadata.Lifecycle != Retired {
+		if metadata.Deprecation != nil {
+			return &DeprecationError{Reason: fmt.Sprintf("a %s metadata can not have a deprecation", metadata.Lifecycle)}
+		}
+		return nil
+	}
+
+	var previous *core.Deprecation
+	if existing != nil {
+		previous = existing.Deprecation
+	}
+	if metadata.Deprecation == nil && previous != nil {
+		kept := *previous
+		metadata.Deprecation = &kept
+	}
+	if metadata.Deprecation == nil {
+		return &DeprecationError{Reason: fmt.Sprintf("deprecation.reason is required for a %s metadata", metadata.Lifecycle)}
+	}
+	if replacement := metadata.Deprecation.Replacement; replacement != "" {
+		id, err := uuid.Parse(replacement)
+		if err != nil || id == metadata.Id || !exists(id) {
+			return &DeprecationError{Reason: fmt.Sprintf("deprecation.replacement %s must be the id of another metadata", replacement)}
+		}
+		metadata.Deprecation.Replacement = id.String()
+	}
+	metadata.Deprecation.DeprecatedAt = now.UTC()
+	if previous != nil {
+		metadata.Deprecation.DeprecatedAt = previous.DeprecatedAt
+	}
+	return nil
+}
+
+// Sets the Deprecation, Sunset and Link headers of a deprecated or retired metadata
+// IE: Deprecation: @1577934245, Sunset: Fri, 01 Jan 2021 00:00:00 GMT and Link: </metadata/{replacement}>; rel="successor-version".
+func SetHeaders(header http.Header, metadata *core.Metadata) {
+	if metadata == nil || metadata.Deprecation == nil {
+		return
+	}
+	header.Set("Deprecation", fmt.Sprintf("@%d", metadata.Deprecation.DeprecatedAt.Unix()))
+	if !metadata.Deprecation.Sunset.IsZero() {
+		header.Set("Sunset", metadata.Deprecation.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if metadata.Deprecation.Replacement != "" {
+		header.Add("Link", fmt.Sprintf(`</metadata/%s>; rel="successor-version"`, metadata.Deprecation.Replacement))
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package lifecycle
+
+import (
+	"APIServerExercise/core"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func noneExists(uuid.UUID) bool {
+	return false
+}
+
+func TestApply_Defaults(t *testing.T) {
+	metadata := &core.Metadata{Id: uuid.New()}
+	assert.Nil(t, Apply(metadata, nil, noneExists, testNow))
+	assert.Equal(t, Published, metadata.Lifecycle)
+
+	// Keeps the lifecycle and the deprecation of the metadata it replaces
+	existing := &core.Metadata{Id: metadata.Id, Lifecycle: Deprecated,
+		Deprecation: &core.Deprecation{Reason: "Use the new one", DeprecatedAt: testNow.Add(-time.Hour)}}
+	metadata = &core.Metadata{Id: metadata.Id}
+	assert.Nil(t, Apply(metadata, existing, noneExists, testNow))
+	assert.Equal(t, Deprecated, metadata.Lifecycle)
+	assert.Equal(t, existing.Deprecation, metadata.Deprecation)
+	assert.NotSame(t, existing.Deprecation, metadata.Deprecation)
+}
+
+func TestApply_Transitions(t *testing.T) {
+	for _, test := range []struct {
+		from    string
+		to      string
+		allowed bool
+	}{
+		{Draft, Published, true},
+		{Draft, Deprecated, false},
+		{Published, Draft, false},
+		{Published, Deprecated, true},
+		{Published, Retired, false},
+		{Deprecated, Published, true},
+		{Deprecated, Retired, true},
+		{Retired, Retired, true},
+		{Retired, Deprecated, false},
+		{Retired, Published, false},
+	} {
+		existing := &core.Metadata{Id: uuid.New(), Lifecycle: test.from}
+		metadata := &core.Metadata{Id: existing.Id, Lifecycle: test.to}
+		if test.to == Deprecated || test.to == Retired {
+			metadata.Deprecation = &core.Deprecation{Reason: "Unmaintained"}
+		}
+		err := Apply(metadata, existing, noneExists, testNow)
+		if test.allowed {
+			assert.Nil(t, err, "%s to %s", test.from, test.to)
+		} else {
+			assert.IsType(t, &TransitionError{}, err, "%s to %s", test.from, test.to)
+		}
+	}
+
+	err := Apply(&core.Metadata{Lifecycle: Published}, &core.Metadata{Lifecycle: Retired}, noneExists, testNow)
+	assert.Equal(t, "can not change the lifecycle from retired to published, a retired metadata stays retired", err.Error())
+	err = Apply(&core.Metadata{Lifecycle: Draft}, &core.Metadata{Lifecycle: Deprecated}, noneExists, testNow)
+	assert.Equal(t, "can not change the lifecycle from deprecated to draft, only to published or retired", err.Error())
+}
+
+func TestApply_Deprecation(t *testing.T) {
+	replacement := uuid.New()
+	exists := func(id uuid.UUID) bool { return id == replacement }
+
+	metadata := &core.Metadata{Id: uuid.New(), Lifecycle: Deprecated,
+		Deprecation: &core.Deprecation{Reason: "Replaced", Replacement: replacement.String(), DeprecatedAt: testNow.Add(time.Hour)}}
+	assert.Nil(t, Apply(metadata, nil, exists, testNow))
+	// The deprecation date of the payload is ignored
+	assert.Equal(t, testNow, metadata.Deprecation.DeprecatedAt)
+
+	// The deprecation date is kept when updating the deprecation
+	updated := &core.Metadata{Id: metadata.Id, Lifecycle: Retired, Deprecation: &core.Deprecation{Reason: "Retired"}}
+	assert.Nil(t, Apply(updated, metadata, exists, testNow.Add(time.Hour)))
+	assert.Equal(t, testNow, updated.Deprecation.DeprecatedAt)
+	assert.Equal(t, "Retired", updated.Deprecation.Reason)
+
+	for _, test := range []struct {
+		metadata *core.Metadata
+		message  string
+	}{
+		{&core.Metadata{Lifecycle: Deprecated}, "deprecation.reason is required for a deprecated metadata"},
+		{&core.Metadata{Lifecycle: Published, Deprecation: &core.Deprecation{Reason: "Replaced"}},
+			"a published metadata can not have a deprecation"},
+		{&core.Metadata{Lifecycle: Deprecated, Deprecation: &core.Deprecation{Reason: "Replaced", Replacement: uuid.New().String()}},
+			"must be the id of another metadata"},
+		{&core.Metadata{Id: replacement, Lifecycle: Deprecated, Deprecation: &core.Deprecation{Reason: "Replaced", Replacement: replacement.String()}},
+			"must be the id of another metadata"},
+	} {
+		err := Apply(test.metadata, nil, exists, testNow)
+		assert.IsType(t, &DeprecationError{}, err)
+		assert.Contains(t, err.Error(), test.message)
+	}
+}
+
+func TestSetHeaders(t *testing.T) {
+	header := http.Header{}
+	SetHeaders(header, &core.Metadata{Lifecycle: Published})
+	assert.Empty(t, header)
+
+	replacement := uuid.New()
+	SetHeaders(header, &core.Metadata{Lifecycle: Deprecated, Deprecation: &core.Deprecation{
+		Reason:       "Replaced",
+		Replacement:  replacement.String(),
+		Sunset:       time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		DeprecatedAt: testNow,
+	}})
+	assert.Equal(t, "@1609502400", header.Get("Deprecation"))
+	assert.Equal(t, "Tue, 01 Jun 2021 00:00:00 GMT", header.Get("Sunset"))
+	assert.Equal(t, `</metadata/`+replacement.String()+`>; rel="successor-version"`, header.Get("Link"))
+}
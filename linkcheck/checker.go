@@ -0,0 +1,202 @@
+package linkcheck
+
+import (
+	"APIServerExercise/core"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Sent with every request so site owners can tell the checker apart
+const UserAgent = "APIServerExercise-linkcheck/1.0"
+
+// Statuses of sites that are up but refuse the checker, they are not broken
+var reachableStatuses = map[int]bool{
+	http.StatusUnauthorized:    true,
+	http.StatusForbidden:       true,
+	http.StatusTooManyRequests: true,
+}
+
+type Config struct {
+	// Time between two checks of every metadata, disabled when 0
+	Interval time.Duration `yaml:"interval"`
+	// Longest a request can take, redirects included
+	Timeout time.Duration `yaml:"timeout"`
+	// Largest number of requests in flight
+	Concurrency int `yaml:"concurrency"`
+	// Shortest time between the start of two requests to the same host
+	HostDelay time.Duration `yaml:"hostDelay"`
+}
+
+func DefaultConfig() Config {
+	return Config{Timeout: 10 * time.Second, Concurrency: 4, HostDelay: time.Second}
+}
+
+func (c *Config) Validate() error {
+	if c.Interval < 0 {
+		return fmt.Errorf("linkCheck.interval must not be negative")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("linkCheck.timeout must be greater than 0")
+	}
+	if c.Concurrency < 1 {
+		return fmt.Errorf("linkCheck.concurrency must be greater than 0")
+	}
+	if c.HostDelay < 0 {
+		return fmt.Errorf("linkCheck.hostDelay must not be negative")
+	}
+	return nil
+}
+
+// The links of a metadata
+type Target struct {
+	Id      uuid.UUID
+	Website *url.URL
+	Source  *url.URL
+}
+
+// Probes URLs with HEAD, or GET when the server does not support HEAD
+// Requests to the same host are spaced by the host delay.
+type Checker struct {
+	Config Config
+	// Uses a client with the configured timeout when nil
+	Client *http.Client
+	// Uses time.Now when nil
+	Now func() time.Time
+
+	mutex sync.Mutex
+	// Host -> earliest start of the next request to the host
+	nextRequest map[string]time.Time
+}
+
+func New(config Config) *Checker {
+	return &Checker{Config: config}
+}
+
+func (c *Checker) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func (c *Checker) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return &http.Client{Timeout: c.Config.Timeout}
+}
+
+// Requests the URL and returns the result
+// A URL is broken when the request fails or the response is an error, except 401, 403 and 429.
+func (c *Checker) Check(ctx context.Context, u *url.URL) *core.LinkCheck {
+	check := &core.LinkCheck{URL: u.String()}
+	response, err := c.request(ctx, http.MethodHead, u)
+	if err == nil && (response.StatusCode == http.StatusMethodNotAllowed || response.StatusCode == http.StatusNotImplemented) {
+		response.Body.Close()
+		response, err = c.request(ctx, http.MethodGet, u)
+	}
+	check.CheckedAt = c.now()
+	if err != nil {
+		check.Broken = true
+		check.Error = err.Error()
+		return check
+	}
+	response.Body.Close()
+
+	check.Status = response.StatusCode
+	check.Broken = response.StatusCode >= http.StatusBadRequest && !reachableStatuses[response.StatusCode]
+	if final := response.Request.URL.String(); final != u.String() {
+		check.RedirectTo = final
+	}
+	return check
+}
+
+func (c *Checker) request(ctx context.Context, method string, u *url.URL) (*http.Response, error) {
+	if err := c.wait(ctx, u.Hostname()); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	return c.client().Do(req)
+}
+
+// Waits for the turn of the next request to the host
+func (c *Checker) wait(ctx context.Context, host string) error {
+	c.mutex.Lock()
+	if c.nextRequest == nil {
+		c.nextRequest = map[string]time.Time{}
+	}
+	start := time.Now()
+	if next := c.nextRequest[host]; next.After(start) {
+		start = next
+	}
+	c.nextRequest[host] = start.Add(c.Config.HostDelay)
+	c.mutex.Unlock()
+
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Checks the website and source of every target, at most Config.Concurrency requests at once
+// Returns the link status of every target, or nothing when the context is cancelled before the end.
+func (c *Checker) CheckAll(ctx context.Context, targets []Target) map[uuid.UUID]*core.LinkStatus {
+	concurrency := c.Config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	results := make(map[uuid.UUID]*core.LinkStatus, len(targets))
+
+	check := func(u *url.URL, set func(check *core.LinkCheck)) {
+		if u == nil {
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			result := c.Check(ctx, u)
+			<-slots
+			mutex.Lock()
+			set(result)
+			mutex.Unlock()
+		}()
+	}
+
+	for _, target := range targets {
+		status := &core.LinkStatus{}
+		results[target.Id] = status
+		check(target.Website, func(check *core.LinkCheck) { status.Website = check })
+		check(target.Source, func(check *core.LinkCheck) { status.Source = check })
+	}
+	wg.Wait()
+	// Interrupted checks would be reported as broken
+	if ctx.Err() != nil {
+		return map[uuid.UUID]*core.LinkStatus{}
+	}
+
+	for _, status := range results {
+		status.Broken = (status.Website != nil && status.Website.Broken) || (status.Source != nil && status.Source.Broken)
+	}
+	return results
+}
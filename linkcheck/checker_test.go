@@ -0,0 +1,157 @@
+package linkcheck
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestChecker() *Checker {
+	return New(Config{Timeout: time.Second, Concurrency: 2})
+}
+
+func mustParse(t *testing.T, raw string) *url.URL {
+	u, err := url.Parse(raw)
+	assert.Nil(t, err)
+	return u
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := DefaultConfig()
+	assert.Nil(t, c.Validate())
+
+	for config, expected := range map[*Config]string{
+		{Interval: -time.Second, Timeout: time.Second, Concurrency: 1}: "linkCheck.interval must not be negative",
+		{Concurrency: 1}:       "linkCheck.timeout must be greater than 0",
+		{Timeout: time.Second}: "linkCheck.concurrency must be greater than 0",
+		{Timeout: time.Second, Concurrency: 1, HostDelay: -1}: "linkCheck.hostDelay must not be negative",
+	} {
+		err := config.Validate()
+		if assert.Error(t, err) {
+			assert.Equal(t, expected, err.Error())
+		}
+	}
+}
+
+func TestChecker_Check(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, UserAgent, req.UserAgent())
+		switch req.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/get-only":
+			methods = append(methods, req.Method)
+			if req.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/moved":
+			http.Redirect(w, req, "/ok", http.StatusMovedPermanently)
+		case "/private":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	checker := newTestChecker()
+	checker.Now = func() time.Time { return now }
+
+	check := checker.Check(context.Background(), mustParse(t, server.URL+"/ok"))
+	assert.Equal(t, server.URL+"/ok", check.URL)
+	assert.Equal(t, http.StatusOK, check.Status)
+	assert.False(t, check.Broken)
+	assert.Equal(t, now, check.CheckedAt)
+
+	check = checker.Check(context.Background(), mustParse(t, server.URL+"/get-only"))
+	assert.Equal(t, http.StatusOK, check.Status)
+	assert.Equal(t, []string{http.MethodHead, http.MethodGet}, methods)
+
+	check = checker.Check(context.Background(), mustParse(t, server.URL+"/moved"))
+	assert.Equal(t, http.StatusOK, check.Status)
+	assert.Equal(t, server.URL+"/ok", check.RedirectTo)
+
+	check = checker.Check(context.Background(), mustParse(t, server.URL+"/private"))
+	assert.Equal(t, http.StatusForbidden, check.Status)
+	assert.False(t, check.Broken, "the site is up but refuses the checker")
+
+	check = checker.Check(context.Background(), mustParse(t, server.URL+"/missing"))
+	assert.Equal(t, http.StatusNotFound, check.Status)
+	assert.True(t, check.Broken)
+
+	server.Close()
+	check = checker.Check(context.Background(), mustParse(t, server.URL+"/ok"))
+	assert.Equal(t, 0, check.Status)
+	assert.True(t, check.Broken)
+	assert.NotEmpty(t, check.Error)
+}
+
+func TestChecker_CheckAll(t *testing.T) {
+	var mutex sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mutex.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mutex.Lock()
+		inFlight--
+		mutex.Unlock()
+		if req.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	checker := newTestChecker()
+
+	var targets []Target
+	for i := 0; i < 4; i++ {
+		targets = append(targets, Target{Id: uuid.New(), Website: mustParse(t, server.URL+"/ok"), Source: mustParse(t, server.URL+"/ok")})
+	}
+	targets[0].Source = mustParse(t, server.URL+"/missing")
+	targets[1].Source = nil
+
+	results := checker.CheckAll(context.Background(), targets)
+	assert.Len(t, results, 4)
+	assert.LessOrEqual(t, maxInFlight, 2)
+	assert.True(t, results[targets[0].Id].Broken)
+	assert.False(t, results[targets[0].Id].Website.Broken)
+	assert.True(t, results[targets[0].Id].Source.Broken)
+	assert.False(t, results[targets[1].Id].Broken)
+	assert.Nil(t, results[targets[1].Id].Source)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Empty(t, checker.CheckAll(ctx, targets), "interrupted checks are not reported")
+}
+
+func TestChecker_HostDelay(t *testing.T) {
+	var mutex sync.Mutex
+	var starts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		starts = append(starts, time.Now())
+		mutex.Unlock()
+	}))
+	defer server.Close()
+	checker := New(Config{Timeout: time.Second, Concurrency: 3, HostDelay: 30 * time.Millisecond})
+
+	targets := []Target{
+		{Id: uuid.New(), Website: mustParse(t, server.URL+"/1")},
+		{Id: uuid.New(), Website: mustParse(t, server.URL+"/2")},
+		{Id: uuid.New(), Website: mustParse(t, server.URL+"/3")},
+	}
+	checker.CheckAll(context.Background(), targets)
+	assert.Len(t, starts, 3)
+	assert.GreaterOrEqual(t, int64(starts[2].Sub(starts[0])), int64(50*time.Millisecond))
+}
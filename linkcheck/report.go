@@ -0,0 +1,92 @@
+package linkcheck
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/storage"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"time"
+)
+
+// Summary of the last check of the links of every metadata
+type Report struct {
+	// Number of metadata whose links were checked
+	Checked int `yaml:"checked"`
+	// Number of metadata whose links were not checked yet
+	Unchecked int `yaml:"unchecked"`
+	// Number of metadata with a broken website or source
+	Broken int `yaml:"broken"`
+	// Every broken link, in the default ordering
+	Links []BrokenLink `yaml:"links"`
+}
+
+type BrokenLink struct {
+	Id    string `yaml:"id"`
+	Title string `yaml:"title"`
+	// website or source
+	Field      string    `yaml:"field"`
+	URL        string    `yaml:"url"`
+	Status     int       `yaml:"status"`
+	Error      string    `yaml:"error,omitempty"`
+	RedirectTo string    `yaml:"redirectTo,omitempty"`
+	CheckedAt  time.Time `yaml:"checkedAt"`
+}
+
+// Lists the broken links of the metadata of the database
+func NewReport(database *core.Database) *Report {
+	report := &Report{Links: []BrokenLink{}}
+	for _, id := range database.Ordering {
+		metadata, ok := database.Metadatas[id]
+		if !ok {
+			continue
+		}
+		if metadata.Links == nil {
+			report.Unchecked++
+			continue
+		}
+		report.Checked++
+		if !metadata.Links.Broken {
+			continue
+		}
+		report.Broken++
+		for _, link := range []struct {
+			field string
+			check *core.LinkCheck
+		}{{"website", metadata.Links.Website}, {"source", metadata.Links.Source}} {
+			if link.check == nil || !link.check.Broken {
+				continue
+			}
+			report.Links = append(report.Links, BrokenLink{
+				Id:         metadata.Id.String(),
+				Title:      metadata.Title,
+				Field:      link.field,
+				URL:        link.check.URL,
+				Status:     link.check.Status,
+				Error:      link.check.Error,
+				RedirectTo: link.check.RedirectTo,
+				CheckedAt:  link.check.CheckedAt,
+			})
+		}
+	}
+	return report
+}
+
+// Serves the broken link report of a database
+type ReportHandler struct {
+	Store *storage.MetadataStore
+}
+
+// GET /reports/links
+func (h *ReportHandler) HandleReport(w http.ResponseWriter, req *http.Request) {
+	var report *Report
+	h.Store.Read(func(database *core.Database) { report = NewReport(database) })
+	r, err := yaml.Marshal(report)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error marshalling report: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(r)
+}
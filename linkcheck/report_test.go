@@ -0,0 +1,53 @@
+package linkcheck
+
+import (
+	"APIServerExercise/core"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewReport(t *testing.T) {
+	store := newTestStore(t,
+		[2]string{"https://example.com", "https://github.com/random/ok"},
+		[2]string{"https://example.com", "https://github.com/random/missing"},
+		[2]string{"https://example.com", "https://github.com/random/unchecked"})
+	checkedAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	ok := &core.LinkCheck{URL: "https://example.com", Status: http.StatusOK, CheckedAt: checkedAt}
+	store.SetLinks(store.Database.Ordering[0], &core.LinkStatus{Website: ok, Source: &core.LinkCheck{
+		URL: "https://github.com/random/ok", Status: http.StatusOK, CheckedAt: checkedAt,
+	}})
+	store.SetLinks(store.Database.Ordering[1], &core.LinkStatus{Broken: true, Website: ok, Source: &core.LinkCheck{
+		URL: "https://github.com/random/missing", Status: http.StatusNotFound, Broken: true, CheckedAt: checkedAt,
+	}})
+
+	report := NewReport(store.Database)
+	assert.Equal(t, 2, report.Checked)
+	assert.Equal(t, 1, report.Unchecked)
+	assert.Equal(t, 1, report.Broken)
+	assert.Equal(t, []BrokenLink{{
+		Id:        store.Database.Ordering[1].String(),
+		Title:     "App 2",
+		Field:     "source",
+		URL:       "https://github.com/random/missing",
+		Status:    http.StatusNotFound,
+		CheckedAt: checkedAt,
+	}}, report.Links)
+}
+
+func TestReportHandler_HandleReport(t *testing.T) {
+	store := newTestStore(t, [2]string{"https://example.com", "https://github.com/random/repo"})
+	handler := &ReportHandler{Store: store}
+
+	w := httptest.NewRecorder()
+	handler.HandleReport(w, httptest.NewRequest(http.MethodGet, "/reports/links", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-yaml", w.Header().Get("Content-Type"))
+
+	report := &Report{}
+	assert.Nil(t, yaml.Unmarshal(w.Body.Bytes(), report))
+	assert.Equal(t, &Report{Unchecked: 1, Links: []BrokenLink{}}, report)
+}
@@ -0,0 +1,44 @@
+package linkcheck
+
+import (
+	"APIServerExercise/storage"
+	"context"
+	"time"
+)
+
+// Checks the links of every metadata periodically and records the results on the metadata
+type Worker struct {
+	Checker *Checker
+	Store   *storage.MetadataStore
+}
+
+// Checks every metadata right away, then every interval until the context is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Checker.Config.Interval)
+	defer ticker.Stop()
+	for {
+		w.CheckOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Checks the links of every metadata once, returns the number of metadata updated
+// Metadata whose website or source changed during the check keep the status of their new links.
+func (w *Worker) CheckOnce(ctx context.Context) int {
+	var targets []Target
+	for _, metadata := range w.Store.Snapshot() {
+		targets = append(targets, Target{Id: metadata.Id, Website: metadata.Website.URL, Source: metadata.Source.URL})
+	}
+
+	updated := 0
+	for id, status := range w.Checker.CheckAll(ctx, targets) {
+		if w.Store.SetLinks(id, status) {
+			updated++
+		}
+	}
+	return updated
+}
@@ -0,0 +1,54 @@
+package linkcheck
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/search"
+	"APIServerExercise/storage"
+	"APIServerExercise/util"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestStore(t *testing.T, links ...[2]string) *storage.MetadataStore {
+	store := &storage.MetadataStore{
+		Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
+		Indexer:  &search.Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}},
+	}
+	for i, link := range links {
+		store.Put(&core.Metadata{
+			Title:   fmt.Sprintf("App %d", i+1),
+			Website: util.Yamlurl{URL: mustParse(t, link[0])},
+			Source:  util.Yamlurl{URL: mustParse(t, link[1])},
+		})
+	}
+	return store
+}
+
+func TestWorker_CheckOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	store := newTestStore(t, [2]string{server.URL + "/ok", server.URL + "/ok"}, [2]string{server.URL + "/ok", server.URL + "/missing"})
+	worker := &Worker{Checker: newTestChecker(), Store: store}
+
+	assert.Equal(t, 2, worker.CheckOnce(context.Background()))
+
+	first, _ := store.Get(store.Database.Ordering[0])
+	second, _ := store.Get(store.Database.Ordering[1])
+	assert.False(t, first.Links.Broken)
+	assert.True(t, second.Links.Broken)
+	assert.Equal(t, http.StatusNotFound, second.Links.Source.Status)
+
+	broken, err := store.Indexer.(*search.Searcher).FilterMetadata(context.Background(),
+		map[string][]string{"links.broken": {"true"}}, store.Database)
+	assert.Nil(t, err)
+	assert.Equal(t, []*core.Metadata{second}, broken)
+}
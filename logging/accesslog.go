@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"APIServerExercise/util"
+	"context"
+	"github.com/google/uuid"
+	"net/http"
+	"time"
+)
+
+const (
+	RequestIdHeader = "X-Request-ID"
+	// Longest request id accepted from clients, longer ids are replaced
+	maxRequestIdLength = 128
+)
+
+type contextKey int
+
+const requestIdKey contextKey = 0
+
+// Returns the request id of the request, empty if the request did not go through the middleware
+func RequestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey).(string)
+	return id
+}
+
+type AccessLog struct {
+	Logger *Logger
+	// Returns the role of the caller, IE: from the API key
+	Caller func(req *http.Request) string
+	// Returns the IP of the caller
+	ClientIP func(req *http.Request) string
+	// Same as Caller and ClientIP for gRPC calls
+	CallCaller   func(ctx context.Context) string
+	CallClientIP func(ctx context.Context) string
+}
+
+// Assigns a request id to every request and logs it once it is handled
+// The request id is taken from the X-Request-ID header when present so it can be traced across services.
+func (a *AccessLog) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		requestId := req.Header.Get(RequestIdHeader)
+		if !validRequestId(requestId) {
+			requestId = uuid.New().String()
+		}
+		w.Header().Set(RequestIdHeader, requestId)
+		req = req.WithContext(context.WithValue(req.Context(), requestIdKey, requestId))
+
+		sw := util.NewStatusWriter(w)
+		next.ServeHTTP(sw, req)
+
+		fields := Fields{
+			"requestId": requestId,
+			"method":    req.Method,
+			"route":     util.RouteTemplate(req),
+			"path":      req.URL.Path,
+			"status":    sw.Status,
+			"latencyMs": float64(time.Since(start).Microseconds()) / 1000,
+			"bytes":     sw.Bytes,
+		}
+		if a.Caller != nil {
+			fields["caller"] = a.Caller(req)
+		}
+		if a.ClientIP != nil {
+			fields["clientIp"] = a.ClientIP(req)
+		}
+		a.Logger.Info("request", fields)
+	})
+}
+
+// Only accept ids made of printable ASCII without spaces so they are safe to echo in headers and logs
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLength {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
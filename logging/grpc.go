@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"APIServerExercise/util"
+	"context"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+	"time"
+)
+
+// Metadata key of the request id, the lowercase RequestIdHeader
+var requestIdMetadata = strings.ToLower(RequestIdHeader)
+
+// Assigns a request id to every unary call and logs it once it is handled, the gRPC counterpart of Middleware
+func (a *AccessLog) UnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	ctx = a.startCall(ctx)
+	response, err := handler(ctx, req)
+	a.logCall(ctx, info.FullMethod, start, err)
+	return response, err
+}
+
+// Streams are logged once they end
+func (a *AccessLog) StreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx := a.startCall(ss.Context())
+	err := handler(srv, &util.ServerStream{ServerStream: ss, Ctx: ctx})
+	a.logCall(ctx, info.FullMethod, start, err)
+	return err
+}
+
+// Adds the request id of the call to ctx and sends it back in the response header
+func (a *AccessLog) startCall(ctx context.Context) context.Context {
+	md, _ := grpcmetadata.FromIncomingContext(ctx)
+	requestId := ""
+	if values := md.Get(requestIdMetadata); len(values) > 0 {
+		requestId = values[0]
+	}
+	if !validRequestId(requestId) {
+		requestId = uuid.New().String()
+	}
+	grpc.SetHeader(ctx, grpcmetadata.Pairs(requestIdMetadata, requestId))
+	return context.WithValue(ctx, requestIdKey, requestId)
+}
+
+func (a *AccessLog) logCall(ctx context.Context, method string, start time.Time, err error) {
+	fields := Fields{
+		"requestId": RequestId(ctx),
+		"rpc":       method,
+		"code":      status.Code(err).String(),
+		"latencyMs": float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if a.CallCaller != nil {
+		fields["caller"] = a.CallCaller(ctx)
+	}
+	if a.CallClientIP != nil {
+		fields["clientIp"] = a.CallClientIP(ctx)
+	}
+	a.Logger.Info("rpc", fields)
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func call(t *testing.T, ctx context.Context) (string, map[string]interface{}) {
+	var buf bytes.Buffer
+	accessLog := AccessLog{
+		Logger:       New(&buf),
+		CallCaller:   func(ctx context.Context) string { return "admin" },
+		CallClientIP: func(ctx context.Context) string { return "10.0.0.1" },
+	}
+
+	var requestId string
+	_, err := accessLog.UnaryInterceptor(ctx, nil,
+		&grpc.UnaryServerInfo{FullMethod: "/metadata.v1.MetadataService/GetMetadata"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			requestId = RequestId(ctx)
+			return nil, status.Error(codes.NotFound, "metadata not found")
+		})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	return requestId, entry
+}
+
+func TestAccessLog_UnaryInterceptor(t *testing.T) {
+	requestId, entry := call(t, context.Background())
+
+	assert.NotEmpty(t, requestId)
+	assert.Equal(t, "rpc", entry["msg"])
+	assert.Equal(t, requestId, entry["requestId"])
+	assert.Equal(t, "/metadata.v1.MetadataService/GetMetadata", entry["rpc"])
+	assert.Equal(t, "NotFound", entry["code"])
+	assert.Equal(t, "admin", entry["caller"])
+	assert.Equal(t, "10.0.0.1", entry["clientIp"])
+	assert.Contains(t, entry, "latencyMs")
+}
+
+func TestAccessLog_UnaryInterceptor_PropagatesRequestId(t *testing.T) {
+	ctx := grpcmetadata.NewIncomingContext(context.Background(), grpcmetadata.Pairs("x-request-id", "upstream-id-1"))
+	requestId, entry := call(t, ctx)
+
+	assert.Equal(t, "upstream-id-1", requestId)
+	assert.Equal(t, "upstream-id-1", entry["requestId"])
+}
@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type Fields map[string]interface{}
+
+// Writes one JSON object per line
+type Logger struct {
+	mutex sync.Mutex
+	out   io.Writer
+	// Allows tests to control time
+	Now func() time.Time
+}
+
+func New(out io.Writer) *Logger {
+	return &Logger{out: out, Now: time.Now}
+}
+
+func (l *Logger) Info(msg string, fields Fields) {
+	l.log("info", msg, fields)
+}
+
+func (l *Logger) Error(msg string, fields Fields) {
+	l.log("error", msg, fields)
+}
+
+func (l *Logger) log(level string, msg string, fields Fields) {
+	entry := make(Fields, len(fields)+3)
+	for key, value := range fields {
+		entry[key] = value
+	}
+	entry["time"] = l.Now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = level
+	entry["msg"] = msg
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		line = []byte(fmt.Sprintf(`{"level":"error","msg":"failed to encode log entry: %v"}`, err))
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.out.Write(append(line, '\n'))
+}
@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger_Info(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf)
+	logger.Now = func() time.Time { return time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	logger.Info("started", Fields{"address": ":8080"})
+
+	assert.Equal(t, `{"address":":8080","level":"info","msg":"started","time":"2022-01-01T00:00:00Z"}`+"\n", buf.String())
+}
+
+// region AccessLog
+
+func serve(t *testing.T, requestId string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	var buf bytes.Buffer
+	accessLog := AccessLog{
+		Logger:   New(&buf),
+		Caller:   func(req *http.Request) string { return "admin" },
+		ClientIP: func(req *http.Request) string { return "10.0.0.1" },
+	}
+
+	r := mux.NewRouter()
+	r.Use(accessLog.Middleware)
+	r.HandleFunc("/metadata/{id}", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(RequestId(req.Context())))
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/metadata/1", nil)
+	if requestId != "" {
+		request.Header.Set(RequestIdHeader, requestId)
+	}
+	responseRecorder := httptest.NewRecorder()
+	r.ServeHTTP(responseRecorder, request)
+
+	var entry map[string]interface{}
+	err := json.Unmarshal(buf.Bytes(), &entry)
+	assert.Nil(t, err)
+	return responseRecorder, entry
+}
+
+func TestAccessLog_Middleware(t *testing.T) {
+	responseRecorder, entry := serve(t, "")
+
+	requestId := responseRecorder.Header().Get(RequestIdHeader)
+	assert.NotEmpty(t, requestId)
+	assert.Equal(t, requestId, responseRecorder.Body.String())
+
+	assert.Equal(t, "request", entry["msg"])
+	assert.Equal(t, requestId, entry["requestId"])
+	assert.Equal(t, http.MethodGet, entry["method"])
+	assert.Equal(t, "/metadata/{id}", entry["route"])
+	assert.Equal(t, "/metadata/1", entry["path"])
+	assert.Equal(t, float64(http.StatusBadRequest), entry["status"])
+	assert.Equal(t, float64(len(requestId)), entry["bytes"])
+	assert.Equal(t, "admin", entry["caller"])
+	assert.Equal(t, "10.0.0.1", entry["clientIp"])
+	assert.Contains(t, entry, "latencyMs")
+}
+
+func TestAccessLog_Middleware_PropagatesRequestId(t *testing.T) {
+	responseRecorder, entry := serve(t, "upstream-id-1")
+
+	assert.Equal(t, "upstream-id-1", responseRecorder.Header().Get(RequestIdHeader))
+	assert.Equal(t, "upstream-id-1", entry["requestId"])
+}
+
+func TestAccessLog_Middleware_ReplacesInvalidRequestId(t *testing.T) {
+	for _, invalid := range []string{"has space", strings.Repeat("a", maxRequestIdLength+1), "new\nline"} {
+		responseRecorder, _ := serve(t, invalid)
+		assert.NotEqual(t, invalid, responseRecorder.Header().Get(RequestIdHeader))
+		assert.NotEmpty(t, responseRecorder.Header().Get(RequestIdHeader))
+	}
+}
+
+// endregion
@@ -1,75 +1,229 @@
 package main
 
 import (
+	"APIServerExercise/config"
 	"APIServerExercise/core"
-	"APIServerExercise/metadatahandlers"
-	"APIServerExercise/search"
+	"APIServerExercise/health"
+	"APIServerExercise/logging"
+	"APIServerExercise/metrics"
+	"APIServerExercise/ratelimit"
+	"APIServerExercise/server"
+	"APIServerExercise/storage"
+	"APIServerExercise/tracing"
+	"context"
 	"flag"
-	"github.com/google/uuid"
-	"github.com/gorilla/mux"
+	"fmt"
+	"google.golang.org/grpc"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
-var database *core.Database
-var searcher *search.Searcher
+func main() {
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if err == flag.ErrHelp {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n%s", os.Args[0], config.Usage())
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 
-func handleMetadata(w http.ResponseWriter, req *http.Request) {
-	manager := metadatahandlers.MetadataHandlerManager{
-		Database: database,
-		Indexer:  searcher,
-		Filterer: searcher,
+	exporter, err := tracing.NewExporter(cfg.Tracing.Exporter, cfg.Tracing.Path, cfg.Tracing.Endpoint)
+	if err != nil {
+		log.Fatal(err)
 	}
+	tracing.Setup(exporter, "APIServerExercise")
 
-	switch req.Method {
-	case http.MethodGet:
-		manager.HandleMetadataGet(w, req)
-	case http.MethodPut:
-		manager.HandleMetadataPut(w, req)
-	default:
-		w.WriteHeader(http.StatusNotFound)
+	srv := server.New(cfg)
+	metrics.RegisterDatabase(srv.Store)
+	metrics.RegisterSearcher(srv.Searcher, srv.Store)
+
+	var store *storage.FileStore
+	if cfg.Storage.Backend == config.FileBackend {
+		store = &storage.FileStore{Path: cfg.Storage.Path}
 	}
-}
 
-func handleMetadataWithId(w http.ResponseWriter, req *http.Request) {
-	manager := metadatahandlers.MetadataHandlerManager{
-		Database: database,
-		Indexer:  searcher,
-		Filterer: searcher,
+	storageReady := health.NewFlag("storage has not been loaded")
+	indexReady := health.NewFlag("index rebuild has not completed")
+	checker := &health.Checker{}
+	checker.AddLivenessCheck("ping", func() error { return nil })
+	checker.AddReadinessCheck("database", storageReady.Check)
+	checker.AddReadinessCheck("index", indexReady.Check)
+
+	logger := logging.New(os.Stdout)
+	accessLog := &logging.AccessLog{
+		Logger: logger,
+		Caller: func(req *http.Request) string {
+			if role, ok := cfg.Auth.RoleFor(req.Header.Get(ratelimit.ApiKeyHeader)); ok {
+				return role
+			}
+			return ratelimit.AnonymousRole
+		},
+		ClientIP: ratelimit.ClientIP,
+		CallCaller: func(ctx context.Context) string {
+			if role, ok := cfg.Auth.RoleFor(ratelimit.CallApiKey(ctx)); ok {
+				return role
+			}
+			return ratelimit.AnonymousRole
+		},
+		CallClientIP: ratelimit.PeerIP,
 	}
 
-	switch req.Method {
-	case http.MethodGet:
-		manager.HandleMetadataGetWithId(w, req)
-	case http.MethodPut:
-		manager.HandleMetadataPutWithId(w, req)
-	case http.MethodDelete:
-		manager.HandleMetadataDeleteWithId(w, req)
-	default:
-		w.WriteHeader(http.StatusNotFound)
+	r := srv.NewApiRouter()
+	r.Use(accessLog.Middleware)
+	r.Use(tracing.Middleware)
+	r.Use(metrics.Middleware)
+	limiter := ratelimit.NewLimiter(cfg.RateLimitConfig())
+	limiter.ReadPaths = []string{server.GraphqlPath}
+	limiter.ReadMethods = server.GrpcReadMethods
+	r.Use(limiter.Middleware)
+	r.Use(indexReady.Gate)
+	healthRouter := server.NewHealthRouter(checker)
+	if err := server.RegisterOpenApi(r, healthRouter); err != nil {
+		log.Fatal(err)
 	}
-}
+	http.Handle("/", r)
+	// Health endpoints are outside of the router so probes are never rate limited or gated
+	http.Handle("/livez", healthRouter)
+	http.Handle("/readyz", healthRouter)
+	http.Handle("/healthz", healthRouter)
 
-func main() {
-	disableIndexWordsFlag := flag.Bool(
-		"disableIndexWords",
-		false,
-		"Disable indexing part of values. IE: Do not index each word in description field")
-	flag.Parse()
-
-	database = &core.Database{
-		Metadatas: map[uuid.UUID]*core.Metadata{},
-		Ordering:  []uuid.UUID{},
+	// The same chain as the HTTP middlewares, in the same order
+	grpcServer, grpcHealth := srv.NewGrpcServer(server.MetadataInterceptors(
+		[]grpc.UnaryServerInterceptor{
+			accessLog.UnaryInterceptor,
+			tracing.UnaryInterceptor,
+			metrics.UnaryInterceptor,
+			limiter.UnaryInterceptor,
+			indexReady.UnaryInterceptor,
+		},
+		[]grpc.StreamServerInterceptor{
+			accessLog.StreamInterceptor,
+			tracing.StreamInterceptor,
+			metrics.StreamInterceptor,
+			limiter.StreamInterceptor,
+			indexReady.StreamInterceptor,
+		})...)
+	linkWorker := srv.NewLinkWorker()
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+
+	go func() {
+		// Nothing reads the database while it is loaded and indexed
+		srv.Store.Write(func(database *core.Database) {
+			if store != nil {
+				_, span := tracing.Start(context.Background(), "storage.Load")
+				err := store.Load(database)
+				span.SetError(err)
+				span.End()
+				if err != nil {
+					logger.Error("Failed to load metadata", logging.Fields{"error": err.Error()})
+					os.Exit(1)
+				}
+			}
+			storageReady.Set()
+			_, span := tracing.Start(context.Background(), "search.Rebuild")
+			srv.Searcher.Rebuild(database)
+			span.End()
+			for id, conflict := range srv.Unique.Rebuild(database) {
+				logger.Error("Metadata breaks a unique constraint", logging.Fields{"id": id.String(), "error": conflict.Error()})
+			}
+		})
+		indexReady.Set()
+		grpcHealth.Resume()
+		if cfg.LinkCheck.Interval > 0 {
+			go linkWorker.Run(workerCtx)
+		}
+		if cfg.Duplicates.Interval > 0 {
+			go srv.Duplicates.Run(workerCtx)
+		}
+	}()
+
+	server := &http.Server{
+		Addr:         cfg.Server.Address,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
-	searcher = &search.Searcher{
-		Index:             map[string]map[string]map[uuid.UUID]bool{},
-		DisableIndexWords: *disableIndexWordsFlag,
+
+	go func() {
+		logger.Info("Listening", logging.Fields{"address": cfg.Server.Address})
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("Failed to listen", logging.Fields{"error": err.Error()})
+			os.Exit(1)
+		}
+	}()
+
+	if cfg.Server.GrpcAddress != "" {
+		listener, err := net.Listen("tcp", cfg.Server.GrpcAddress)
+		if err != nil {
+			logger.Error("Failed to listen", logging.Fields{"error": err.Error()})
+			os.Exit(1)
+		}
+		go func() {
+			logger.Info("Listening for gRPC", logging.Fields{"address": cfg.Server.GrpcAddress})
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Error("Failed to serve gRPC", logging.Fields{"error": err.Error()})
+				os.Exit(1)
+			}
+		}()
 	}
 
-	r := mux.NewRouter()
-	r.HandleFunc("/metadata", handleMetadata)
-	r.HandleFunc("/metadata/{id}", handleMetadataWithId)
-	http.Handle("/", r)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-stop
+	logger.Info("Shutting down", logging.Fields{"signal": sig.String()})
 
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	// Stop accepting new connections and wait for in-flight requests to finish
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	grpcHealth.Shutdown()
+	stopWorkers()
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("Failed to drain in-flight requests", logging.Fields{"error": err.Error()})
+	}
+	stopGrpc(ctx, grpcServer)
+
+	// In-flight requests have finished, unless the timeout expired, persist the database
+	// The database is saved under the store lock so requests still running can not change it while it is written, and
+	// only once it is loaded so an interrupted load does not overwrite the data file.
+	if store != nil && storageReady.Check() != nil {
+		logger.Info("Not saving metadata, storage has not been loaded", logging.Fields{"path": store.Path})
+	} else if store != nil {
+		_, span := tracing.Start(context.Background(), "storage.Save")
+		var err error
+		count := 0
+		srv.Store.Read(func(database *core.Database) {
+			err = store.Save(database)
+			count = len(database.Metadatas)
+		})
+		span.SetError(err)
+		span.End()
+		if err != nil {
+			logger.Error("Failed to save metadata", logging.Fields{"error": err.Error()})
+			os.Exit(1)
+		}
+		logger.Info("Saved metadata", logging.Fields{"count": count, "path": store.Path})
+	}
+
+	if err := tracing.Shutdown(context.Background()); err != nil {
+		logger.Error("Failed to flush spans", logging.Fields{"error": err.Error()})
+	}
+}
+
+// Waits for in-flight RPCs, watch streams are cancelled once the context expires
+func stopGrpc(ctx context.Context, grpcServer *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
 }
@@ -1,6 +1,8 @@
 package metadatahandlers
 
 import (
+	"APIServerExercise/handlerutil"
+	"APIServerExercise/tracing"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -14,24 +16,24 @@ func (m *MetadataHandlerManager) HandleMetadataDeleteWithId(
 	vars := mux.Vars(req)
 	id, err := uuid.Parse(vars["id"])
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf("Error parsing ID: %v\n", err.Error())))
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("Error parsing ID: %v", err.Error()))
 		return
 	}
 
-	if _, ok := m.Database.Metadatas[id]; !ok {
-		w.WriteHeader(http.StatusNotFound)
+	_, span := tracing.Start(req.Context(), "storage.Delete")
+	defer span.End()
+	span.SetAttribute("metadata.id", id.String())
+
+	deleted, err := m.Store.Delete(id)
+	span.SetError(err)
+	if err != nil {
+		// A dependency by title of another metadata would resolve to a metadata leading back to it
+		handlerutil.WriteError(w, req, http.StatusConflict, err.Error())
 		return
 	}
-
-	m.Indexer.RemoveFromIndex(id)
-	delete(m.Database.Metadatas, id)
-	for index, existingId := range m.Database.Ordering {
-		if id == existingId {
-			// remove id from Ordering
-			m.Database.Ordering = append(m.Database.Ordering[:index], m.Database.Ordering[index+1:]...)
-			break
-		}
+	if !deleted {
+		w.WriteHeader(http.StatusNotFound)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -3,6 +3,7 @@ package metadatahandlers
 import (
 	"APIServerExercise/core"
 	mock_search "APIServerExercise/mock/search"
+	"APIServerExercise/storage"
 	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
@@ -32,15 +33,17 @@ func TestMetadataHandlerManager_HandleMetadataDeleteWithId(t *testing.T) {
 	mockIndexer.EXPECT().RemoveFromIndex(id).Times(1)
 
 	manager := MetadataHandlerManager{
-		Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{
-			id: {},
-		}},
-		Indexer: mockIndexer,
+		Store: &storage.MetadataStore{
+			Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{
+				id: {},
+			}},
+			Indexer: mockIndexer,
+		},
 	}
 	manager.HandleMetadataDeleteWithId(responseRecorder, request)
 
 	assert.Equal(t, http.StatusOK, responseRecorder.Code)
-	assert.Len(t, manager.Database.Metadatas, 0)
+	assert.Len(t, manager.Store.Database.Metadatas, 0)
 }
 
 func TestMetadataHandlerManager_HandleMetadataDeleteWithId_WithInvalidId(t *testing.T) {
@@ -81,12 +84,43 @@ func TestMetadataHandlerManager_HandleMetadataDeleteWithId_WithNonExistentId(t *
 	responseRecorder := httptest.NewRecorder()
 
 	manager := MetadataHandlerManager{
-		Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{
-			uuid.New(): {},
-		}},
+		Store: &storage.MetadataStore{
+			Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{
+				uuid.New(): {},
+			}},
+		},
 	}
 	manager.HandleMetadataDeleteWithId(responseRecorder, request)
 
 	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
-	assert.Len(t, manager.Database.Metadatas, 1)
+	assert.Len(t, manager.Store.Database.Metadatas, 1)
+}
+
+func TestMetadataHandlerManager_HandleMetadataDeleteWithId_WithCycle(t *testing.T) {
+	older := &core.Metadata{Id: uuid.New(), Title: "Library", Version: "1.0.0", Dependencies: []*core.Dependency{{Title: "Service"}}}
+	newest := &core.Metadata{Id: uuid.New(), Title: "Library", Version: "2.0.0"}
+	service := &core.Metadata{Id: uuid.New(), Title: "Service", Version: "1.0.0", Dependencies: []*core.Dependency{{Title: "Library"}}}
+
+	request := httptest.NewRequest(
+		http.MethodDelete,
+		fmt.Sprintf("/metadata/%s", newest.Id.String()),
+		nil)
+	request = mux.SetURLVars(request, map[string]string{
+		"id": newest.Id.String(),
+	})
+	responseRecorder := httptest.NewRecorder()
+
+	manager := MetadataHandlerManager{
+		Store: &storage.MetadataStore{
+			Database: &core.Database{
+				Metadatas: map[uuid.UUID]*core.Metadata{older.Id: older, newest.Id: newest, service.Id: service},
+				Ordering:  []uuid.UUID{older.Id, newest.Id, service.Id},
+			},
+		},
+	}
+	manager.HandleMetadataDeleteWithId(responseRecorder, request)
+
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "dependencies form a cycle: Service 1.0.0 -> Library 1.0.0 -> Service 1.0.0")
+	assert.Len(t, manager.Store.Database.Metadatas, 3)
 }
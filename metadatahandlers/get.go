@@ -1,47 +1,42 @@
 package metadatahandlers
 
 import (
+	"APIServerExercise/applications"
 	"APIServerExercise/core"
+	"APIServerExercise/handlerutil"
+	"APIServerExercise/lifecycle"
+	"APIServerExercise/tracing"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	"gopkg.in/yaml.v3"
 	"net/http"
-	"strconv"
 )
 
 const (
-	offsetParameter   = "offset"
-	pageSizeParameter = "pageSize"
-	defaultOffset     = 0
-	defaultPageSize   = 10
-	// Hardcoded http as we are using http.ListenAndServe and NOT http.ListenAndServeTLS
-	// Will need to update this if we use https
-	scheme = "http://"
+	// Collapses the results to one per application, IE: collapse=application
+	collapseParameter     = "collapse"
+	collapseByApplication = "application"
 )
 
 // GET /metadata/{id}
+// A deprecated or retired metadata is sent with the Deprecation header, and the Sunset and Link headers when it has a
+// sunset date or a replacement.
 func (m *MetadataHandlerManager) HandleMetadataGetWithId(
 	w http.ResponseWriter,
 	req *http.Request) {
 	vars := mux.Vars(req)
 	id, err := uuid.Parse(vars["id"])
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf("Error parsing ID: %v\n", err.Error())))
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("Error parsing ID: %v", err.Error()))
 		return
 	}
 
-	result := m.Database.Metadatas[id]
-	r, err := yaml.Marshal(result)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(fmt.Sprintf("Error marshalling metadata: Error: %v", err.Error())))
-		return
-	}
-	w.Header().Set("Content-Type", "application/x-yaml")
-	w.WriteHeader(http.StatusOK)
-	w.Write(r)
+	_, span := tracing.Start(req.Context(), "storage.Get")
+	result, _ := m.Store.Get(id)
+	span.End()
+
+	lifecycle.SetHeaders(w.Header(), result)
+	handlerutil.WriteYaml(w, req, http.StatusOK, result)
 }
 
 // GET /metadata
@@ -52,35 +47,43 @@ func (m *MetadataHandlerManager) HandleMetadataGet(
 
 	var query map[string][]string = req.URL.Query()
 
-	offset, pageSize, err := parsePagingParameters(query)
+	offset, pageSize, err := m.parsePagingParameters(query)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(err.Error()))
+		handlerutil.WriteError(w, req, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Remove the paging parameters as they are not going to be in the index
-	delete(query, offsetParameter)
-	delete(query, pageSizeParameter)
+	collapse := ""
+	if values, ok := query[collapseParameter]; ok {
+		collapse = values[0]
+		if collapse != collapseByApplication {
+			handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("collapse must be %s", collapseByApplication))
+			return
+		}
+	}
+
+	// Remove the paging and collapse parameters as they are not going to be in the index
+	delete(query, handlerutil.OffsetParameter)
+	delete(query, handlerutil.PageSizeParameter)
+	delete(query, collapseParameter)
 
-	results, err := m.Filterer.FilterMetadata(query, m.Database)
+	var results []*core.Metadata
+	m.Store.Read(func(database *core.Database) {
+		results, err = m.Filterer.FilterMetadata(req.Context(), query, database)
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(err.Error()))
+		handlerutil.WriteError(w, req, http.StatusBadRequest, err.Error())
 		return
 	}
+	if collapse == collapseByApplication {
+		results = applications.Collapse(results)
+	}
 
+	_, span := tracing.Start(req.Context(), "pageResults")
 	page := pageResults(results, offset, pageSize, req)
+	span.End()
 
-	p, err := yaml.Marshal(page)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(fmt.Sprintf("Error marshalling metadata: Error: %v", err.Error())))
-		return
-	}
-	w.Header().Set("Content-Type", "application/x-yaml")
-	w.WriteHeader(http.StatusOK)
-	w.Write(p)
+	handlerutil.WriteYaml(w, req, http.StatusOK, page)
 }
 
 // Returns the page based on paging parameters
@@ -89,61 +92,14 @@ func pageResults(
 	offset int,
 	pageSize int,
 	req *http.Request) *core.ResultPage {
-	begin := offset
-	end := offset + pageSize
-	addNextLink := true
-
-	if end >= len(results) {
-		end = len(results)
-		addNextLink = false
-	}
-	if begin > len(results) {
-		begin = len(results)
-	}
-
-	var nextLink string
-
-	// Add NextLink only if there is going to be a next page
-	if addNextLink {
-		originalUrl := req.URL
-		query := originalUrl.Query()
-		query.Set(offsetParameter, fmt.Sprintf("%d", end))
-		query.Set(pageSizeParameter, fmt.Sprintf("%d", pageSize))
-		originalUrl.RawQuery = query.Encode()
-		nextLink = fmt.Sprintf("%s%s%s", scheme, req.Host, originalUrl.String())
-	}
-
+	begin, end, nextLink := handlerutil.Page(len(results), offset, pageSize, req)
 	return &core.ResultPage{
 		Resources: results[begin:end],
 		NextLink:  nextLink,
 	}
 }
 
-// Extract and validate offset and pageSize from the query parameters and return
-func parsePagingParameters(query map[string][]string) (int, int, error) {
-	var err error
-	offset := defaultOffset
-	pageSize := defaultPageSize
-
-	if o, ok := query[offsetParameter]; ok {
-		offset, err = strconv.Atoi(o[0])
-		if err != nil {
-			return 0, 0, fmt.Errorf("offset must be numeric")
-		}
-	}
-	if o, ok := query[pageSizeParameter]; ok {
-		pageSize, err = strconv.Atoi(o[0])
-		if err != nil {
-			return 0, 0, fmt.Errorf("pageSize must be numeric")
-		}
-	}
-
-	if offset < 0 {
-		return 0, 0, fmt.Errorf("offset must be greater than or equal to 0")
-	}
-	if pageSize < 1 {
-		return 0, 0, fmt.Errorf("pageSize must be greater than 0")
-	}
-
-	return offset, pageSize, nil
+// Extract and validate offset and pageSize from the query parameters with the page sizes of the manager
+func (m *MetadataHandlerManager) parsePagingParameters(query map[string][]string) (int, int, error) {
+	return handlerutil.ParsePaging(query, m.DefaultPageSize, m.MaxPageSize)
 }
@@ -2,7 +2,11 @@ package metadatahandlers
 
 import (
 	"APIServerExercise/core"
+	"APIServerExercise/handlerutil"
+	"APIServerExercise/lifecycle"
 	mock_search "APIServerExercise/mock/search"
+	"APIServerExercise/storage"
+	"context"
 	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
@@ -13,6 +17,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 )
 
 // region HandleMetadataGetWithId
@@ -36,7 +41,7 @@ func TestMetadataHandlerManager_HandleMetadataGetWithId(t *testing.T) {
 	responseRecorder := httptest.NewRecorder()
 
 	manager := MetadataHandlerManager{
-		Database: database,
+		Store: &storage.MetadataStore{Database: database},
 	}
 	manager.HandleMetadataGetWithId(responseRecorder, request)
 
@@ -48,6 +53,30 @@ func TestMetadataHandlerManager_HandleMetadataGetWithId(t *testing.T) {
 	assert.Equal(t, testMetadata, &actual)
 }
 
+func TestMetadataHandlerManager_HandleMetadataGetWithId_Deprecated(t *testing.T) {
+	setupTest()
+	testMetadata.Lifecycle = lifecycle.Deprecated
+	testMetadata.Deprecation = &core.Deprecation{
+		Reason:       "Unmaintained",
+		Sunset:       time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		DeprecatedAt: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	manager := MetadataHandlerManager{
+		Store: &storage.MetadataStore{Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{testMetadata.Id: testMetadata}}},
+	}
+
+	request := mux.SetURLVars(
+		httptest.NewRequest(http.MethodGet, fmt.Sprintf("/metadata/%s", testMetadata.Id.String()), nil),
+		map[string]string{"id": testMetadata.Id.String()})
+	responseRecorder := httptest.NewRecorder()
+	manager.HandleMetadataGetWithId(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "@1609502400", responseRecorder.Header().Get("Deprecation"))
+	assert.Equal(t, "Tue, 01 Jun 2021 00:00:00 GMT", responseRecorder.Header().Get("Sunset"))
+	assert.Empty(t, responseRecorder.Header().Get("Link"))
+}
+
 func TestMetadataHandlerManager_HandleMetadataGetWithId_WithInvalidId(t *testing.T) {
 	invalidId := "badId"
 
@@ -86,15 +115,15 @@ func TestMetadataHandlerManager_HandleMetadataGet(t *testing.T) {
 	mockFilterer := mock_search.NewMockFilterer(ctrl)
 	mockFilterer.
 		EXPECT().
-		FilterMetadata(gomock.Any(), database).
-		DoAndReturn(func(query map[string][]string, database *core.Database) ([]*core.Metadata, error) {
+		FilterMetadata(gomock.Any(), gomock.Any(), database).
+		DoAndReturn(func(ctx context.Context, query map[string][]string, database *core.Database) ([]*core.Metadata, error) {
 			assert.Empty(t, query)
 			return []*core.Metadata{testMetadata}, nil
 		}).
 		Times(1)
 
 	manager := MetadataHandlerManager{
-		Database: database,
+		Store:    &storage.MetadataStore{Database: database},
 		Filterer: mockFilterer,
 	}
 	manager.HandleMetadataGet(responseRecorder, request)
@@ -127,8 +156,8 @@ func TestMetadataHandlerManager_HandleMetadataGet_WithQuery(t *testing.T) {
 	mockFilterer := mock_search.NewMockFilterer(ctrl)
 	mockFilterer.
 		EXPECT().
-		FilterMetadata(gomock.Any(), database).
-		DoAndReturn(func(query map[string][]string, database *core.Database) ([]*core.Metadata, error) {
+		FilterMetadata(gomock.Any(), gomock.Any(), database).
+		DoAndReturn(func(ctx context.Context, query map[string][]string, database *core.Database) ([]*core.Metadata, error) {
 			assert.Len(t, query, 1)
 			assert.Equal(t, "value", query["key"][0])
 			return []*core.Metadata{testMetadata}, nil
@@ -136,7 +165,7 @@ func TestMetadataHandlerManager_HandleMetadataGet_WithQuery(t *testing.T) {
 		Times(1)
 
 	manager := MetadataHandlerManager{
-		Database: database,
+		Store:    &storage.MetadataStore{Database: database},
 		Filterer: mockFilterer,
 	}
 	manager.HandleMetadataGet(responseRecorder, request)
@@ -162,23 +191,23 @@ func TestMetadataHandlerManager_HandleMetadataGet_WithPaging(t *testing.T) {
 
 	request := httptest.NewRequest(http.MethodGet, "/metadata", nil)
 	query := url.Values{}
-	query[offsetParameter] = []string{"0"}
-	query[pageSizeParameter] = []string{"1"}
+	query[handlerutil.OffsetParameter] = []string{"0"}
+	query[handlerutil.PageSizeParameter] = []string{"1"}
 	request.URL.RawQuery = query.Encode()
 	responseRecorder := httptest.NewRecorder()
 
 	mockFilterer := mock_search.NewMockFilterer(ctrl)
 	mockFilterer.
 		EXPECT().
-		FilterMetadata(gomock.Any(), database).
-		DoAndReturn(func(query map[string][]string, database *core.Database) ([]*core.Metadata, error) {
+		FilterMetadata(gomock.Any(), gomock.Any(), database).
+		DoAndReturn(func(ctx context.Context, query map[string][]string, database *core.Database) ([]*core.Metadata, error) {
 			assert.Empty(t, query)
 			return []*core.Metadata{testMetadata, testMetadata}, nil
 		}).
 		Times(1)
 
 	manager := MetadataHandlerManager{
-		Database: database,
+		Store:    &storage.MetadataStore{Database: database},
 		Filterer: mockFilterer,
 	}
 	manager.HandleMetadataGet(responseRecorder, request)
@@ -194,8 +223,8 @@ func TestMetadataHandlerManager_HandleMetadataGet_WithPaging(t *testing.T) {
 	nextLink, err := url.Parse(actual.NextLink)
 	assert.Nil(t, err)
 	assert.Equal(t, "/metadata", nextLink.Path)
-	assert.Equal(t, "1", nextLink.Query().Get(offsetParameter))
-	assert.Equal(t, "1", nextLink.Query().Get(pageSizeParameter))
+	assert.Equal(t, "1", nextLink.Query().Get(handlerutil.OffsetParameter))
+	assert.Equal(t, "1", nextLink.Query().Get(handlerutil.PageSizeParameter))
 }
 
 func TestMetadataHandlerManager_HandleMetadataGet_WithFilterError(t *testing.T) {
@@ -215,15 +244,15 @@ func TestMetadataHandlerManager_HandleMetadataGet_WithFilterError(t *testing.T)
 	mockFilterer := mock_search.NewMockFilterer(ctrl)
 	mockFilterer.
 		EXPECT().
-		FilterMetadata(gomock.Any(), database).
-		DoAndReturn(func(query map[string][]string, database *core.Database) ([]core.Metadata, error) {
+		FilterMetadata(gomock.Any(), gomock.Any(), database).
+		DoAndReturn(func(ctx context.Context, query map[string][]string, database *core.Database) ([]core.Metadata, error) {
 			assert.Empty(t, query)
 			return nil, testError
 		}).
 		Times(1)
 
 	manager := MetadataHandlerManager{
-		Database: database,
+		Store:    &storage.MetadataStore{Database: database},
 		Filterer: mockFilterer,
 	}
 	manager.HandleMetadataGet(responseRecorder, request)
@@ -232,46 +261,101 @@ func TestMetadataHandlerManager_HandleMetadataGet_WithFilterError(t *testing.T)
 	assert.Contains(t, responseRecorder.Body.String(), testError.Error())
 }
 
+func TestMetadataHandlerManager_HandleMetadataGet_WithCollapse(t *testing.T) {
+	setupTest()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	newer := *testMetadata
+	newer.Id = uuid.New()
+	newer.Version = "0.1.0"
+	other := *testMetadata
+	other.Id = uuid.New()
+	other.Application = "other-app"
+	database := &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{
+		testMetadata.Id: testMetadata,
+		newer.Id:        &newer,
+		other.Id:        &other,
+	}}
+
+	request := httptest.NewRequest(http.MethodGet, "/metadata?collapse=application", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	mockFilterer := mock_search.NewMockFilterer(ctrl)
+	mockFilterer.
+		EXPECT().
+		FilterMetadata(gomock.Any(), gomock.Any(), database).
+		DoAndReturn(func(ctx context.Context, query map[string][]string, database *core.Database) ([]*core.Metadata, error) {
+			assert.Empty(t, query)
+			return []*core.Metadata{testMetadata, &other, &newer}, nil
+		}).
+		Times(1)
+
+	manager := MetadataHandlerManager{
+		Store:    &storage.MetadataStore{Database: database},
+		Filterer: mockFilterer,
+	}
+	manager.HandleMetadataGet(responseRecorder, request)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	var actual core.ResultPage
+	err := yaml.Unmarshal(responseRecorder.Body.Bytes(), &actual)
+	assert.Nil(t, err)
+	assert.Equal(t, []*core.Metadata{&newer, &other}, actual.Resources)
+}
+
+func TestMetadataHandlerManager_HandleMetadataGet_WithUnknownCollapse(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/metadata?collapse=company", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	manager := MetadataHandlerManager{}
+	manager.HandleMetadataGet(responseRecorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "collapse must be application")
+}
+
 // endregion
 
 // region parsePagingParameters
 
 func TestParsePagingParameters_WithDefaults(t *testing.T) {
-	offset, pageSize, err := parsePagingParameters(map[string][]string{})
+	offset, pageSize, err := (&MetadataHandlerManager{}).parsePagingParameters(map[string][]string{})
 	assert.Nil(t, err)
-	assert.Equal(t, defaultOffset, offset)
-	assert.Equal(t, defaultPageSize, pageSize)
+	assert.Equal(t, handlerutil.DefaultOffset, offset)
+	assert.Equal(t, handlerutil.DefaultPageSize, pageSize)
 }
 
 func TestParsePagingParameters_WithOffset(t *testing.T) {
 	expectedOffset := 3
-	offset, pageSize, err := parsePagingParameters(
+	offset, pageSize, err := (&MetadataHandlerManager{}).parsePagingParameters(
 		map[string][]string{
-			offsetParameter: {fmt.Sprintf("%d", expectedOffset)},
+			handlerutil.OffsetParameter: {fmt.Sprintf("%d", expectedOffset)},
 		})
 	assert.Nil(t, err)
 	assert.Equal(t, expectedOffset, offset)
-	assert.Equal(t, defaultPageSize, pageSize)
+	assert.Equal(t, handlerutil.DefaultPageSize, pageSize)
 }
 
 func TestParsePagingParameters_WithPageSize(t *testing.T) {
 	expectedPageSize := 5
-	offset, pageSize, err := parsePagingParameters(
+	offset, pageSize, err := (&MetadataHandlerManager{}).parsePagingParameters(
 		map[string][]string{
-			pageSizeParameter: {fmt.Sprintf("%d", expectedPageSize)},
+			handlerutil.PageSizeParameter: {fmt.Sprintf("%d", expectedPageSize)},
 		})
 	assert.Nil(t, err)
-	assert.Equal(t, defaultOffset, offset)
+	assert.Equal(t, handlerutil.DefaultOffset, offset)
 	assert.Equal(t, expectedPageSize, pageSize)
 }
 
 func TestParsePagingParameters_WithOffsetAndPageSize(t *testing.T) {
 	expectedOffset := 23
 	expectedPageSize := 30
-	offset, pageSize, err := parsePagingParameters(
+	offset, pageSize, err := (&MetadataHandlerManager{}).parsePagingParameters(
 		map[string][]string{
-			offsetParameter:   {fmt.Sprintf("%d", expectedOffset)},
-			pageSizeParameter: {fmt.Sprintf("%d", expectedPageSize)},
+			handlerutil.OffsetParameter:   {fmt.Sprintf("%d", expectedOffset)},
+			handlerutil.PageSizeParameter: {fmt.Sprintf("%d", expectedPageSize)},
 		})
 	assert.Nil(t, err)
 	assert.Equal(t, expectedOffset, offset)
@@ -279,41 +363,58 @@ func TestParsePagingParameters_WithOffsetAndPageSize(t *testing.T) {
 }
 
 func TestParsePagingParameters_WithNonNumericOffset(t *testing.T) {
-	_, _, err := parsePagingParameters(
+	_, _, err := (&MetadataHandlerManager{}).parsePagingParameters(
 		map[string][]string{
-			offsetParameter: {"NotANumber"},
+			handlerutil.OffsetParameter: {"NotANumber"},
 		})
 	assert.Error(t, err)
 	assert.Equal(t, "offset must be numeric", err.Error())
 }
 
 func TestParsePagingParameters_WithNonNumericPageSize(t *testing.T) {
-	_, _, err := parsePagingParameters(
+	_, _, err := (&MetadataHandlerManager{}).parsePagingParameters(
 		map[string][]string{
-			pageSizeParameter: {"NotANumber"},
+			handlerutil.PageSizeParameter: {"NotANumber"},
 		})
 	assert.Error(t, err)
 	assert.Equal(t, "pageSize must be numeric", err.Error())
 }
 
 func TestParsePagingParameters_WithInvalidOffset(t *testing.T) {
-	_, _, err := parsePagingParameters(
+	_, _, err := (&MetadataHandlerManager{}).parsePagingParameters(
 		map[string][]string{
-			offsetParameter: {"-1"},
+			handlerutil.OffsetParameter: {"-1"},
 		})
 	assert.Error(t, err)
 	assert.Equal(t, "offset must be greater than or equal to 0", err.Error())
 }
 
 func TestParsePagingParameters_WithInvalidPageSize(t *testing.T) {
-	_, _, err := parsePagingParameters(
+	_, _, err := (&MetadataHandlerManager{}).parsePagingParameters(
 		map[string][]string{
-			pageSizeParameter: {"0"},
+			handlerutil.PageSizeParameter: {"0"},
 		})
 	assert.Error(t, err)
 	assert.Equal(t, "pageSize must be greater than 0", err.Error())
 }
 
+func TestParsePagingParameters_WithConfiguredDefaultPageSize(t *testing.T) {
+	manager := MetadataHandlerManager{DefaultPageSize: 25}
+	_, pageSize, err := manager.parsePagingParameters(map[string][]string{})
+	assert.Nil(t, err)
+	assert.Equal(t, 25, pageSize)
+}
+
+func TestParsePagingParameters_WithPageSizeOverMax(t *testing.T) {
+	manager := MetadataHandlerManager{MaxPageSize: 100}
+	_, _, err := manager.parsePagingParameters(
+		map[string][]string{
+			handlerutil.PageSizeParameter: {"101"},
+		})
+	assert.Error(t, err)
+	assert.Equal(t, "pageSize must be less than or equal to 100", err.Error())
+}
+
 // endregion
 
 // region pageResults
@@ -337,8 +438,8 @@ func TestPageResults(t *testing.T) {
 	nextLink, err := url.Parse(actual.NextLink)
 	assert.Nil(t, err)
 	assert.Equal(t, "/metadata", nextLink.Path)
-	assert.Equal(t, fmt.Sprintf("%d", offset+pageSize), nextLink.Query().Get(offsetParameter))
-	assert.Equal(t, fmt.Sprintf("%d", pageSize), nextLink.Query().Get(pageSizeParameter))
+	assert.Equal(t, fmt.Sprintf("%d", offset+pageSize), nextLink.Query().Get(handlerutil.OffsetParameter))
+	assert.Equal(t, fmt.Sprintf("%d", pageSize), nextLink.Query().Get(handlerutil.PageSizeParameter))
 }
 
 func TestPageResults_EndOfPage(t *testing.T) {
@@ -1,12 +1,21 @@
 package metadatahandlers
 
 import (
-	"APIServerExercise/core"
+	"APIServerExercise/licensepolicy"
 	"APIServerExercise/search"
+	"APIServerExercise/storage"
 )
 
 type MetadataHandlerManager struct {
-	Database *core.Database
-	Indexer  search.Indexer
+	// Database, index, watchers and unique constraints of the metadata
+	Store    *storage.MetadataStore
 	Filterer search.Filterer
+	// Page size used when the request does not specify one, uses handlerutil.DefaultPageSize if 0
+	DefaultPageSize int
+	// Largest page size a request can ask for, unlimited if 0
+	MaxPageSize int
+	// Checks the license of saved metadata, optional
+	Policy *licensepolicy.Policy
+	// Hosts the source URL can be on, any host when empty
+	SourceHosts []string
 }
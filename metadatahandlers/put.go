@@ -2,6 +2,12 @@ package metadatahandlers
 
 import (
 	"APIServerExercise/core"
+	"APIServerExercise/dependencies"
+	"APIServerExercise/handlerutil"
+	"APIServerExercise/lifecycle"
+	"APIServerExercise/metrics"
+	"APIServerExercise/storage"
+	"APIServerExercise/tracing"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -16,8 +22,7 @@ func (m *MetadataHandlerManager) HandleMetadataPutWithId(
 	vars := mux.Vars(req)
 	id, err := uuid.Parse(vars["id"])
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf("Error parsing ID: %v\n", err.Error())))
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("Error parsing ID: %v", err.Error()))
 		return
 	}
 	m.handleMetadataPutInner(w, req, id)
@@ -36,43 +41,74 @@ func (m *MetadataHandlerManager) handleMetadataPutInner(
 	id uuid.UUID) {
 	// Decode request body
 	var metadata core.Metadata
+	_, span := tracing.Start(req.Context(), "yaml.Decode")
 	decoder := yaml.NewDecoder(req.Body)
-	if err := decoder.Decode(&metadata); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf("Failed to decode body: %v\n", err.Error())))
+	err := decoder.Decode(&metadata)
+	span.SetError(err)
+	span.End()
+	if err != nil {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("Failed to decode body: %v", err.Error()))
 		return
 	}
 
 	// Validate request metadata
-	if err := core.ValidateStruct(metadata); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf("Validation failed: %v\n", err.Error())))
+	_, span = tracing.Start(req.Context(), "core.ValidateStruct")
+	err = core.ValidateStruct(metadata)
+	if err == nil {
+		err = core.ValidateURLs(&metadata, m.SourceHosts)
+	}
+	span.SetError(err)
+	span.End()
+	if err != nil {
+		metrics.RecordValidationError(err)
+		handlerutil.WriteError(w, req, http.StatusBadRequest, fmt.Sprintf("Validation failed: %v", err.Error()))
+		return
+	}
+
+	// Check the license against the license policy
+	warnings, err := m.Policy.Check(metadata.License)
+	if err != nil {
+		handlerutil.WriteError(w, req, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	if id.String() != (uuid.UUID{}).String() {
 		// Id was passed in from url, takes precedence
 		metadata.Id = id
-	} else if metadata.Id.String() == (uuid.UUID{}).String() {
-		// No id was passed in request body, generate new id
-		metadata.Id = uuid.New()
-	} // Else use Id that was passed in from request body
+	} // Else use Id that was passed in from request body, a new id is generated if there is none
 
-	// Check if there is an existing metadata with the same Id
-	// If so, remove old metadata Id from indexes
-	if _, ok := m.Database.Metadatas[metadata.Id]; ok {
-		m.Indexer.RemoveFromIndex(metadata.Id)
-	} else {
-		// this is a new metadata, add to ordering
-		m.Database.Ordering = append(m.Database.Ordering, metadata.Id)
+	_, span = tracing.Start(req.Context(), "storage.Put")
+	_, err = m.Store.Put(&metadata)
+	span.SetAttribute("metadata.id", metadata.Id.String())
+	span.SetError(err)
+	span.End()
+	switch e := err.(type) {
+	case *storage.ConflictError:
+		w.Header().Set("Location", fmt.Sprintf("/metadata/%s", e.Existing.Id))
+		handlerutil.WriteError(w, req, http.StatusConflict, e.Error())
+		return
+	case *dependencies.UnresolvedError:
+		handlerutil.WriteError(w, req, http.StatusBadRequest, e.Error())
+		return
+	case *dependencies.CycleError:
+		handlerutil.WriteError(w, req, http.StatusConflict, e.Error())
+		return
+	case *lifecycle.DeprecationError:
+		handlerutil.WriteError(w, req, http.StatusBadRequest, e.Error())
+		return
+	case *lifecycle.TransitionError:
+		handlerutil.WriteError(w, req, http.StatusConflict, e.Error())
+		return
+	default:
+		// The metadata was not saved, never answer as if it was
+		if err != nil {
+			handlerutil.WriteError(w, req, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	for _, warning := range warnings {
+		w.Header().Add("Warning", fmt.Sprintf(`299 - "%s"`, warning))
 	}
 
-	// Save metadata in database and add to index
-	m.Database.Metadatas[metadata.Id] = &metadata
-	m.Indexer.AddToIndex(&metadata, metadata.Id, "")
-
-	responseByte, _ := yaml.Marshal(&metadata)
-	w.Header().Set("Content-Type", "application/x-yaml")
-	w.WriteHeader(http.StatusCreated)
-	w.Write(responseByte)
+	handlerutil.WriteYaml(w, req, http.StatusCreated, &metadata)
 }
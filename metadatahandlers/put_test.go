@@ -2,7 +2,11 @@ package metadatahandlers
 
 import (
 	"APIServerExercise/core"
+	"APIServerExercise/licensepolicy"
+	"APIServerExercise/lifecycle"
 	mock_search "APIServerExercise/mock/search"
+	"APIServerExercise/search"
+	"APIServerExercise/storage"
 	"APIServerExercise/util"
 	"bytes"
 	"fmt"
@@ -24,9 +28,11 @@ func setupTest() {
 	website, _ := url.Parse("https://website.com")
 	source, _ := url.Parse("https://github.com/random/repo")
 	testMetadata = &core.Metadata{
-		Id:      uuid.New(),
-		Title:   "Valid App 1",
-		Version: "0.0.1",
+		Id:          uuid.New(),
+		Title:       "Valid App 1",
+		Version:     "0.0.1",
+		Application: "valid-app-1",
+		Lifecycle:   lifecycle.Published,
 		Maintainers: []*core.Maintainer{
 			{
 				Name:  "firstmaintainer app1",
@@ -78,17 +84,19 @@ func TestMetadataHandlerManager_HandleMetadataPutWithId(t *testing.T) {
 		Times(1)
 
 	manager := MetadataHandlerManager{
-		Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
-		Indexer:  mockIndexer,
+		Store: &storage.MetadataStore{
+			Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
+			Indexer:  mockIndexer,
+		},
 	}
 	manager.HandleMetadataPutWithId(responseRecorder, request)
 
 	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
 	assert.Equal(t, string(expectedBytes), responseRecorder.Body.String())
-	assert.Len(t, manager.Database.Metadatas, 1)
-	assert.Equal(t, manager.Database.Metadatas[testMetadata.Id], testMetadata)
-	assert.Len(t, manager.Database.Ordering, 1)
-	assert.Equal(t, manager.Database.Ordering[0], testMetadata.Id)
+	assert.Len(t, manager.Store.Database.Metadatas, 1)
+	assert.Equal(t, manager.Store.Database.Metadatas[testMetadata.Id], testMetadata)
+	assert.Len(t, manager.Store.Database.Ordering, 1)
+	assert.Equal(t, manager.Store.Database.Ordering[0], testMetadata.Id)
 }
 
 func TestMetadataHandlerManager_HandleMetadataPutWithId_DifferentIds(t *testing.T) {
@@ -125,17 +133,19 @@ func TestMetadataHandlerManager_HandleMetadataPutWithId_DifferentIds(t *testing.
 		Times(1)
 
 	manager := MetadataHandlerManager{
-		Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
-		Indexer:  mockIndexer,
+		Store: &storage.MetadataStore{
+			Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
+			Indexer:  mockIndexer,
+		},
 	}
 	manager.HandleMetadataPutWithId(responseRecorder, request)
 
 	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
 	assert.Equal(t, string(expectedBytes), responseRecorder.Body.String())
-	assert.Len(t, manager.Database.Metadatas, 1)
-	assert.Equal(t, manager.Database.Metadatas[pathId], testMetadata)
-	assert.Len(t, manager.Database.Ordering, 1)
-	assert.Equal(t, manager.Database.Ordering[0], testMetadata.Id)
+	assert.Len(t, manager.Store.Database.Metadatas, 1)
+	assert.Equal(t, manager.Store.Database.Metadatas[pathId], testMetadata)
+	assert.Len(t, manager.Store.Database.Ordering, 1)
+	assert.Equal(t, manager.Store.Database.Ordering[0], testMetadata.Id)
 }
 
 func TestMetadataHandlerManager_HandleMetadataPutWithId_UpdateMetadata(t *testing.T) {
@@ -183,24 +193,26 @@ func TestMetadataHandlerManager_HandleMetadataPutWithId_UpdateMetadata(t *testin
 	mockIndexer.EXPECT().RemoveFromIndex(testMetadata.Id).Times(1)
 
 	manager := MetadataHandlerManager{
-		Database: &core.Database{
-			Metadatas: map[uuid.UUID]*core.Metadata{
-				testMetadata.Id: oldMetadata,
-			},
-			Ordering: []uuid.UUID{
-				testMetadata.Id,
+		Store: &storage.MetadataStore{
+			Database: &core.Database{
+				Metadatas: map[uuid.UUID]*core.Metadata{
+					testMetadata.Id: oldMetadata,
+				},
+				Ordering: []uuid.UUID{
+					testMetadata.Id,
+				},
 			},
+			Indexer: mockIndexer,
 		},
-		Indexer: mockIndexer,
 	}
 	manager.HandleMetadataPutWithId(responseRecorder, request)
 
 	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
 	assert.Equal(t, string(expectedBytes), responseRecorder.Body.String())
-	assert.Len(t, manager.Database.Metadatas, 1)
-	assert.Equal(t, manager.Database.Metadatas[testMetadata.Id], testMetadata)
-	assert.Len(t, manager.Database.Ordering, 1)
-	assert.Equal(t, manager.Database.Ordering[0], testMetadata.Id)
+	assert.Len(t, manager.Store.Database.Metadatas, 1)
+	assert.Equal(t, manager.Store.Database.Metadatas[testMetadata.Id], testMetadata)
+	assert.Len(t, manager.Store.Database.Ordering, 1)
+	assert.Equal(t, manager.Store.Database.Ordering[0], testMetadata.Id)
 }
 
 func TestMetadataHandlerManager_HandleMetadataPutWithId_InvalidPathId(t *testing.T) {
@@ -294,17 +306,19 @@ func TestMetadataHandlerManager_HandleMetadataPut_WithIdInPayload(t *testing.T)
 		Times(1)
 
 	manager := MetadataHandlerManager{
-		Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
-		Indexer:  mockIndexer,
+		Store: &storage.MetadataStore{
+			Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
+			Indexer:  mockIndexer,
+		},
 	}
 	manager.HandleMetadataPut(responseRecorder, request)
 
 	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
 	assert.Equal(t, string(expectedBytes), responseRecorder.Body.String())
-	assert.Len(t, manager.Database.Metadatas, 1)
-	assert.Equal(t, manager.Database.Metadatas[testMetadata.Id], testMetadata)
-	assert.Len(t, manager.Database.Ordering, 1)
-	assert.Equal(t, manager.Database.Ordering[0], testMetadata.Id)
+	assert.Len(t, manager.Store.Database.Metadatas, 1)
+	assert.Equal(t, manager.Store.Database.Metadatas[testMetadata.Id], testMetadata)
+	assert.Len(t, manager.Store.Database.Ordering, 1)
+	assert.Equal(t, manager.Store.Database.Ordering[0], testMetadata.Id)
 }
 
 func TestMetadataHandlerManager_HandleMetadataPut_WithoutId(t *testing.T) {
@@ -335,8 +349,10 @@ func TestMetadataHandlerManager_HandleMetadataPut_WithoutId(t *testing.T) {
 		Times(1)
 
 	manager := MetadataHandlerManager{
-		Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
-		Indexer:  mockIndexer,
+		Store: &storage.MetadataStore{
+			Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
+			Indexer:  mockIndexer,
+		},
 	}
 	manager.HandleMetadataPut(responseRecorder, request)
 
@@ -345,10 +361,10 @@ func TestMetadataHandlerManager_HandleMetadataPut_WithoutId(t *testing.T) {
 
 	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
 	assert.Equal(t, string(expectedBytes), responseRecorder.Body.String())
-	assert.Len(t, manager.Database.Metadatas, 1)
-	assert.Equal(t, manager.Database.Metadatas[testMetadata.Id], testMetadata)
-	assert.Len(t, manager.Database.Ordering, 1)
-	assert.Equal(t, manager.Database.Ordering[0], testMetadata.Id)
+	assert.Len(t, manager.Store.Database.Metadatas, 1)
+	assert.Equal(t, manager.Store.Database.Metadatas[testMetadata.Id], testMetadata)
+	assert.Len(t, manager.Store.Database.Ordering, 1)
+	assert.Equal(t, manager.Store.Database.Ordering[0], testMetadata.Id)
 }
 
 func TestMetadataHandlerManager_HandleMetadataPut_InvalidPayload(t *testing.T) {
@@ -381,4 +397,168 @@ func TestMetadataHandlerManager_HandleMetadata_MalformedYaml(t *testing.T) {
 	assert.Contains(t, responseRecorder.Body.String(), "Failed to decode body")
 }
 
+func TestMetadataHandlerManager_HandleMetadataPut_InvalidURL(t *testing.T) {
+	manager := MetadataHandlerManager{SourceHosts: []string{"gitlab.com"}}
+
+	for website, expected := range map[string]string{
+		"javascript:alert(1)": `Validation failed: website "javascript:alert(1)" has scheme "javascript", must be http or https`,
+		"https://website.com": `Validation failed: source "https://github.com/random/repo" is on host "github.com", must be on gitlab.com`,
+	} {
+		setupTest()
+		testMetadata.Website.URL, _ = url.Parse(website)
+		var buf bytes.Buffer
+		assert.Nil(t, yaml.NewEncoder(&buf).Encode(testMetadata))
+		responseRecorder := httptest.NewRecorder()
+		manager.HandleMetadataPut(responseRecorder, httptest.NewRequest(http.MethodPut, "/metadata", &buf))
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		assert.Equal(t, expected+"\n", responseRecorder.Body.String())
+	}
+}
+
+// endregion
+
+// region License policy
+
+func TestMetadataHandlerManager_HandleMetadataPut_LicensePolicy(t *testing.T) {
+	policy := licensepolicy.New(licensepolicy.Config{
+		Review:      []string{"GPL-3.0-only"},
+		Deny:        []string{"AGPL-3.0-only"},
+		Unlisted:    licensepolicy.Allowed,
+		Enforcement: licensepolicy.Reject,
+	})
+	manager := MetadataHandlerManager{
+		Store: &storage.MetadataStore{
+			Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
+			Indexer:  &search.Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}},
+		},
+		Policy: policy,
+	}
+
+	for license, expectedWarnings := range map[string][]string{
+		"Apache-2.0":   nil,
+		"GPL-3.0-only": {`299 - "license GPL-3.0-only requires a legal review: GPL-3.0-only"`},
+	} {
+		setupTest()
+		testMetadata.License = license
+		var buf bytes.Buffer
+		assert.Nil(t, yaml.NewEncoder(&buf).Encode(testMetadata))
+		responseRecorder := httptest.NewRecorder()
+		manager.HandleMetadataPut(responseRecorder, httptest.NewRequest(http.MethodPut, "/metadata", &buf))
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+		assert.Equal(t, expectedWarnings, responseRecorder.Header()["Warning"])
+	}
+
+	setupTest()
+	testMetadata.License = "MIT AND AGPL-3.0-only"
+	var buf bytes.Buffer
+	assert.Nil(t, yaml.NewEncoder(&buf).Encode(testMetadata))
+	responseRecorder := httptest.NewRecorder()
+	manager.HandleMetadataPut(responseRecorder, httptest.NewRequest(http.MethodPut, "/metadata", &buf))
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, "license MIT AND AGPL-3.0-only is denied by the license policy: AGPL-3.0-only\n", responseRecorder.Body.String())
+	assert.Len(t, manager.Store.Database.Metadatas, 2)
+}
+
 // endregion
+
+// region Unique constraints
+
+func TestMetadataHandlerManager_HandleMetadataPut_Conflict(t *testing.T) {
+	setupTest()
+	existing := testMetadata
+	manager := MetadataHandlerManager{
+		Store: &storage.MetadataStore{
+			Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
+			Indexer:  &search.Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}},
+			Unique:   storage.NewUniqueIndex([][]string{{"title", "version"}}),
+		},
+	}
+	_, err := manager.Store.Put(existing)
+	assert.Nil(t, err)
+
+	setupTest()
+	var buf bytes.Buffer
+	assert.Nil(t, yaml.NewEncoder(&buf).Encode(testMetadata))
+	responseRecorder := httptest.NewRecorder()
+	manager.HandleMetadataPut(responseRecorder, httptest.NewRequest(http.MethodPut, "/metadata", &buf))
+
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+	assert.Equal(t, fmt.Sprintf("/metadata/%s", existing.Id), responseRecorder.Header().Get("Location"))
+	assert.Equal(t, fmt.Sprintf("title and version must be unique, metadata %s already has title \"Valid App 1\" and version \"0.0.1\"\n",
+		existing.Id), responseRecorder.Body.String())
+	assert.Len(t, manager.Store.Database.Metadatas, 1)
+}
+
+func TestMetadataHandlerManager_HandleMetadataPut_Dependencies(t *testing.T) {
+	setupTest()
+	library := testMetadata
+	manager := MetadataHandlerManager{
+		Store: &storage.MetadataStore{
+			Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
+			Indexer:  &search.Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}},
+		},
+	}
+	_, err := manager.Store.Put(library)
+	assert.Nil(t, err)
+
+	put := func(metadata *core.Metadata) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		assert.Nil(t, yaml.NewEncoder(&buf).Encode(metadata))
+		responseRecorder := httptest.NewRecorder()
+		manager.HandleMetadataPut(responseRecorder, httptest.NewRequest(http.MethodPut, "/metadata", &buf))
+		return responseRecorder
+	}
+
+	setupTest()
+	testMetadata.Title = "Valid App 2"
+	testMetadata.Dependencies = []*core.Dependency{{Title: "Valid App 1", Version: "^1.0.0"}}
+	responseRecorder := put(testMetadata)
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, "dependencies[0]: no metadata titled \"Valid App 1\" has a version in \"^1.0.0\"\n", responseRecorder.Body.String())
+
+	testMetadata.Dependencies = []*core.Dependency{{Title: "Valid App 1", Version: "0.0.1"}}
+	responseRecorder = put(testMetadata)
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+
+	library.Dependencies = []*core.Dependency{{Id: testMetadata.Id.String()}}
+	responseRecorder = put(library)
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+	assert.Equal(t, "dependencies form a cycle: Valid App 1 0.0.1 -> Valid App 2 0.0.1 -> Valid App 1 0.0.1\n", responseRecorder.Body.String())
+}
+
+// endregion
+
+func TestMetadataHandlerManager_HandleMetadataPut_Lifecycle(t *testing.T) {
+	setupTest()
+	manager := MetadataHandlerManager{
+		Store: &storage.MetadataStore{
+			Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}},
+			Indexer:  &search.Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}},
+		},
+	}
+	put := func(metadata *core.Metadata) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		assert.Nil(t, yaml.NewEncoder(&buf).Encode(metadata))
+		responseRecorder := httptest.NewRecorder()
+		manager.HandleMetadataPut(responseRecorder, httptest.NewRequest(http.MethodPut, "/metadata", &buf))
+		return responseRecorder
+	}
+
+	testMetadata.Lifecycle = lifecycle.Deprecated
+	responseRecorder := put(testMetadata)
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, "deprecation.reason is required for a deprecated metadata\n", responseRecorder.Body.String())
+
+	testMetadata.Deprecation = &core.Deprecation{Reason: "Unmaintained"}
+	responseRecorder = put(testMetadata)
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+
+	testMetadata.Lifecycle = lifecycle.Draft
+	testMetadata.Deprecation = nil
+	responseRecorder = put(testMetadata)
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+	assert.Equal(t, "can not change the lifecycle from deprecated to draft, only to published or retired\n", responseRecorder.Body.String())
+}
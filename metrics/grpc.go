@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// Records call count and latency for every unary call, the gRPC counterpart of Middleware
+func UnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	response, err := handler(ctx, req)
+	recordCall(info.FullMethod, start, err)
+	return response, err
+}
+
+// Streams are recorded once they end
+func StreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	recordCall(info.FullMethod, start, err)
+	return err
+}
+
+func recordCall(method string, start time.Time, err error) {
+	code := status.Code(err).String()
+	GrpcRequests.WithLabelValues(method, code).Inc()
+	GrpcRequestDuration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestUnaryInterceptor(t *testing.T) {
+	const method = "/metrics.test.Service/Get"
+	before := testutil.ToFloat64(GrpcRequests.WithLabelValues(method, "NotFound"))
+
+	_, err := UnaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.NotFound, "not found")
+		})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, before+1, testutil.ToFloat64(GrpcRequests.WithLabelValues(method, "NotFound")))
+	assert.NotZero(t, histogramCount(GrpcRequestDuration, method, "NotFound"))
+}
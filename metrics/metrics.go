@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/search"
+	"APIServerExercise/storage"
+	"APIServerExercise/util"
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// Registry of the metrics served under /metrics
+	Default = prometheus.NewRegistry()
+
+	HttpRequests = promauto.With(Default).NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests handled",
+	}, []string{"route", "method", "status"})
+	HttpRequestDuration = promauto.With(Default).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+	GrpcRequests = promauto.With(Default).NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_requests_total",
+		Help: "Number of gRPC calls handled",
+	}, []string{"method", "code"})
+	GrpcRequestDuration = promauto.With(Default).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_request_duration_seconds",
+		Help:    "Latency of gRPC calls",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+	FilterDuration = promauto.With(Default).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "search_filter_duration_seconds",
+		Help:    "Time spent filtering metadata by query parameters",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"result"})
+	ValidationFailures = promauto.With(Default).NewCounterVec(prometheus.CounterOpts{
+		Name: "metadata_validation_failures_total",
+		Help: "Number of failed metadata validations by validation rule",
+	}, []string{"rule"})
+)
+
+// GET /metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Default, promhttp.HandlerOpts{})
+}
+
+// Records request count and latency for every request routed by mux
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		sw := util.NewStatusWriter(w)
+		next.ServeHTTP(sw, req)
+
+		route := util.RouteTemplate(req)
+		if route == "" {
+			route = "unknown"
+		}
+		status := strconv.Itoa(sw.Status)
+		HttpRequests.WithLabelValues(route, req.Method, status).Inc()
+		HttpRequestDuration.WithLabelValues(route, req.Method, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Count validation failures by the rule that failed
+func RecordValidationError(err error) {
+	var urlError *core.URLError
+	if errors.As(err, &urlError) {
+		ValidationFailures.WithLabelValues("url").Inc()
+		return
+	}
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		ValidationFailures.WithLabelValues("unknown").Inc()
+		return
+	}
+	for _, fieldError := range validationErrors {
+		ValidationFailures.WithLabelValues(fieldError.Tag()).Inc()
+	}
+}
+
+// Expose the number of stored metadata entries
+func RegisterDatabase(store *storage.MetadataStore) {
+	Default.MustRegister(newDatabaseCollector(store))
+}
+
+// Expose the number of distinct indexed values per field
+func RegisterSearcher(searcher *search.Searcher, store *storage.MetadataStore) {
+	Default.MustRegister(newSearcherCollector(searcher, store))
+}
+
+func newDatabaseCollector(store *storage.MetadataStore) prometheus.Collector {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "metadata_entries",
+		Help: "Number of stored metadata entries",
+	}, func() float64 {
+		var entries int
+		store.Read(func(database *core.Database) {
+			entries = len(database.Metadatas)
+		})
+		return float64(entries)
+	})
+}
+
+// Collects the number of distinct indexed values per field
+// The index is read under the lock of the store changing it.
+type searcherCollector struct {
+	searcher *search.Searcher
+	store    *storage.MetadataStore
+	desc     *prometheus.Desc
+}
+
+func newSearcherCollector(searcher *search.Searcher, store *storage.MetadataStore) *searcherCollector {
+	return &searcherCollector{
+		searcher: searcher,
+		store:    store,
+		desc: prometheus.NewDesc(
+			"search_index_values",
+			"Number of distinct indexed values per field",
+			[]string{"field"},
+			nil),
+	}
+}
+
+func (c *searcherCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *searcherCollector) Collect(ch chan<- prometheus.Metric) {
+	values := map[string]int{}
+	c.store.Read(func(*core.Database) {
+		for field, fieldValues := range c.searcher.Index {
+			values[field] = len(fieldValues)
+		}
+	})
+	for field, count := range values {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), field)
+	}
+}
+
+// Filterer decorator that records how long filtering takes
+type InstrumentedFilterer struct {
+	Filterer search.Filterer
+}
+
+var _ search.Filterer = &InstrumentedFilterer{}
+
+func (f *InstrumentedFilterer) FilterMetadata(
+	ctx context.Context,
+	query map[string][]string,
+	database *core.Database) ([]*core.Metadata, error) {
+	start := time.Now()
+	results, err := f.Filterer.FilterMetadata(ctx, query, database)
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	FilterDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	return results, err
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/search"
+	"APIServerExercise/storage"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Number of observations of the histogram with the label values
+func histogramCount(histogram *prometheus.HistogramVec, labelValues ...string) uint64 {
+	metric := &dto.Metric{}
+	if err := histogram.WithLabelValues(labelValues...).(prometheus.Metric).Write(metric); err != nil {
+		panic(err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+// region Middleware
+
+func TestMiddleware(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(Middleware)
+	r.HandleFunc("/metrics-test/{id}", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	before := testutil.ToFloat64(HttpRequests.WithLabelValues("/metrics-test/{id}", http.MethodGet, "418"))
+
+	responseRecorder := httptest.NewRecorder()
+	r.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/metrics-test/1", nil))
+
+	assert.Equal(t, http.StatusTeapot, responseRecorder.Code)
+	assert.Equal(t, before+1, testutil.ToFloat64(HttpRequests.WithLabelValues("/metrics-test/{id}", http.MethodGet, "418")))
+	assert.NotZero(t, histogramCount(HttpRequestDuration, "/metrics-test/{id}", http.MethodGet, "418"))
+}
+
+// endregion
+
+func TestRecordValidationError(t *testing.T) {
+	before := testutil.ToFloat64(ValidationFailures.WithLabelValues("required"))
+
+	err := core.ValidateStruct(core.Maintainer{})
+	RecordValidationError(err)
+
+	assert.Equal(t, before+2, testutil.ToFloat64(ValidationFailures.WithLabelValues("required")))
+}
+
+func TestRecordValidationError_WithURLError(t *testing.T) {
+	before := testutil.ToFloat64(ValidationFailures.WithLabelValues("url"))
+	RecordValidationError(&core.URLError{Field: "website", Value: "website.com", Reason: "must be an absolute URL"})
+	assert.Equal(t, before+1, testutil.ToFloat64(ValidationFailures.WithLabelValues("url")))
+}
+
+func TestRecordValidationError_WithUnknownError(t *testing.T) {
+	before := testutil.ToFloat64(ValidationFailures.WithLabelValues("unknown"))
+	RecordValidationError(fmt.Errorf("test error"))
+	assert.Equal(t, before+1, testutil.ToFloat64(ValidationFailures.WithLabelValues("unknown")))
+}
+
+type stubFilterer struct {
+	err error
+}
+
+func (s *stubFilterer) FilterMetadata(context.Context, map[string][]string, *core.Database) ([]*core.Metadata, error) {
+	return nil, s.err
+}
+
+func TestInstrumentedFilterer_FilterMetadata(t *testing.T) {
+	before := histogramCount(FilterDuration, "error")
+
+	filterer := InstrumentedFilterer{Filterer: &stubFilterer{err: fmt.Errorf("test error")}}
+	_, err := filterer.FilterMetadata(context.Background(), map[string][]string{}, &core.Database{})
+
+	assert.Error(t, err)
+	assert.Equal(t, before+1, histogramCount(FilterDuration, "error"))
+}
+
+func TestRegisterSearcher(t *testing.T) {
+	id := uuid.New()
+	searcher := &search.Searcher{Index: map[string]map[string]map[uuid.UUID]bool{
+		"title": {
+			"app1": {id: true},
+			"app2": {id: true},
+		},
+	}}
+	store := &storage.MetadataStore{Database: &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{id: {}}}, Indexer: searcher}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newSearcherCollector(searcher, store), newDatabaseCollector(store))
+
+	expected := `# HELP metadata_entries Number of stored metadata entries
+# TYPE metadata_entries gauge
+metadata_entries 1
+# HELP search_index_values Number of distinct indexed values per field
+# TYPE search_index_values gauge
+search_index_values{field="title"} 2
+`
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(expected)))
+}
+
+func TestHandler(t *testing.T) {
+	ValidationFailures.WithLabelValues("handler-test").Inc()
+
+	responseRecorder := httptest.NewRecorder()
+	Handler().ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "# TYPE metadata_validation_failures_total counter\n")
+	assert.Contains(t, responseRecorder.Body.String(), "metadata_validation_failures_total{rule=\"handler-test\"} 1\n")
+}
@@ -0,0 +1,87 @@
+package openapi
+
+// Subset of the OpenAPI 3 document model used by this server
+// https://spec.openapis.org/oas/v3.0.3
+
+type Document struct {
+	OpenApi    string              `yaml:"openapi" json:"openapi"`
+	Info       Info                `yaml:"info" json:"info"`
+	Paths      map[string]PathItem `yaml:"paths" json:"paths"`
+	Components Components          `yaml:"components" json:"components"`
+}
+
+type Info struct {
+	Title       string `yaml:"title" json:"title"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Version     string `yaml:"version" json:"version"`
+}
+
+// Map of lower case HTTP method -> operation
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationId string                `yaml:"operationId" json:"operationId"`
+	Summary     string                `yaml:"summary" json:"summary"`
+	Description string                `yaml:"description,omitempty" json:"description,omitempty"`
+	Parameters  []Parameter           `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	RequestBody *RequestBody          `yaml:"requestBody,omitempty" json:"requestBody,omitempty"`
+	Responses   map[string]Response   `yaml:"responses" json:"responses"`
+	Security    []map[string][]string `yaml:"security,omitempty" json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `yaml:"name" json:"name"`
+	In          string  `yaml:"in" json:"in"`
+	Description string  `yaml:"description,omitempty" json:"description,omitempty"`
+	Required    bool    `yaml:"required,omitempty" json:"required,omitempty"`
+	Style       string  `yaml:"style,omitempty" json:"style,omitempty"`
+	Explode     *bool   `yaml:"explode,omitempty" json:"explode,omitempty"`
+	Schema      *Schema `yaml:"schema" json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `yaml:"required" json:"required"`
+	Content  map[string]MediaType `yaml:"content" json:"content"`
+}
+
+type Response struct {
+	Description string               `yaml:"description" json:"description"`
+	Headers     map[string]Header    `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Content     map[string]MediaType `yaml:"content,omitempty" json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `yaml:"description,omitempty" json:"description,omitempty"`
+	Schema      *Schema `yaml:"schema" json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `yaml:"schema" json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `yaml:"$ref,omitempty" json:"$ref,omitempty"`
+	Type                 string             `yaml:"type,omitempty" json:"type,omitempty"`
+	Format               string             `yaml:"format,omitempty" json:"format,omitempty"`
+	Description          string             `yaml:"description,omitempty" json:"description,omitempty"`
+	Enum                 []string           `yaml:"enum,omitempty" json:"enum,omitempty"`
+	Pattern              string             `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	MinLength            *int               `yaml:"minLength,omitempty" json:"minLength,omitempty"`
+	Minimum              *float64           `yaml:"minimum,omitempty" json:"minimum,omitempty"`
+	MinItems             *int               `yaml:"minItems,omitempty" json:"minItems,omitempty"`
+	Items                *Schema            `yaml:"items,omitempty" json:"items,omitempty"`
+	Properties           map[string]*Schema `yaml:"properties,omitempty" json:"properties,omitempty"`
+	Required             []string           `yaml:"required,omitempty" json:"required,omitempty"`
+	AdditionalProperties *Schema            `yaml:"additionalProperties,omitempty" json:"additionalProperties,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `yaml:"schemas" json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `yaml:"securitySchemes,omitempty" json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type string `yaml:"type" json:"type"`
+	In   string `yaml:"in" json:"in"`
+	Name string `yaml:"name" json:"name"`
+}
@@ -0,0 +1,119 @@
+package openapi
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// region Schemas
+
+func TestSchemas(t *testing.T) {
+	schemas := Schemas(componentTypes...)
+
+	assert.Len(t, schemas, 8)
+	metadata := schemas["Metadata"]
+	assert.Equal(t, "object", metadata.Type)
+	assert.Equal(t,
+		[]string{"title", "version", "maintainers", "company", "website", "source", "license", "description"},
+		metadata.Required)
+	assert.Equal(t, &Schema{Type: "string", Format: "uuid"}, metadata.Properties["id"])
+	assert.Equal(t, &Schema{Type: "string", Format: "uri", MinLength: intPtr(1)}, metadata.Properties["website"])
+	assert.Equal(t, &Schema{Type: "array", Items: Ref("Maintainer"), MinItems: intPtr(1)}, metadata.Properties["maintainers"])
+
+	maintainer := schemas["Maintainer"]
+	assert.Equal(t, []string{"name", "email"}, maintainer.Required)
+	assert.Equal(t, "email", maintainer.Properties["email"].Format)
+
+	assert.Equal(t, Ref("LinkStatus"), metadata.Properties["links"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, schemas["LinkCheck"].Properties["checkedAt"])
+	assert.Equal(t, &Schema{Type: "array", Items: Ref("MergeRecord")}, metadata.Properties["merged"])
+	assert.Equal(t, &Schema{Type: "array", Items: Ref("Dependency")}, metadata.Properties["dependencies"])
+	assert.Equal(t, "uuid", schemas["Dependency"].Properties["id"].Format)
+	assert.Equal(t, Ref("Deprecation"), metadata.Properties["deprecation"])
+	assert.Equal(t, []string{"reason"}, schemas["Deprecation"].Required)
+
+	resultPage := schemas["ResultPage"]
+	assert.Empty(t, resultPage.Required)
+	assert.Equal(t, &Schema{Type: "array", Items: Ref("Metadata")}, resultPage.Properties["resources"])
+}
+
+// endregion
+
+// region Generate
+
+func TestSpec_Generate(t *testing.T) {
+	r := mux.NewRouter()
+	handler := func(w http.ResponseWriter, req *http.Request) {}
+	for key := range operations {
+		parts := strings.SplitN(key, " ", 2)
+		r.HandleFunc(parts[1], handler).Methods(parts[0])
+	}
+
+	spec := &Spec{Info: Info{Title: "test", Version: "1"}}
+	err := spec.Generate(r)
+	assert.Nil(t, err)
+	assert.Equal(t, openApiVersion, spec.Document().OpenApi)
+	assert.Equal(t, "getMetadata", spec.Document().Paths["/metadata/{id}"]["get"].OperationId)
+	assert.Equal(t, "deleteMetadata", spec.Document().Paths["/metadata/{id}"]["delete"].OperationId)
+}
+
+func TestSpec_Generate_OutOfSync(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) {}
+	r := mux.NewRouter()
+	r.HandleFunc("/metadata", handler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/undocumented", handler)
+
+	spec := &Spec{}
+	err := spec.Generate(r)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "route POST /metadata is not documented")
+	assert.Contains(t, err.Error(), "route /undocumented does not restrict its methods")
+	assert.Contains(t, err.Error(), "operation DELETE /metadata/{id} has no route")
+	assert.NotContains(t, err.Error(), "GET /metadata ")
+	assert.Nil(t, spec.Document())
+}
+
+// endregion
+
+// region Handlers
+
+func TestSpec_Handlers(t *testing.T) {
+	spec := &Spec{Info: Info{Title: "test", Version: "1"}}
+	spec.document = &Document{
+		OpenApi:    openApiVersion,
+		Info:       spec.Info,
+		Paths:      map[string]PathItem{},
+		Components: Components{Schemas: Schemas(componentTypes...)},
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	spec.HandleYaml(responseRecorder, httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, yamlContentType, responseRecorder.Header().Get("Content-Type"))
+	var fromYaml map[string]interface{}
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), &fromYaml))
+	assert.Equal(t, openApiVersion, fromYaml["openapi"])
+
+	responseRecorder = httptest.NewRecorder()
+	spec.HandleJson(responseRecorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "application/json", responseRecorder.Header().Get("Content-Type"))
+	var fromJson map[string]interface{}
+	assert.Nil(t, json.Unmarshal(responseRecorder.Body.Bytes(), &fromJson))
+	assert.Equal(t, fromYaml["openapi"], fromJson["openapi"])
+	assert.Contains(t, responseRecorder.Body.String(), `"$ref": "#/components/schemas/Maintainer"`)
+}
+
+func TestSpec_Handlers_NotGenerated(t *testing.T) {
+	responseRecorder := httptest.NewRecorder()
+	(&Spec{}).HandleJson(responseRecorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusInternalServerError, responseRecorder.Code)
+}
+
+// endregion
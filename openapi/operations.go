@@ -0,0 +1,740 @@
+package openapi
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/ratelimit"
+)
+
+const (
+	yamlContentType = "application/x-yaml"
+	textContentType = "text/plain"
+	apiKeyScheme    = "apiKey"
+)
+
+// Component schemas generated from the core types
+var componentTypes = []interface{}{core.Metadata{}, core.Maintainer{}, core.ResultPage{}}
+
+var idParameter = Parameter{
+	Name:        "id",
+	In:          "path",
+	Description: "Id of the metadata",
+	Required:    true,
+	Schema:      &Schema{Type: "string", Format: "uuid"},
+}
+
+var verboseParameter = Parameter{
+	Name:        "verbose",
+	In:          "query",
+	Description: "List the result of every check",
+	Schema:      &Schema{Type: "boolean"},
+}
+
+func yamlContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{yamlContentType: {Schema: schema}}
+}
+
+func textResponse(description string) Response {
+	return Response{
+		Description: description,
+		Content:     map[string]MediaType{textContentType: {Schema: &Schema{Type: "string"}}},
+	}
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+var graphqlResult = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"data":   {Type: "object"},
+		"errors": {Type: "array", Items: &Schema{Type: "object"}},
+	},
+}
+
+var metadataBody = &RequestBody{Required: true, Content: yamlContent(Ref("Metadata"))}
+
+var kindParameter = Parameter{
+	Name:        "kind",
+	In:          "path",
+	Description: "Name of the kind, lower case letters, digits and dashes",
+	Required:    true,
+	Schema:      &Schema{Type: "string", Pattern: "^[a-z][a-z0-9-]{0,62}$"},
+}
+
+var resourceIdParameter = Parameter{
+	Name:        "id",
+	In:          "path",
+	Description: "Id of the resource",
+	Required:    true,
+	Schema:      &Schema{Type: "string", Format: "uuid"},
+}
+
+// Kinds are described by their JSON Schema at runtime, resources only have a known id
+var slugParameter = Parameter{
+	Name:        "slug",
+	In:          "path",
+	Description: "Slug of the application, lower case letters, digits and dashes",
+	Required:    true,
+	Schema:      &Schema{Type: "string", Pattern: "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"},
+}
+
+// Summary of the releases of an application
+var applicationSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"slug":     {Type: "string"},
+		"title":    {Type: "string", Description: "Title of the latest release"},
+		"latest":   {Type: "string", Description: "Version of the latest release"},
+		"versions": {Type: "array", Items: &Schema{Type: "string"}, Description: "Versions of the releases, newest first"},
+	},
+}
+
+var kindSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"name":   {Type: "string"},
+		"schema": {Type: "object", Description: "JSON Schema of the resources"},
+	},
+}
+
+var resourceSchema = &Schema{
+	Type:                 "object",
+	Properties:           map[string]*Schema{"id": {Type: "string", Format: "uuid"}},
+	AdditionalProperties: &Schema{},
+}
+
+var resourceBody = &RequestBody{Required: true, Content: yamlContent(resourceSchema)}
+
+var licenseCategorySchema = &Schema{Type: "string", Enum: []string{"allow", "review", "deny"}}
+
+var licenseEntrySchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"id":        {Type: "string", Format: "uuid"},
+		"title":     {Type: "string"},
+		"version":   {Type: "string"},
+		"company":   {Type: "string"},
+		"license":   {Type: "string"},
+		"category":  licenseCategorySchema,
+		"offending": {Type: "array", Items: &Schema{Type: "string"}, Description: "Licenses that are not allowed"},
+	},
+}
+
+var licenseReportSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"total": {Type: "integer"},
+		"categories": {Type: "array", Items: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"category": licenseCategorySchema,
+				"count":    {Type: "integer"},
+				"licenses": {Type: "array", Items: &Schema{
+					Type:       "object",
+					Properties: map[string]*Schema{"license": {Type: "string"}, "count": {Type: "integer"}},
+				}},
+			},
+		}},
+		"offending": {Type: "array", Items: licenseEntrySchema},
+	},
+}
+
+// Summary returned by GET /reports/links
+var linkReportSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"checked":   {Type: "integer"},
+		"unchecked": {Type: "integer"},
+		"broken":    {Type: "integer"},
+		"links": {Type: "array", Items: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"id":         {Type: "string", Format: "uuid"},
+				"title":      {Type: "string"},
+				"field":      {Type: "string", Enum: []string{"website", "source"}},
+				"url":        {Type: "string"},
+				"status":     {Type: "integer"},
+				"error":      {Type: "string"},
+				"redirectTo": {Type: "string"},
+				"checkedAt":  {Type: "string", Format: "date-time"},
+			},
+		}},
+	},
+}
+
+var duplicateEntrySchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"id":      {Type: "string", Format: "uuid"},
+		"title":   {Type: "string"},
+		"version": {Type: "string"},
+	},
+}
+
+// Candidates returned by GET /duplicates
+var duplicatesSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"detectedAt": {Type: "string", Format: "date-time"},
+		"candidates": {Type: "array", Items: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"score":       {Type: "number", Description: "Weighted sum of the title, source and maintainers similarities"},
+				"title":       {Type: "number"},
+				"source":      {Type: "number"},
+				"maintainers": {Type: "number"},
+				"first":       duplicateEntrySchema,
+				"second":      duplicateEntrySchema,
+			},
+		}},
+	},
+}
+
+// Sent when another metadata has the same values for the fields of a unique constraint
+var conflictResponse = Response{
+	Description: "Another metadata has the same values for the fields of a unique constraint, IE: the same title and version",
+	Headers: map[string]Header{
+		"Location": {Description: "Path of the existing metadata", Schema: &Schema{Type: "string"}},
+	},
+	Content: map[string]MediaType{textContentType: {Schema: &Schema{Type: "string"}}},
+}
+
+// Sent when saving metadata, the Location header is only set for unique constraints
+var putConflictResponse = Response{
+	Description: conflictResponse.Description + ", the dependencies of the metadata lead back to it, " +
+		"or the lifecycle can not change to the requested state, IE: from retired to published",
+	Headers: conflictResponse.Headers,
+	Content: conflictResponse.Content,
+}
+
+var transitiveParameter = Parameter{
+	Name:        "transitive",
+	In:          "query",
+	Description: "Whether to follow the graph past the direct neighbours",
+	Schema:      &Schema{Type: "boolean"},
+}
+
+// Metadata reached through the dependency graph
+var dependencyGraphSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"root": dependencyEntrySchema,
+		"entries": {Type: "array", Items: dependencyEntrySchema,
+			Description: "Breadth first, each metadata once at its lowest depth"},
+		"unresolved": {Type: "array", Items: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"id":         {Type: "string", Format: "uuid", Description: "Id of the metadata with the dependency"},
+				"dependency": Ref("Dependency"),
+			},
+		}},
+	},
+}
+
+var dependencyEntrySchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"id":      {Type: "string", Format: "uuid"},
+		"title":   {Type: "string"},
+		"version": {Type: "string"},
+		"depth":   {Type: "integer", Description: "1 for the direct dependencies or dependents, 2 for theirs, and so on"},
+	},
+}
+
+// Sent with a deprecated or retired metadata
+var deprecationHeaders = map[string]Header{
+	"Deprecation": {Description: "When the metadata was deprecated, IE: `@1735689600`", Schema: &Schema{Type: "string"}},
+	"Sunset":      {Description: "When the metadata is or was retired, only set when the deprecation has a sunset date", Schema: &Schema{Type: "string"}},
+	"Link":        {Description: "Path of the replacement, IE: `</metadata/{id}>; rel=\"successor-version\"`", Schema: &Schema{Type: "string"}},
+}
+
+// Sent when the license policy asks for a review or only warns about a denied license
+var licenseWarningHeaders = map[string]Header{
+	"Warning": {Description: "License policy warning, IE: `299 - \"license GPL-3.0-only requires a legal review: GPL-3.0-only\"`",
+		Schema: &Schema{Type: "string"}},
+}
+
+var adminSecurity = []map[string][]string{{apiKeyScheme: {}}}
+
+// Documentation of every route, keyed by "METHOD path"
+// Every route registered in the routers must have an entry and every entry must have a route.
+var operations = map[string]*Operation{
+	"GET /metadata": gated(&Operation{
+		OperationId: "listMetadata",
+		Summary:     "Search metadata",
+		Description: "Returns a page of the metadata matching every filter. " +
+			"Filters are field paths such as `license` or `maintainers.email`, matched exactly or by word. " +
+			"A field can be followed by an operator, `eq` (default) or `ne`, IE: `license[ne]=MIT`. " +
+			"Draft and retired metadata are hidden unless a filter is on `lifecycle`, `lifecycle=any` matches every state.",
+		Parameters: []Parameter{
+			{
+				Name:        "offset",
+				In:          "query",
+				Description: "The position from where the page should start",
+				Schema:      &Schema{Type: "integer", Minimum: floatPtr(0)},
+			},
+			{
+				Name:        "pageSize",
+				In:          "query",
+				Description: "The size of the page, at most the configured maximum page size",
+				Schema:      &Schema{Type: "integer", Minimum: floatPtr(1)},
+			},
+			{
+				Name:        "collapse",
+				In:          "query",
+				Description: "Keeps the latest matching release of each application",
+				Schema:      &Schema{Type: "string", Enum: []string{"application"}},
+			},
+			{
+				Name:        "filter",
+				In:          "query",
+				Description: "Field path, optionally with an operator, to value, IE: `license=Apache-2.0`, `source.host=github.com` or `labels.tier=critical`",
+				Style:       "form",
+				Explode:     boolPtr(true),
+				Schema:      &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			},
+			{
+				Name: "labelSelector",
+				In:   "query",
+				Description: "Comma separated label requirements: `key=value`, `key!=value`, `key in (a,b)`, " +
+					"`key notin (a,b)`, `key` or `!key`",
+				Schema: &Schema{Type: "string"},
+			},
+		},
+		Responses: map[string]Response{
+			"200": {Description: "A page of metadata", Content: yamlContent(Ref("ResultPage"))},
+			"400": textResponse("Invalid paging parameters or filter"),
+		},
+	}),
+	"PUT /metadata": gated(&Operation{
+		OperationId: "createMetadata",
+		Summary:     "Create metadata",
+		Description: "Creates a metadata entry, a random id is generated when the payload has none.",
+		RequestBody: metadataBody,
+		Responses: map[string]Response{
+			"201": {Description: "The created metadata", Headers: licenseWarningHeaders, Content: yamlContent(Ref("Metadata"))},
+			"400": textResponse("The payload could not be decoded, failed validation,, has a rejected license, a dependency that does not resolve or a deprecation that does not match its lifecycle"),
+			"409": putConflictResponse,
+		},
+	}),
+	"GET /metadata/{id}": gated(&Operation{
+		OperationId: "getMetadata",
+		Summary:     "Get metadata",
+		Parameters:  []Parameter{idParameter},
+		Responses: map[string]Response{
+			"200": {Description: "The metadata", Headers: deprecationHeaders, Content: yamlContent(Ref("Metadata"))},
+			"400": textResponse("The id is not a valid UUID"),
+		},
+	}),
+	"PUT /metadata/{id}": gated(&Operation{
+		OperationId: "putMetadata",
+		Summary:     "Create or replace metadata",
+		Parameters:  []Parameter{idParameter},
+		RequestBody: metadataBody,
+		Responses: map[string]Response{
+			"201": {Description: "The saved metadata", Headers: licenseWarningHeaders, Content: yamlContent(Ref("Metadata"))},
+			"400": textResponse("The id is not a valid UUID, the payload failed validation,, has a rejected license, a dependency that does not resolve or a deprecation that does not match its lifecycle"),
+			"409": putConflictResponse,
+		},
+	}),
+	"GET /metadata/{id}/dependencies": gated(&Operation{
+		OperationId: "getDependencies",
+		Summary:     "Dependencies of metadata",
+		Description: "Returns the metadata the metadata depends on, and with `transitive=true` what they depend on in turn. " +
+			"A dependency by title resolves to the newest version in its range.",
+		Parameters: []Parameter{idParameter, transitiveParameter},
+		Responses: map[string]Response{
+			"200": {Description: "The dependencies", Content: yamlContent(dependencyGraphSchema)},
+			"400": textResponse("The id is not a valid UUID or transitive is not a boolean"),
+			"404": textResponse("The metadata does not exist"),
+		},
+	}),
+	"GET /metadata/{id}/dependents": gated(&Operation{
+		OperationId: "getDependents",
+		Summary:     "Dependents of metadata",
+		Description: "Returns the metadata whose dependencies resolve to the metadata, and with `transitive=true` " +
+			"their own dependents, IE: every metadata impacted when it is deprecated.",
+		Parameters: []Parameter{idParameter, transitiveParameter},
+		Responses: map[string]Response{
+			"200": {Description: "The dependents", Content: yamlContent(dependencyGraphSchema)},
+			"400": textResponse("The id is not a valid UUID or transitive is not a boolean"),
+			"404": textResponse("The metadata does not exist"),
+		},
+	}),
+	"DELETE /metadata/{id}": gated(&Operation{
+		OperationId: "deleteMetadata",
+		Summary:     "Delete metadata",
+		Parameters:  []Parameter{idParameter},
+		Responses: map[string]Response{
+			"200": {Description: "The metadata was deleted"},
+			"400": textResponse("The id is not a valid UUID"),
+			"404": {Description: "No metadata with this id"},
+			"409": textResponse("A dependency by title of another metadata would then resolve to a metadata leading back to it"),
+		},
+	}),
+	"GET /metrics": gated(&Operation{
+		OperationId: "getMetrics",
+		Summary:     "Prometheus metrics",
+		Responses: map[string]Response{
+			"200": textResponse("Metrics in the Prometheus text exposition format"),
+		},
+	}),
+	"GET /config": gated(&Operation{
+		OperationId: "getConfig",
+		Summary:     "Effective configuration",
+		Description: "Returns the effective configuration with secrets masked, only for admin roles.",
+		Security:    adminSecurity,
+		Responses: map[string]Response{
+			"200": {Description: "The configuration", Content: yamlContent(&Schema{Type: "object"})},
+			"401": {Description: "Missing or unknown API key"},
+			"403": {Description: "The API key is not an admin key"},
+		},
+	}),
+	"GET /reports/licenses": gated(&Operation{
+		OperationId: "getLicenseReport",
+		Summary:     "License compliance report",
+		Description: "Sorts every metadata by the license policy category of its license and lists the metadata " +
+			"whose license is not allowed. With `format=csv` or an `Accept: text/csv` header, returns one row per metadata.",
+		Parameters: []Parameter{
+			{
+				Name:        "format",
+				In:          "query",
+				Description: "Format of the report",
+				Schema:      &Schema{Type: "string", Enum: []string{"yaml", "csv"}},
+			},
+		},
+		Responses: map[string]Response{
+			"200": {Description: "The report", Content: map[string]MediaType{
+				yamlContentType: {Schema: licenseReportSchema},
+				"text/csv":      {Schema: &Schema{Type: "string"}},
+			}},
+			"400": textResponse("Unknown format"),
+		},
+	}),
+	"GET /reports/links": gated(&Operation{
+		OperationId: "getLinkReport",
+		Summary:     "Broken link report",
+		Description: "Counts the metadata whose website and source were checked by the link checker " +
+			"and lists every broken link with the result of its last check.",
+		Responses: map[string]Response{
+			"200": {Description: "The report", Content: yamlContent(linkReportSchema)},
+		},
+	}),
+	"GET /duplicates": gated(&Operation{
+		OperationId: "listDuplicates",
+		Summary:     "Duplicate candidates",
+		Description: "Returns the pairs of metadata that are likely the same application, highest score first, as found " +
+			"by the last detection. The score weighs the similarity of the titles, whether the sources are the same " +
+			"repository and the maintainers both have.",
+		Parameters: []Parameter{
+			{
+				Name:        "minScore",
+				In:          "query",
+				Description: "Lowest score of the returned pairs, the detection threshold by default",
+				Schema:      &Schema{Type: "number", Minimum: floatPtr(0)},
+			},
+		},
+		Responses: map[string]Response{
+			"200": {Description: "The candidates", Content: yamlContent(duplicatesSchema)},
+			"400": textResponse("minScore is not a number between 0 and 1"),
+		},
+	}),
+	"POST /duplicates/merge": gated(&Operation{
+		OperationId: "mergeDuplicates",
+		Summary:     "Merge two metadata",
+		Description: "Merges a metadata into another one and deletes it, only for admin roles. The kept metadata gains " +
+			"the maintainers, labels and annotations it does not have, and a record of the merged metadata in its `merged` history. " +
+			"Dependencies and replacements referring to the merged metadata by id refer to the kept one instead.",
+		Security: adminSecurity,
+		RequestBody: &RequestBody{Required: true, Content: yamlContent(&Schema{
+			Type:     "object",
+			Required: []string{"keep", "merge"},
+			Properties: map[string]*Schema{
+				"keep":  {Type: "string", Format: "uuid", Description: "Id of the metadata that is kept"},
+				"merge": {Type: "string", Format: "uuid", Description: "Id of the metadata merged and deleted"},
+			},
+		})},
+		Responses: map[string]Response{
+			"200": {Description: "The kept metadata", Content: yamlContent(Ref("Metadata"))},
+			"400": textResponse("The ids are invalid or the same"),
+			"401": {Description: "Missing or unknown API key"},
+			"403": {Description: "The API key is not an admin key"},
+			"404": textResponse("A metadata does not exist"),
+			"409": {
+				Description: conflictResponse.Description + ", or the dependencies would form a cycle",
+				Headers:     conflictResponse.Headers,
+				Content:     conflictResponse.Content,
+			},
+		},
+	}),
+	"GET /openapi.yaml": gated(&Operation{
+		OperationId: "getOpenApiYaml",
+		Summary:     "This document in YAML",
+		Responses: map[string]Response{
+			"200": {Description: "The OpenAPI document", Content: yamlContent(&Schema{Type: "object"})},
+		},
+	}),
+	"GET /openapi.json": gated(&Operation{
+		OperationId: "getOpenApiJson",
+		Summary:     "This document in JSON",
+		Responses: map[string]Response{
+			"200": {
+				Description: "The OpenAPI document",
+				Content:     jsonContent(&Schema{Type: "object"}),
+			},
+		},
+	}),
+	"POST /graphql": gated(&Operation{
+		OperationId: "graphql",
+		Summary:     "Query the catalog with GraphQL",
+		Description: "Executes a read only GraphQL query over the metadata and their maintainers. " +
+			"Queries deeper or more complex than the configured limits are rejected before execution.",
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(&Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"query":         {Type: "string"},
+				"operationName": {Type: "string"},
+				"variables":     {Type: "object"},
+			},
+			Required: []string{"query"},
+		})},
+		Responses: map[string]Response{
+			"200": {Description: "The data, with the errors of the fields that failed", Content: jsonContent(graphqlResult)},
+			"400": {Description: "The query could not be parsed, is invalid or exceeds the limits", Content: jsonContent(graphqlResult)},
+		},
+	}),
+	"GET /kinds": gated(&Operation{
+		OperationId: "listKinds",
+		Summary:     "List kinds",
+		Description: "Returns every registered kind with its number of resources.",
+		Responses: map[string]Response{
+			"200": {Description: "The kinds sorted by name", Content: yamlContent(&Schema{
+				Type: "array",
+				Items: &Schema{
+					Type: "object",
+					Properties: map[string]*Schema{
+						"name":      {Type: "string"},
+						"resources": {Type: "integer"},
+					},
+				},
+			})},
+		},
+	}),
+	"GET /applications": gated(&Operation{
+		OperationId: "listApplications",
+		Summary:     "List the applications",
+		Description: "Groups the metadata by their `application` slug, sorted by slug.",
+		Responses: map[string]Response{
+			"200": {Description: "Every application", Content: yamlContent(&Schema{Type: "array", Items: applicationSchema})},
+		},
+	}),
+	"GET /applications/{slug}": gated(&Operation{
+		OperationId: "getApplication",
+		Summary:     "Get an application",
+		Parameters:  []Parameter{slugParameter},
+		Responses: map[string]Response{
+			"200": {Description: "The application", Content: yamlContent(applicationSchema)},
+			"404": textResponse("The application has no release"),
+		},
+	}),
+	"GET /applications/{slug}/versions": gated(&Operation{
+		OperationId: "listApplicationVersions",
+		Summary:     "List the releases of an application",
+		Description: "Returns the metadata of every release, newest first by semantic version. " +
+			"Versions that are not semantic versions come last.",
+		Parameters: []Parameter{slugParameter},
+		Responses: map[string]Response{
+			"200": {Description: "The releases", Content: yamlContent(&Schema{Type: "array", Items: Ref("Metadata")})},
+			"404": textResponse("The application has no release"),
+		},
+	}),
+	"GET /applications/{slug}/versions/{version}": gated(&Operation{
+		OperationId: "getApplicationVersion",
+		Summary:     "Get a release of an application",
+		Parameters: []Parameter{slugParameter, {
+			Name:        "version",
+			In:          "path",
+			Description: "Version of the release, or `latest` for the newest release that is not a pre-release",
+			Required:    true,
+			Schema:      &Schema{Type: "string"},
+		}},
+		Responses: map[string]Response{
+			"200": {Description: "The release", Headers: deprecationHeaders, Content: yamlContent(Ref("Metadata"))},
+			"404": textResponse("The application has no release with the version"),
+		},
+	}),
+	"GET /kinds/{kind}": gated(&Operation{
+		OperationId: "getKind",
+		Summary:     "Get the schema of a kind",
+		Parameters:  []Parameter{kindParameter},
+		Responses: map[string]Response{
+			"200": {Description: "The kind and its JSON Schema", Content: yamlContent(kindSchema)},
+			"404": textResponse("The kind is not registered"),
+		},
+	}),
+	"PUT /kinds/{kind}": gated(&Operation{
+		OperationId: "putKind",
+		Summary:     "Register a kind",
+		Description: "Registers a kind with the JSON Schema of its resources, or replaces its schema, only for admin roles. " +
+			"The schema must describe an object and can not define the `id` property. Every keyword of JSON Schema draft " +
+			"2020-12 is supported, or of the draft named by `$schema`, `format` is asserted and `$ref` can only refer to " +
+			"the schema itself.",
+		Parameters:  []Parameter{kindParameter},
+		Security:    adminSecurity,
+		RequestBody: &RequestBody{Required: true, Content: yamlContent(&Schema{Type: "object"})},
+		Responses: map[string]Response{
+			"201": {Description: "The registered kind", Content: yamlContent(kindSchema)},
+			"400": textResponse("The name or the schema is invalid, or `$ref` refers outside of the schema"),
+			"401": {Description: "Missing or unknown API key"},
+			"403": {Description: "The API key is not an admin key"},
+			"409": textResponse("The new schema rejects an existing resource"),
+		},
+	}),
+	"DELETE /kinds/{kind}": gated(&Operation{
+		OperationId: "deleteKind",
+		Summary:     "Unregister a kind",
+		Description: "Removes a kind without resources, only for admin roles.",
+		Parameters:  []Parameter{kindParameter},
+		Security:    adminSecurity,
+		Responses: map[string]Response{
+			"200": {Description: "The kind was unregistered"},
+			"401": {Description: "Missing or unknown API key"},
+			"403": {Description: "The API key is not an admin key"},
+			"404": textResponse("The kind is not registered"),
+			"409": textResponse("The kind still has resources"),
+		},
+	}),
+	"GET /kinds/{kind}/resources": gated(&Operation{
+		OperationId: "listResources",
+		Summary:     "Search the resources of a kind",
+		Description: "Returns a page of the resources matching every filter, the same as `GET /metadata`. " +
+			"Filters are the property paths of the schema of the kind, IE: `owner.email`.",
+		Parameters: []Parameter{
+			kindParameter,
+			{
+				Name:        "offset",
+				In:          "query",
+				Description: "The position from where the page should start",
+				Schema:      &Schema{Type: "integer", Minimum: floatPtr(0)},
+			},
+			{
+				Name:        "pageSize",
+				In:          "query",
+				Description: "The size of the page, at most the configured maximum page size",
+				Schema:      &Schema{Type: "integer", Minimum: floatPtr(1)},
+			},
+			{
+				Name:        "filter",
+				In:          "query",
+				Description: "Property path, optionally with an operator, to value, IE: `owner.email=a@hotmail.com`",
+				Style:       "form",
+				Explode:     boolPtr(true),
+				Schema:      &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			},
+		},
+		Responses: map[string]Response{
+			"200": {Description: "A page of resources", Content: yamlContent(&Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"resources": {Type: "array", Items: resourceSchema},
+					"nextLink":  {Type: "string"},
+				},
+			})},
+			"400": textResponse("Invalid paging parameters or filter"),
+			"404": textResponse("The kind is not registered"),
+		},
+	}),
+	"PUT /kinds/{kind}/resources": gated(&Operation{
+		OperationId: "createResource",
+		Summary:     "Create a resource",
+		Description: "Creates a resource validated against the schema of its kind, a random id is generated when the payload has none.",
+		Parameters:  []Parameter{kindParameter},
+		RequestBody: resourceBody,
+		Responses: map[string]Response{
+			"201": {Description: "The created resource", Content: yamlContent(resourceSchema)},
+			"400": textResponse("The payload could not be decoded or failed validation"),
+			"404": textResponse("The kind is not registered"),
+		},
+	}),
+	"GET /kinds/{kind}/resources/{id}": gated(&Operation{
+		OperationId: "getResource",
+		Summary:     "Get a resource",
+		Parameters:  []Parameter{kindParameter, resourceIdParameter},
+		Responses: map[string]Response{
+			"200": {Description: "The resource", Content: yamlContent(resourceSchema)},
+			"400": textResponse("The id is not a valid UUID"),
+			"404": textResponse("The kind is not registered or has no resource with this id"),
+		},
+	}),
+	"PUT /kinds/{kind}/resources/{id}": gated(&Operation{
+		OperationId: "putResource",
+		Summary:     "Create or replace a resource",
+		Parameters:  []Parameter{kindParameter, resourceIdParameter},
+		RequestBody: resourceBody,
+		Responses: map[string]Response{
+			"201": {Description: "The saved resource", Content: yamlContent(resourceSchema)},
+			"400": textResponse("The id is not a valid UUID or the payload failed validation"),
+			"404": textResponse("The kind is not registered"),
+		},
+	}),
+	"DELETE /kinds/{kind}/resources/{id}": gated(&Operation{
+		OperationId: "deleteResource",
+		Summary:     "Delete a resource",
+		Parameters:  []Parameter{kindParameter, resourceIdParameter},
+		Responses: map[string]Response{
+			"200": {Description: "The resource was deleted"},
+			"400": textResponse("The id is not a valid UUID"),
+			"404": textResponse("The kind is not registered or has no resource with this id"),
+		},
+	}),
+	"GET /livez":   healthOperation("livez", "Liveness probe"),
+	"GET /readyz":  healthOperation("readyz", "Readiness probe"),
+	"GET /healthz": healthOperation("healthz", "Readiness probe, kept for older clients"),
+}
+
+func healthOperation(name string, summary string) *Operation {
+	return &Operation{
+		OperationId: name,
+		Summary:     summary,
+		Parameters:  []Parameter{verboseParameter},
+		Responses: map[string]Response{
+			"200": textResponse("Every check passed"),
+			"503": textResponse("A check failed"),
+		},
+	}
+}
+
+var securitySchemes = map[string]SecurityScheme{
+	apiKeyScheme: {Type: "apiKey", In: "header", Name: ratelimit.ApiKeyHeader},
+}
+
+// Responses every operation behind the middlewares can return
+var gatedResponses = map[string]Response{
+	"429": {
+		Description: "Rate limit exceeded",
+		Headers:     map[string]Header{"Retry-After": {Schema: &Schema{Type: "integer"}}},
+	},
+	"503": {
+		Description: "The index is still being rebuilt",
+		Headers:     map[string]Header{"Retry-After": {Schema: &Schema{Type: "integer"}}},
+	},
+}
+
+// Adds the responses of the rate limiter and the readiness gate
+func gated(operation *Operation) *Operation {
+	for status, response := range gatedResponses {
+		if _, ok := operation.Responses[status]; !ok {
+			operation.Responses[status] = response
+		}
+	}
+	return operation
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,153 @@
+package openapi
+
+import (
+	"APIServerExercise/util"
+	"github.com/google/uuid"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const componentsPrefix = "#/components/schemas/"
+
+// Types that marshal to a string instead of their Go structure
+var stringTypes = map[reflect.Type]*Schema{
+	reflect.TypeOf(uuid.UUID{}):    {Type: "string", Format: "uuid"},
+	reflect.TypeOf(util.Yamlurl{}): {Type: "string", Format: "uri"},
+	reflect.TypeOf(time.Time{}):    {Type: "string", Format: "date-time"},
+}
+
+// Returns a reference to a component schema
+func Ref(name string) *Schema {
+	return &Schema{Ref: componentsPrefix + name}
+}
+
+// Builds the schemas of the given structs from their yaml and validate tags
+// Nested structs are added as components as well and referenced by name.
+func Schemas(values ...interface{}) map[string]*Schema {
+	schemas := map[string]*Schema{}
+	for _, value := range values {
+		schemaOf(reflect.TypeOf(value), schemas)
+	}
+	return schemas
+}
+
+func schemaOf(t reflect.Type, schemas map[string]*Schema) *Schema {
+	if s, ok := stringTypes[t]; ok {
+		copied := *s
+		return &copied
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaOf(t.Elem(), schemas)
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), schemas)}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Struct:
+		// Only build the component the first time it is seen, also stops recursive types
+		if _, ok := schemas[t.Name()]; !ok {
+			schemas[t.Name()] = nil
+			schemas[t.Name()] = structSchema(t, schemas)
+		}
+		return Ref(t.Name())
+	default:
+		return &Schema{}
+	}
+}
+
+func structSchema(t reflect.Type, schemas map[string]*Schema) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		property := schemaOf(field.Type, schemas)
+		if applyRules(property, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+// Translates the validator rules to schema constraints
+// Rules after dive apply to the elements of a slice.
+// Returns whether the field is required.
+func applyRules(schema *Schema, validate string) bool {
+	required := false
+	target := schema
+	for _, rule := range strings.Split(validate, ",") {
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+
+		switch name {
+		case "required":
+			if target == schema {
+				required = true
+			}
+			if target.Type == "string" && target.MinLength == nil {
+				target.MinLength = intPtr(1)
+			}
+		case "dive":
+			if schema.Items == nil {
+				return required
+			}
+			target = schema.Items
+		case "email":
+			target.Format = "email"
+		case "url", "uri":
+			target.Format = "uri"
+		case "uuid", "uuid4":
+			target.Format = "uuid"
+		case "oneof":
+			target.Enum = strings.Fields(param)
+		case "gt", "gte", "min":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			if name == "gt" {
+				n++
+			}
+			setMinimum(target, n)
+		}
+	}
+	return required
+}
+
+// gt, gte and min constrain the length of strings and slices and the value of numbers
+func setMinimum(schema *Schema, n int) {
+	switch schema.Type {
+	case "array":
+		schema.MinItems = intPtr(n)
+	case "string":
+		schema.MinLength = intPtr(n)
+	case "integer", "number":
+		f := float64(n)
+		schema.Minimum = &f
+	}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
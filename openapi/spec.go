@@ -0,0 +1,111 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const openApiVersion = "3.0.3"
+
+// Serves the OpenAPI document of the routes registered in the routers
+type Spec struct {
+	Info     Info
+	document *Document
+}
+
+// Builds the document from the routes of the routers
+// Fails when a route is not documented or a documented operation has no route, so the two can not drift apart.
+func (s *Spec) Generate(routers ...*mux.Router) error {
+	document := &Document{
+		OpenApi: openApiVersion,
+		Info:    s.Info,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas:         Schemas(componentTypes...),
+			SecuritySchemes: securitySchemes,
+		},
+	}
+
+	var problems []string
+	routed := map[string]bool{}
+	for _, router := range routers {
+		err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+			path, err := route.GetPathTemplate()
+			if err != nil {
+				return nil
+			}
+			methods, err := route.GetMethods()
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("route %s does not restrict its methods", path))
+				return nil
+			}
+			for _, method := range methods {
+				key := fmt.Sprintf("%s %s", method, path)
+				routed[key] = true
+				operation, ok := operations[key]
+				if !ok {
+					problems = append(problems, fmt.Sprintf("route %s is not documented", key))
+					continue
+				}
+				if document.Paths[path] == nil {
+					document.Paths[path] = PathItem{}
+				}
+				document.Paths[path][strings.ToLower(method)] = operation
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for key := range operations {
+		if !routed[key] {
+			problems = append(problems, fmt.Sprintf("operation %s has no route", key))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("the OpenAPI document is out of sync with the routes: %s", strings.Join(problems, ", "))
+	}
+	s.document = document
+	return nil
+}
+
+// Returns the generated document, nil until Generate succeeds
+func (s *Spec) Document() *Document {
+	return s.document
+}
+
+// GET /openapi.yaml
+func (s *Spec) HandleYaml(w http.ResponseWriter, req *http.Request) {
+	s.write(w, yamlContentType, yaml.Marshal)
+}
+
+// GET /openapi.json
+func (s *Spec) HandleJson(w http.ResponseWriter, req *http.Request) {
+	s.write(w, "application/json", func(v interface{}) ([]byte, error) {
+		return json.MarshalIndent(v, "", "  ")
+	})
+}
+
+func (s *Spec) write(w http.ResponseWriter, contentType string, marshal func(interface{}) ([]byte, error)) {
+	if s.document == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	body, err := marshal(s.document)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "Error marshalling the OpenAPI document: %v", err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
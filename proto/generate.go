@@ -0,0 +1,4 @@
+// Protobuf definitions of the gRPC API, the generated code is committed
+package proto
+
+//go:generate sh -c "test metadata/v1/metadata.pb.go -nt metadata/v1/metadata.proto && exit 0; protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative metadata/v1/metadata.proto"
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Returns the API key the caller sent in the x-api-key metadata, empty when there is none
+func CallApiKey(ctx context.Context) string {
+	md, _ := grpcmetadata.FromIncomingContext(ctx)
+	if values := md.Get(strings.ToLower(ApiKeyHeader)); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Returns the IP of the client making the call
+func PeerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// Rejects calls exceeding the caller's budget with RESOURCE_EXHAUSTED, the gRPC counterpart of Middleware
+// Calls share the budget of the HTTP requests of the same caller.
+func (l *Limiter) UnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if err := l.takeCall(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// A stream takes a single token when it starts, whatever the number of messages
+func (l *Limiter) StreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	if err := l.takeCall(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// Takes a token for the call, the retry-after header is sent when the budget is exceeded
+func (l *Limiter) takeCall(ctx context.Context, method string) error {
+	identity, role := l.identifyCaller(CallApiKey(ctx), PeerIP(ctx))
+	read := false
+	for _, readMethod := range l.ReadMethods {
+		if method == readMethod {
+			read = true
+		}
+	}
+	limit, kind := l.limitOf(role, read)
+	if limit.Rate <= 0 {
+		return nil
+	}
+
+	d := l.take(fmt.Sprintf("%s|%s", kind, identity), limit)
+	if d.allowed {
+		return nil
+	}
+	grpc.SetHeader(ctx, grpcmetadata.Pairs("retry-after", strconv.Itoa(ceilSeconds(d.retryAfter))))
+	return status.Errorf(codes.ResourceExhausted, "Rate limit exceeded for %s requests, retry in %d seconds",
+		kind, ceilSeconds(d.retryAfter))
+}
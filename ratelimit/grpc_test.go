@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"net/http"
+	"testing"
+)
+
+const getMethod = "/metadata.v1.MetadataService/GetMetadata"
+
+func call(limiter *Limiter, method string, remoteAddr string, apiKey string) error {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(remoteAddr), Port: 1234}})
+	if apiKey != "" {
+		ctx = grpcmetadata.NewIncomingContext(ctx, grpcmetadata.Pairs("x-api-key", apiKey))
+	}
+	_, err := limiter.UnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+	return err
+}
+
+func TestLimiter_UnaryInterceptor(t *testing.T) {
+	limiter := setupTest()
+	limiter.ReadMethods = []string{getMethod}
+
+	assert.Nil(t, call(limiter, getMethod, "10.0.0.1", ""))
+	assert.Nil(t, call(limiter, getMethod, "10.0.0.1", ""))
+	err := call(limiter, getMethod, "10.0.0.1", "")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, "Rate limit exceeded for read requests, retry in 1 seconds", status.Convert(err).Message())
+
+	// Other methods write and have their own budget
+	assert.Nil(t, call(limiter, "/metadata.v1.MetadataService/PutMetadata", "10.0.0.1", ""))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call(limiter, "/metadata.v1.MetadataService/PutMetadata", "10.0.0.1", "")))
+
+	// Known API keys have the budget of their role
+	assert.Nil(t, call(limiter, getMethod, "10.0.0.1", "adminkey"))
+}
+
+func TestLimiter_UnaryInterceptor_SharesHttpBudget(t *testing.T) {
+	limiter := setupTest()
+	limiter.ReadMethods = []string{getMethod}
+
+	assert.Equal(t, http.StatusOK, serve(limiter, http.MethodGet, "10.0.0.1:1234", "").Code)
+	assert.Nil(t, call(limiter, getMethod, "10.0.0.1", ""))
+	assert.Equal(t, http.StatusTooManyRequests, serve(limiter, http.MethodGet, "10.0.0.1:1234", "").Code)
+}
@@ -0,0 +1,251 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Header clients use to identify themselves
+	ApiKeyHeader  = "X-API-Key"
+	AnonymousRole = "anonymous"
+	// How often idle buckets are removed so the bucket map does not grow forever
+	sweepInterval = time.Minute
+)
+
+// Budget for a single kind of request (read or write)
+// Rate is the number of tokens added per second, Burst is the bucket capacity.
+// A Rate of 0 or less means unlimited.
+type Limit struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+type RoleLimits struct {
+	Read  Limit `yaml:"read"`
+	Write Limit `yaml:"write"`
+}
+
+type Config struct {
+	// Map of role name -> limits for that role
+	Roles map[string]RoleLimits `yaml:"roles"`
+	// Map of API key -> role name
+	ApiKeys map[string]string `yaml:"apiKeys"`
+}
+
+// Limits used when none are configured
+func DefaultConfig() *Config {
+	return &Config{
+		Roles: map[string]RoleLimits{
+			AnonymousRole: {
+				Read:  Limit{Rate: 50, Burst: 100},
+				Write: Limit{Rate: 5, Burst: 10},
+			},
+		},
+		ApiKeys: map[string]string{},
+	}
+}
+
+func (c *Config) Validate() error {
+	if _, ok := c.Roles[AnonymousRole]; !ok {
+		return fmt.Errorf("rate limit config must define the %s role", AnonymousRole)
+	}
+	for key, role := range c.ApiKeys {
+		if _, ok := c.Roles[role]; !ok {
+			return fmt.Errorf("api key %s references unknown role %s", redact(key), role)
+		}
+	}
+	for name, role := range c.Roles {
+		for _, limit := range []Limit{role.Read, role.Write} {
+			if limit.Rate > 0 && limit.Burst < 1 {
+				return fmt.Errorf("role %s must have a burst of at least 1", name)
+			}
+		}
+	}
+	return nil
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+type Limiter struct {
+	Config *Config
+	// Paths whose requests only read whatever the method, IE: a GraphQL endpoint
+	ReadPaths []string
+	// gRPC methods that only read, IE: /metadata.v1.MetadataService/GetMetadata, other methods write
+	ReadMethods []string
+	// Allows tests to control time
+	Now func() time.Time
+
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewLimiter(config *Config) *Limiter {
+	return &Limiter{
+		Config:  config,
+		Now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Result of taking a token from a bucket
+type decision struct {
+	allowed   bool
+	remaining int
+	// Time until a token is available
+	retryAfter time.Duration
+	// Time until the bucket is full again
+	reset time.Duration
+}
+
+// Wraps next and rejects requests exceeding the caller's budget with 429
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		identity, role := l.identify(req)
+		limit, kind := l.limitFor(role, req)
+
+		if limit.Rate <= 0 {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		d := l.take(fmt.Sprintf("%s|%s", kind, identity), limit)
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.reset)))
+
+		if !d.allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(d.retryAfter)))
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(fmt.Sprintf("Rate limit exceeded for %s requests, retry in %d seconds\n",
+				kind, ceilSeconds(d.retryAfter))))
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// Returns the bucket identity and role of the caller
+// Known API keys are limited per key, everything else is limited per client IP.
+// Unknown keys are not used as identity so rotating keys can not bypass the limit.
+func (l *Limiter) identify(req *http.Request) (string, string) {
+	return l.identifyCaller(req.Header.Get(ApiKeyHeader), ClientIP(req))
+}
+
+// Same as identify for an API key and client IP however they were received
+func (l *Limiter) identifyCaller(key string, ip string) (string, string) {
+	if key != "" {
+		if role, ok := l.Config.ApiKeys[key]; ok {
+			return "key:" + key, role
+		}
+	}
+	return "ip:" + ip, AnonymousRole
+}
+
+func (l *Limiter) limitFor(role string, req *http.Request) (Limit, string) {
+	read := false
+	for _, path := range l.ReadPaths {
+		if req.URL.Path == path {
+			read = true
+		}
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		read = true
+	}
+	return l.limitOf(role, read)
+}
+
+func (l *Limiter) limitOf(role string, read bool) (Limit, string) {
+	roleLimits, ok := l.Config.Roles[role]
+	if !ok {
+		roleLimits = l.Config.Roles[AnonymousRole]
+	}
+	if read {
+		return roleLimits.Read, "read"
+	}
+	return roleLimits.Write, "write"
+}
+
+func (l *Limiter) take(key string, limit Limit) decision {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now, limit: limit}
+		l.buckets[key] = b
+	}
+
+	// Refill based on elapsed time
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+		b.last = now
+	}
+
+	d := decision{}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.allowed = true
+	} else {
+		d.retryAfter = secondsToDuration((1 - b.tokens) / limit.Rate)
+	}
+	d.remaining = int(math.Floor(b.tokens))
+	d.reset = secondsToDuration((float64(limit.Burst) - b.tokens) / limit.Rate)
+	return d
+}
+
+// Removes buckets that have been idle long enough to be full again
+// A full bucket behaves the same as a missing one, so nothing is lost.
+// Must be called with the mutex held
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Returns the IP of the client making the request
+func ClientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// Only show the first few characters of a secret
+func redact(s string) string {
+	if len(s) <= 4 {
+		return "****"
+	}
+	return s[:4] + "****"
+}
@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var now time.Time
+
+func setupTest() *Limiter {
+	now = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(&Config{
+		Roles: map[string]RoleLimits{
+			AnonymousRole: {
+				Read:  Limit{Rate: 1, Burst: 2},
+				Write: Limit{Rate: 0.5, Burst: 1},
+			},
+			"admin": {
+				Read:  Limit{Rate: 0},
+				Write: Limit{Rate: 10, Burst: 10},
+			},
+		},
+		ApiKeys: map[string]string{"adminkey": "admin"},
+	})
+	limiter.Now = func() time.Time { return now }
+	return limiter
+}
+
+func serve(limiter *Limiter, method string, remoteAddr string, apiKey string) *httptest.ResponseRecorder {
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := httptest.NewRequest(method, "/metadata", nil)
+	request.RemoteAddr = remoteAddr
+	if apiKey != "" {
+		request.Header.Set(ApiKeyHeader, apiKey)
+	}
+	responseRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, request)
+	return responseRecorder
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	limiter := setupTest()
+
+	r := serve(limiter, http.MethodGet, "10.0.0.1:1234", "")
+	assert.Equal(t, http.StatusOK, r.Code)
+	assert.Equal(t, "2", r.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", r.Header().Get("RateLimit-Remaining"))
+
+	r = serve(limiter, http.MethodGet, "10.0.0.1:1234", "")
+	assert.Equal(t, http.StatusOK, r.Code)
+	assert.Equal(t, "0", r.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2", r.Header().Get("RateLimit-Reset"))
+
+	r = serve(limiter, http.MethodGet, "10.0.0.1:1234", "")
+	assert.Equal(t, http.StatusTooManyRequests, r.Code)
+	assert.Equal(t, "1", r.Header().Get("Retry-After"))
+
+	// Token is refilled after a second
+	now = now.Add(time.Second)
+	r = serve(limiter, http.MethodGet, "10.0.0.1:1234", "")
+	assert.Equal(t, http.StatusOK, r.Code)
+}
+
+func TestLimiter_Middleware_SeparateReadAndWrite(t *testing.T) {
+	limiter := setupTest()
+
+	r := serve(limiter, http.MethodPut, "10.0.0.1:1234", "")
+	assert.Equal(t, http.StatusOK, r.Code)
+	r = serve(limiter, http.MethodPut, "10.0.0.1:1234", "")
+	assert.Equal(t, http.StatusTooManyRequests, r.Code)
+	assert.Equal(t, "2", r.Header().Get("Retry-After"))
+
+	// Reads still have their own budget
+	r = serve(limiter, http.MethodGet, "10.0.0.1:1234", "")
+	assert.Equal(t, http.StatusOK, r.Code)
+}
+
+func TestLimiter_Middleware_WithReadPath(t *testing.T) {
+	limiter := setupTest()
+	limiter.ReadPaths = []string{"/graphql"}
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Both requests use the read budget of 2 even though the write budget is 1
+	for i := 0; i < 2; i++ {
+		responseRecorder := httptest.NewRecorder()
+		handler.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodPost, "/graphql", nil))
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	}
+	r := serve(limiter, http.MethodGet, "192.0.2.1:1234", "")
+	assert.Equal(t, http.StatusTooManyRequests, r.Code)
+}
+
+func TestLimiter_Middleware_SeparateClients(t *testing.T) {
+	limiter := setupTest()
+
+	r := serve(limiter, http.MethodPut, "10.0.0.1:1234", "")
+	assert.Equal(t, http.StatusOK, r.Code)
+	r = serve(limiter, http.MethodPut, "10.0.0.2:1234", "")
+	assert.Equal(t, http.StatusOK, r.Code)
+}
+
+func TestLimiter_Middleware_WithApiKey(t *testing.T) {
+	limiter := setupTest()
+
+	// Admin reads are unlimited
+	for i := 0; i < 5; i++ {
+		r := serve(limiter, http.MethodGet, "10.0.0.1:1234", "adminkey")
+		assert.Equal(t, http.StatusOK, r.Code)
+		assert.Empty(t, r.Header().Get("RateLimit-Limit"))
+	}
+
+	r := serve(limiter, http.MethodPut, "10.0.0.1:1234", "adminkey")
+	assert.Equal(t, http.StatusOK, r.Code)
+	assert.Equal(t, "10", r.Header().Get("RateLimit-Limit"))
+}
+
+func TestLimiter_Middleware_WithUnknownApiKey(t *testing.T) {
+	limiter := setupTest()
+
+	// Unknown keys fall back to the client IP so rotating keys does not help
+	r := serve(limiter, http.MethodPut, "10.0.0.1:1234", "key1")
+	assert.Equal(t, http.StatusOK, r.Code)
+	r = serve(limiter, http.MethodPut, "10.0.0.1:1234", "key2")
+	assert.Equal(t, http.StatusTooManyRequests, r.Code)
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	limiter := setupTest()
+
+	serve(limiter, http.MethodGet, "10.0.0.1:1234", "")
+	assert.Len(t, limiter.buckets, 1)
+
+	now = now.Add(sweepInterval)
+	serve(limiter, http.MethodGet, "10.0.0.2:1234", "")
+	assert.Len(t, limiter.buckets, 1)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	config := DefaultConfig()
+	assert.Nil(t, config.Validate())
+
+	config.ApiKeys["secretkey"] = "missing"
+	err := config.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, "api key secr**** references unknown role missing", err.Error())
+
+	config = &Config{Roles: map[string]RoleLimits{}}
+	assert.Error(t, config.Validate())
+}
@@ -6,6 +6,7 @@ fi
 
 go generate ./...
 go build
+go build -o metadatactl ./cmd/metadatactl
 go test ./...
 
 docker build -f docker/Dockerfile -t apiserverexercise.azurecr.io/server:latest .
@@ -0,0 +1,79 @@
+package search
+
+import (
+	"APIServerExercise/tracing"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// Field names of a document, in the same form as the index keys
+type FieldSet struct {
+	// IE: title or maintainers.email
+	Names []string
+	// Objects with arbitrary keys, each key is its own field, IE: labels for labels.tier
+	Maps []string
+}
+
+// Whether the field can be filtered on
+func (f FieldSet) Has(field string) bool {
+	if contains(f.Names, field) {
+		return true
+	}
+	for _, name := range f.Maps {
+		if strings.HasPrefix(field, name+".") && len(field) > len(name)+1 {
+			return true
+		}
+	}
+	return false
+}
+
+// Adds a document decoded from YAML or JSON to the index, IE: a resource of a registered kind
+// Properties of nested objects are prefixed with the name of the object, IE: owner.email,
+// every element of an array is indexed under the name of the array. Null values are not indexed.
+func (s *Searcher) AddDocumentToIndex(document map[string]interface{}, id uuid.UUID, prefix string) {
+	for key, value := range document {
+		fieldName := key
+		if prefix != "" {
+			fieldName = fmt.Sprintf("%s.%s", prefix, key)
+		}
+		s.addDocumentValue(fieldName, value, id)
+	}
+}
+
+func (s *Searcher) addDocumentValue(fieldName string, value interface{}, id uuid.UUID) {
+	switch value := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		s.AddDocumentToIndex(value, id, fieldName)
+	case []interface{}:
+		for _, element := range value {
+			s.addDocumentValue(fieldName, element, id)
+		}
+	default:
+		s.addValue(fieldName, fmt.Sprintf("%v", value), id)
+	}
+}
+
+// Filters the ids of documents indexed with AddDocumentToIndex
+// The query is validated against the fields, the same as ParseQuery for the metadata.
+// Returns the ids matching every condition, in the order of ids
+func (s *Searcher) FilterDocuments(
+	ctx context.Context,
+	query map[string][]string,
+	fields FieldSet,
+	ids []uuid.UUID) ([]uuid.UUID, error) {
+	ctx, span := tracing.Start(ctx, "search.FilterDocuments")
+	defer span.End()
+
+	conditions, err := ParseDocumentQuery(query, fields)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+
+	results := s.filterIds(ctx, conditions, ids)
+	span.SetAttribute("search.results", len(results))
+	return results, nil
+}
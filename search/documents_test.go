@@ -0,0 +1,67 @@
+package search
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSearcher_AddDocumentToIndex(t *testing.T) {
+	searcher := Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}}
+
+	id := uuid.New()
+	searcher.AddDocumentToIndex(map[string]interface{}{
+		"name":      "Data Lake",
+		"rows":      12,
+		"owner":     map[string]interface{}{"email": "a@hotmail.com"},
+		"columns":   []interface{}{map[string]interface{}{"name": "id"}, map[string]interface{}{"name": "date"}},
+		"retention": nil,
+	}, id, "")
+
+	assert.True(t, searcher.Index["name"]["Data Lake"][id])
+	assert.True(t, searcher.Index["name"]["Lake"][id])
+	assert.True(t, searcher.Index["rows"]["12"][id])
+	assert.True(t, searcher.Index["owner.email"]["a@hotmail.com"][id])
+	assert.True(t, searcher.Index["columns.name"]["id"][id])
+	assert.True(t, searcher.Index["columns.name"]["date"][id])
+	assert.NotContains(t, searcher.Index, "retention")
+}
+
+func TestFieldSet_Has(t *testing.T) {
+	fields := FieldSet{Names: []string{"name", "owner.email"}, Maps: []string{"labels"}}
+
+	assert.True(t, fields.Has("owner.email"))
+	assert.True(t, fields.Has("labels.tier"))
+	assert.False(t, fields.Has("owner"))
+	assert.False(t, fields.Has("labels"))
+	assert.False(t, fields.Has("labels."))
+}
+
+func TestSearcher_FilterDocuments(t *testing.T) {
+	searcher := Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}}
+	fields := FieldSet{Names: []string{"name", "owner.email"}}
+
+	id1, id2 := uuid.New(), uuid.New()
+	searcher.AddDocumentToIndex(map[string]interface{}{"name": "a", "owner": map[string]interface{}{"email": "a@hotmail.com"}}, id1, "")
+	searcher.AddDocumentToIndex(map[string]interface{}{"name": "b", "owner": map[string]interface{}{"email": "a@hotmail.com"}}, id2, "")
+
+	ids, err := searcher.FilterDocuments(context.Background(), map[string][]string{"owner.email": {"a@hotmail.com"}},
+		fields, []uuid.UUID{id2, id1})
+	assert.Nil(t, err)
+	assert.Equal(t, []uuid.UUID{id2, id1}, ids)
+
+	ids, err = searcher.FilterDocuments(context.Background(), map[string][]string{"name[ne]": {"a"}},
+		fields, []uuid.UUID{id1, id2})
+	assert.Nil(t, err)
+	assert.Equal(t, []uuid.UUID{id2}, ids)
+
+	_, err = searcher.FilterDocuments(context.Background(), map[string][]string{"email": {"a@hotmail.com"}},
+		fields, []uuid.UUID{id1, id2})
+	assert.Equal(t, `unknown field "email", did you mean "owner.email"?`, err.Error())
+
+	// Documents without a labels object can not be selected by labels
+	_, err = searcher.FilterDocuments(context.Background(), map[string][]string{LabelSelectorParameter: {"tier"}},
+		fields, []uuid.UUID{id1, id2})
+	assert.Equal(t, `unknown field "labels.tier", the label selector requires a labels field`, err.Error())
+}
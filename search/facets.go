@@ -0,0 +1,146 @@
+package search
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/util"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Every field name the metadata can be sorted on, nested fields have several values and can not be sorted on
+var sortFields = topLevelFields(metadataFields)
+
+// Number of metadata having a value of a field
+type FacetValue struct {
+	Value string
+	Count int
+}
+
+// A field to sort on, IE: title descending
+type SortKey struct {
+	Field      string
+	Descending bool
+}
+
+// Returns the fields that have a single value, the fields that can be sorted on, IE: title but not maintainers.email
+func SingleValuedFields() []string {
+	return append([]string{}, sortFields...)
+}
+
+func topLevelFields(fields []string) []string {
+	var names []string
+	for _, field := range fields {
+		if !strings.Contains(field, ".") {
+			names = append(names, field)
+		}
+	}
+	return names
+}
+
+// Returns the whole values of the field, as they are indexed
+// Nested fields such as maintainers.email have a value for every element of the slice.
+// Labels have the value of their key, IE: labels.tier.
+func FieldValues(metadata *core.Metadata, field string) []string {
+	return fieldValues(reflect.ValueOf(metadata), strings.Split(field, "."))
+}
+
+func fieldValues(v reflect.Value, path []string) []string {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		var values []string
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, fieldValues(v.Index(i), path)...)
+		}
+		return values
+	}
+	if len(path) == 0 {
+		return []string{fmt.Sprintf("%v", v.Interface())}
+	}
+	if u, ok := v.Interface().(util.Yamlurl); ok {
+		// Components of the URL, IE: source.host
+		if len(path) != 1 {
+			return nil
+		}
+		return core.URLComponents(u.URL)[path[0]]
+	}
+	if v.Kind() == reflect.Map {
+		// Keys can contain dots, IE: labels.app.kubernetes.io/name
+		value := v.MapIndex(reflect.ValueOf(strings.Join(path, ".")))
+		if !value.IsValid() {
+			return nil
+		}
+		return []string{fmt.Sprintf("%v", value.Interface())}
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	// Field names are lower case, the same as AddToIndex
+	field := v.FieldByNameFunc(func(name string) bool { return strings.ToLower(name) == path[0] })
+	if !field.IsValid() {
+		return nil
+	}
+	return fieldValues(field, path[1:])
+}
+
+// Counts the metadata having each value of the field
+// Sorted by descending count then value, a metadata is counted once per distinct value.
+func Facets(results []*core.Metadata, field string) ([]FacetValue, error) {
+	if !isField(field) {
+		return nil, fmt.Errorf("unknown field %q%s", field, suggest(field, metadataFields))
+	}
+
+	counts := map[string]int{}
+	for _, metadata := range results {
+		seen := map[string]bool{}
+		for _, value := range FieldValues(metadata, field) {
+			if !seen[value] {
+				seen[value] = true
+				counts[value]++
+			}
+		}
+	}
+
+	facets := make([]FacetValue, 0, len(counts))
+	for value, count := range counts {
+		facets = append(facets, FacetValue{Value: value, Count: count})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	return facets, nil
+}
+
+// Sorts the results in place by the keys, in order
+// The sort is stable so results equal on every key keep the default ordering.
+func SortMetadata(results []*core.Metadata, keys []SortKey) error {
+	for _, key := range keys {
+		if !contains(sortFields, key.Field) {
+			return fmt.Errorf("can not sort on field %q%s", key.Field, suggest(key.Field, sortFields))
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		for _, key := range keys {
+			a, b := FieldValues(results[i], key.Field)[0], FieldValues(results[j], key.Field)[0]
+			if a == b {
+				continue
+			}
+			if key.Descending {
+				return a > b
+			}
+			return a < b
+		}
+		return false
+	})
+	return nil
+}
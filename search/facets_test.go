@@ -0,0 +1,72 @@
+package search
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/util"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func newFacetMetadata(title string, license string, emails ...string) *core.Metadata {
+	metadata := &core.Metadata{Title: title, License: license}
+	for _, email := range emails {
+		metadata.Maintainers = append(metadata.Maintainers, &core.Maintainer{Email: email})
+	}
+	return metadata
+}
+
+func TestFieldValues(t *testing.T) {
+	website, _ := url.Parse("https://website.com")
+	metadata := newFacetMetadata("App", "MIT", "a@hotmail.com", "b@hotmail.com")
+	metadata.Website = util.Yamlurl{URL: website}
+
+	assert.Equal(t, []string{"App"}, FieldValues(metadata, "title"))
+	assert.Equal(t, []string{"https://website.com"}, FieldValues(metadata, "website"))
+	assert.Equal(t, []string{"website.com"}, FieldValues(metadata, "website.host"))
+	assert.Empty(t, FieldValues(metadata, "website.owner"))
+	assert.Equal(t, []string{"a@hotmail.com", "b@hotmail.com"}, FieldValues(metadata, "maintainers.email"))
+	assert.Empty(t, FieldValues(metadata, "unknown"))
+
+	metadata.Labels = map[string]string{"app.kubernetes.io/name": "app"}
+	assert.Equal(t, []string{"app"}, FieldValues(metadata, "labels.app.kubernetes.io/name"))
+	assert.Empty(t, FieldValues(metadata, "labels.tier"))
+}
+
+func TestFacets(t *testing.T) {
+	results := []*core.Metadata{
+		newFacetMetadata("App 1", "MIT", "a@hotmail.com", "a@hotmail.com"),
+		newFacetMetadata("App 2", "Apache-2.0", "a@hotmail.com"),
+		newFacetMetadata("App 3", "MIT", "b@hotmail.com"),
+	}
+
+	facets, err := Facets(results, "license")
+	assert.Nil(t, err)
+	assert.Equal(t, []FacetValue{{Value: "MIT", Count: 2}, {Value: "Apache-2.0", Count: 1}}, facets)
+
+	// A metadata is counted once even when several maintainers have the same email
+	facets, err = Facets(results, "maintainers.email")
+	assert.Nil(t, err)
+	assert.Equal(t, []FacetValue{{Value: "a@hotmail.com", Count: 2}, {Value: "b@hotmail.com", Count: 1}}, facets)
+
+	_, err = Facets(results, "licence")
+	assert.Error(t, err)
+	assert.Equal(t, `unknown field "licence", did you mean "license"?`, err.Error())
+}
+
+func TestSortMetadata(t *testing.T) {
+	results := []*core.Metadata{
+		newFacetMetadata("B", "MIT"),
+		newFacetMetadata("A", "MIT"),
+		newFacetMetadata("C", "Apache-2.0"),
+	}
+
+	err := SortMetadata(results, []SortKey{{Field: "license", Descending: true}, {Field: "title"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "A", results[0].Title)
+	assert.Equal(t, "B", results[1].Title)
+	assert.Equal(t, "C", results[2].Title)
+
+	err = SortMetadata(results, []SortKey{{Field: "maintainers.email"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package search
+
+import (
+	"APIServerExercise/core"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// key in (a,b) or key notin (a,b)
+var setRequirementPattern = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+// Parses a Kubernetes style label selector into conditions on the labels
+// Requirements are separated by commas: key=value, key==value, key!=value, key in (a,b), key notin (a,b), key, !key.
+// As in Kubernetes, != and notin also match the metadata without the label.
+func ParseLabelSelector(selector string) ([]Condition, error) {
+	var conditions []Condition
+	for _, requirement := range splitRequirements(selector) {
+		requirement = strings.TrimSpace(requirement)
+		if requirement == "" {
+			return nil, fmt.Errorf("invalid label selector %q: empty requirement", selector)
+		}
+		condition, err := parseRequirement(requirement)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector %q: %v", selector, err)
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+// Splits on the commas that are not between parentheses
+func splitRequirements(selector string) []string {
+	if strings.TrimSpace(selector) == "" {
+		return nil
+	}
+	var requirements []string
+	depth, start := 0, 0
+	for i, r := range selector {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				requirements = append(requirements, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(requirements, selector[start:])
+}
+
+func parseRequirement(requirement string) (Condition, error) {
+	if match := setRequirementPattern.FindStringSubmatch(requirement); match != nil {
+		condition := Condition{Field: match[1], Operator: match[2]}
+		for _, value := range strings.Split(match[3], ",") {
+			value = strings.TrimSpace(value)
+			if !core.IsLabelValue(value) {
+				return Condition{}, fmt.Errorf("invalid label value %q", value)
+			}
+			condition.Values = append(condition.Values, value)
+		}
+		return labelCondition(condition)
+	}
+
+	for _, operator := range []struct{ token, operator string }{
+		{"!=", OperatorNotEqual},
+		{"==", OperatorEqual},
+		{"=", OperatorEqual},
+	} {
+		if i := strings.Index(requirement, operator.token); i >= 0 {
+			value := strings.TrimSpace(requirement[i+len(operator.token):])
+			if !core.IsLabelValue(value) {
+				return Condition{}, fmt.Errorf("invalid label value %q", value)
+			}
+			return labelCondition(Condition{
+				Field:    strings.TrimSpace(requirement[:i]),
+				Operator: operator.operator,
+				Value:    value,
+			})
+		}
+	}
+
+	if strings.HasPrefix(requirement, "!") {
+		return labelCondition(Condition{Field: strings.TrimSpace(requirement[1:]), Operator: OperatorNotExists})
+	}
+	return labelCondition(Condition{Field: requirement, Operator: OperatorExists})
+}
+
+// Validates the label key and turns it into the field of the index
+func labelCondition(condition Condition) (Condition, error) {
+	if !core.IsLabelKey(condition.Field) {
+		return Condition{}, fmt.Errorf("invalid label key %q", condition.Field)
+	}
+	condition.Field = labelsPrefix + condition.Field
+	return condition, nil
+}
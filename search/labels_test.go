@@ -0,0 +1,95 @@
+package search
+
+import (
+	"APIServerExercise/core"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	conditions, err := ParseLabelSelector("tier=critical, env!=prod,team==payments,region in (eu, us),zone notin (a),owner,!deprecated")
+	assert.Nil(t, err)
+	assert.Equal(t, []Condition{
+		{Field: "labels.tier", Operator: OperatorEqual, Value: "critical"},
+		{Field: "labels.env", Operator: OperatorNotEqual, Value: "prod"},
+		{Field: "labels.team", Operator: OperatorEqual, Value: "payments"},
+		{Field: "labels.region", Operator: OperatorIn, Values: []string{"eu", "us"}},
+		{Field: "labels.zone", Operator: OperatorNotIn, Values: []string{"a"}},
+		{Field: "labels.owner", Operator: OperatorExists},
+		{Field: "labels.deprecated", Operator: OperatorNotExists},
+	}, conditions)
+
+	conditions, err = ParseLabelSelector("")
+	assert.Nil(t, err)
+	assert.Empty(t, conditions)
+}
+
+func TestParseLabelSelector_Invalid(t *testing.T) {
+	for selector, message := range map[string]string{
+		"tier=critical,":      `invalid label selector "tier=critical,": empty requirement`,
+		"tier=critical value": `invalid label selector "tier=critical value": invalid label value "critical value"`,
+		"-tier":               `invalid label selector "-tier": invalid label key "-tier"`,
+		"tier in (a b)":       `invalid label selector "tier in (a b)": invalid label value "a b"`,
+	} {
+		_, err := ParseLabelSelector(selector)
+		assert.Error(t, err)
+		assert.Equal(t, message, err.Error())
+	}
+}
+
+func TestParseQuery_WithLabels(t *testing.T) {
+	conditions, err := ParseQuery(map[string][]string{
+		"labels.example.com/tier": {"critical"},
+		LabelSelectorParameter:    {"env"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []Condition{
+		{Field: "labels.env", Operator: OperatorExists},
+		{Field: "labels.example.com/tier", Operator: OperatorEqual, Value: "critical"},
+	}, conditions)
+
+	_, err = ParseQuery(map[string][]string{"labels.-tier": {"critical"}})
+	assert.Error(t, err)
+}
+
+func TestSearcher_FilterMetadata_WithLabelSelector(t *testing.T) {
+	searcher := &Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}}
+	database := &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}}
+	for _, labels := range []map[string]string{
+		{"tier": "critical", "env": "prod"},
+		{"tier": "low", "env": "staging"},
+		{"env": "prod"},
+		nil,
+	} {
+		metadata := &core.Metadata{Id: uuid.New(), Labels: labels, Annotations: map[string]string{"notes": "not indexed"}}
+		database.Metadatas[metadata.Id] = metadata
+		database.Ordering = append(database.Ordering, metadata.Id)
+		searcher.AddToIndex(metadata, metadata.Id, "")
+	}
+	assert.NotContains(t, searcher.Index, "annotations.notes")
+
+	for selector, expected := range map[string][]int{
+		"tier=critical":          {0},
+		"tier!=critical":         {1, 2, 3},
+		"tier in (critical,low)": {0, 1},
+		"tier notin (critical)":  {1, 2, 3},
+		"tier":                   {0, 1},
+		"!tier":                  {2, 3},
+		"env=prod,!tier":         {2},
+		"env in (prod),tier=low": {},
+	} {
+		results, err := searcher.FilterMetadata(context.Background(), map[string][]string{LabelSelectorParameter: {selector}}, database)
+		assert.Nil(t, err)
+		ids := []uuid.UUID{}
+		for _, index := range expected {
+			ids = append(ids, database.Ordering[index])
+		}
+		resultIds := []uuid.UUID{}
+		for _, metadata := range results {
+			resultIds = append(resultIds, metadata.Id)
+		}
+		assert.Equal(t, ids, resultIds, selector)
+	}
+}
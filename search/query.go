@@ -0,0 +1,310 @@
+package search
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/lifecycle"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// Value is the whole field value or one of its words
+	OperatorEqual = "eq"
+	// Inverse of eq
+	OperatorNotEqual = "ne"
+	// Value is one of Values, only from label selectors
+	OperatorIn = "in"
+	// Inverse of in
+	OperatorNotIn = "notin"
+	// The field has a value, only from label selectors
+	OperatorExists = "exists"
+	// Inverse of exists
+	OperatorNotExists = "notexists"
+
+	// Query parameter holding a label selector, IE: labelSelector=tier=critical,env in (prod,staging)
+	LabelSelectorParameter = "labelSelector"
+	// Prefix of the index keys of the labels, IE: labels.tier
+	labelsPrefix = "labels."
+	// Field holding the lifecycle state, draft and retired metadata are hidden unless a condition is on it
+	lifecycleField = "lifecycle"
+	// Tag of the fields holding an SPDX license expression
+	spdxIndexTag = "spdx"
+	// Tag of the fields holding a URL, indexed by component as well
+	urlIndexTag = "url"
+)
+
+// Operators that can follow a field in a query key
+var operators = []string{OperatorEqual, OperatorNotEqual}
+
+// Every field name that can be filtered on, in the same form as the index keys
+var metadataFields = FieldNames(reflect.TypeOf(core.Metadata{}), "")
+
+// Fields holding an SPDX license expression, their values are matched in canonical form
+var licenseFields = taggedFields(reflect.TypeOf(core.Metadata{}), spdxIndexTag)
+
+// Fields holding a URL, their values are matched in canonical form
+var urlFields = taggedFields(reflect.TypeOf(core.Metadata{}), urlIndexTag)
+
+// A single filter of a query, IE: license[ne]=MIT
+type Condition struct {
+	Field    string
+	Operator string
+	Value    string
+	// Values of the in and notin operators
+	Values []string
+}
+
+// Whether the condition keeps the metadata that do not match the value, IE: ne
+func (c Condition) Negated() bool {
+	return c.Operator == OperatorNotEqual || c.Operator == OperatorNotIn || c.Operator == OperatorNotExists
+}
+
+// Returns the names AddToIndex uses for the fields of t
+// Fields of slice elements are prefixed with the name of the slice, IE: maintainers.email
+// Maps have a field per key and are left out, like the fields that are not indexed.
+// URL fields also have a field per component, IE: source.host, and optional structs a field per field, IE: links.broken
+func FieldNames(t reflect.Type, prefix string) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type.Kind() == reflect.Map || field.Tag.Get("index") == "-" {
+			continue
+		}
+		name := field.Name
+		if prefix != "" {
+			name = fmt.Sprintf("%s.%s", prefix, name)
+		}
+		name = strings.ToLower(name)
+
+		elem := field.Type
+		if elem.Kind() == reflect.Slice {
+			elem = elem.Elem()
+			for elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+			if elem.Kind() == reflect.Struct {
+				names = append(names, FieldNames(elem, name)...)
+			}
+			// slices themselves are not indexed
+			continue
+		}
+		if elem.Kind() == reflect.Ptr && elem.Elem().Kind() == reflect.Struct {
+			// Optional structs have the fields of the struct, IE: links.broken
+			names = append(names, FieldNames(elem, name)...)
+			continue
+		}
+		names = append(names, name)
+		if field.Tag.Get("index") == urlIndexTag {
+			for _, component := range core.URLComponentNames {
+				names = append(names, name+"."+component)
+			}
+		}
+	}
+	return names
+}
+
+// Returns the names of the top level fields of t with the index tag
+func taggedFields(t reflect.Type, tag string) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("index") == tag {
+			names = append(names, strings.ToLower(t.Field(i).Name))
+		}
+	}
+	return names
+}
+
+// Whether the field can be filtered on, a metadata field or a label, IE: labels.tier
+func isField(field string) bool {
+	if strings.HasPrefix(field, labelsPrefix) {
+		return core.IsLabelKey(strings.TrimPrefix(field, labelsPrefix))
+	}
+	return contains(metadataFields, field)
+}
+
+// Validates the query parameters against the metadata fields
+// Keys are a field name optionally followed by an operator, IE: title or title[ne], or the label selector parameter.
+// Returns the conditions sorted by field so filtering is deterministic.
+// License values are normalized, IE: license=apache-2.0 matches Apache-2.0 and MIT OR Apache-2.0.
+// URL values are normalized as well, IE: source=https://GitHub.com/random/repo/ matches https://github.com/random/repo
+// and source.host=GitHub.com matches github.com.
+func ParseQuery(query map[string][]string) ([]Condition, error) {
+	conditions, err := parseQuery(query, isField, metadataFields)
+	if err != nil {
+		return nil, err
+	}
+	for i, condition := range conditions {
+		if contains(licenseFields, condition.Field) {
+			conditions[i].Value = core.NormalizeLicense(condition.Value)
+		}
+		if contains(urlFields, condition.Field) {
+			if u, err := url.Parse(condition.Value); err == nil && u.IsAbs() {
+				conditions[i].Value = core.NormalizeURL(u).String()
+			}
+		}
+		for _, field := range urlFields {
+			if condition.Field == field+"."+core.URLHost {
+				conditions[i].Value = strings.ToLower(condition.Value)
+			}
+		}
+	}
+	return conditions, nil
+}
+
+// Hides the draft and retired metadata unless a condition is on the lifecycle
+// lifecycle=any matches every state, IE: to find a metadata by title whatever its state.
+func withDefaultLifecycle(conditions []Condition) []Condition {
+	result := make([]Condition, 0, len(conditions)+len(lifecycle.Hidden))
+	onLifecycle := false
+	for _, condition := range conditions {
+		if condition.Field != lifecycleField {
+			result = append(result, condition)
+			continue
+		}
+		onLifecycle = true
+		if condition.Operator != OperatorEqual || condition.Value != lifecycle.Any {
+			result = append(result, condition)
+		}
+	}
+	if !onLifecycle {
+		for _, state := range lifecycle.Hidden {
+			result = append(result, Condition{Field: lifecycleField, Operator: OperatorNotEqual, Value: state})
+		}
+	}
+	return result
+}
+
+// Same as ParseQuery for the fields of a document, IE: a resource of a registered kind
+func ParseDocumentQuery(query map[string][]string, fields FieldSet) ([]Condition, error) {
+	return parseQuery(query, fields.Has, fields.Names)
+}
+
+// Fields can be filtered on when isField is true, candidates are suggested for the others
+func parseQuery(query map[string][]string, isField func(string) bool, candidates []string) ([]Condition, error) {
+	conditions := make([]Condition, 0, len(query))
+	for key, values := range query {
+		if key == LabelSelectorParameter {
+			for _, selector := range values {
+				selected, err := ParseLabelSelector(selector)
+				if err != nil {
+					return nil, err
+				}
+				for _, condition := range selected {
+					if !isField(condition.Field) {
+						return nil, fmt.Errorf("unknown field %q, the label selector requires a labels field", condition.Field)
+					}
+				}
+				conditions = append(conditions, selected...)
+			}
+			continue
+		}
+
+		field, operator := key, OperatorEqual
+		if i := strings.Index(key, "["); i >= 0 && strings.HasSuffix(key, "]") {
+			field, operator = key[:i], key[i+1:len(key)-1]
+		}
+
+		if !isField(field) {
+			return nil, fmt.Errorf("unknown field %q%s", field, suggest(field, candidates))
+		}
+		if !contains(operators, operator) {
+			return nil, fmt.Errorf("unknown operator %q for field %q%s", operator, field, suggest(operator, operators))
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("field %q requires a value", field)
+		}
+
+		// Only care about the first query value
+		conditions = append(conditions, Condition{Field: field, Operator: operator, Value: values[0]})
+	}
+
+	sort.Slice(conditions, func(i, j int) bool {
+		if conditions[i].Field != conditions[j].Field {
+			return conditions[i].Field < conditions[j].Field
+		}
+		return conditions[i].Operator < conditions[j].Operator
+	})
+	return conditions, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a "did you mean" hint for the closest candidate, or the list of candidates when none is close
+func suggest(value string, candidates []string) string {
+	// Slices are not fields themselves, suggest their nested fields
+	var nested []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, strings.ToLower(value)+".") {
+			nested = append(nested, fmt.Sprintf("%q", candidate))
+		}
+	}
+	if len(nested) > 0 {
+		return fmt.Sprintf(", did you mean %s?", strings.Join(nested, " or "))
+	}
+
+	best, bestDistance := "", -1
+	for _, candidate := range candidates {
+		distance := Levenshtein(strings.ToLower(value), candidate)
+		// Also compare with the last part of nested fields, IE: email for maintainers.email
+		if i := strings.LastIndex(candidate, "."); i >= 0 {
+			if d := Levenshtein(strings.ToLower(value), candidate[i+1:]); d < distance {
+				distance = d
+			}
+		}
+		if bestDistance == -1 || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	// Allow about one typo for every three characters
+	if bestDistance >= 0 && bestDistance <= len(value)/3+1 {
+		return fmt.Sprintf(", did you mean %q?", best)
+	}
+	return fmt.Sprintf(", expected one of: %s", strings.Join(candidates, ", "))
+}
+
+// Number of single character insertions, deletions and substitutions to turn a into b
+func Levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minOf(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// Smallest of the values, values must not be empty
+func minOf(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,83 @@
+package search
+
+import (
+	"APIServerExercise/core"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+func TestFieldNames(t *testing.T) {
+	fields := FieldNames(reflect.TypeOf(core.Metadata{}), "")
+	assert.Equal(t, []string{
+		"id", "title", "version", "application", "maintainers.name", "maintainers.email", "company",
+		"website", "website.host", "website.path", "website.owner", "website.repo",
+		"source", "source.host", "source.path", "source.owner", "source.repo", "license", "description",
+		"dependencies.id", "dependencies.title", "lifecycle", "deprecation.reason", "deprecation.replacement",
+		"links.broken", "links.website.status", "links.website.broken", "links.source.status", "links.source.broken",
+	}, fields)
+}
+
+// region ParseQuery
+
+func TestParseQuery(t *testing.T) {
+	conditions, err := ParseQuery(map[string][]string{
+		"title":             {"App", "ignored"},
+		"maintainers.email": {"a@b.com"},
+		"license[ne]":       {"MIT"},
+		"license[eq]":       {"Apache-2.0"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []Condition{
+		{Field: "license", Operator: OperatorEqual, Value: "Apache-2.0"},
+		{Field: "license", Operator: OperatorNotEqual, Value: "MIT"},
+		{Field: "maintainers.email", Operator: OperatorEqual, Value: "a@b.com"},
+		{Field: "title", Operator: OperatorEqual, Value: "App"},
+	}, conditions)
+}
+
+func TestParseQuery_NormalizesURLs(t *testing.T) {
+	conditions, err := ParseQuery(map[string][]string{
+		"source":      {"HTTPS://GitHub.com/random/repo/"},
+		"source.host": {"GitHub.com"},
+		"website":     {"not a url/"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []Condition{
+		{Field: "source", Operator: OperatorEqual, Value: "https://github.com/random/repo"},
+		{Field: "source.host", Operator: OperatorEqual, Value: "github.com"},
+		{Field: "website", Operator: OperatorEqual, Value: "not a url/"},
+	}, conditions)
+}
+
+func TestParseQuery_UnknownField(t *testing.T) {
+	for key, message := range map[string]string{
+		"titel":         `unknown field "titel", did you mean "title"?`,
+		"email":         `unknown field "email", did you mean "maintainers.email"?`,
+		"maintainers":   `unknown field "maintainers", did you mean "maintainers.name" or "maintainers.email"?`,
+		"Title":         `unknown field "Title", did you mean "title"?`,
+		"somethingelse": `unknown field "somethingelse", expected one of: id, title, version, application, maintainers.name, maintainers.email, company, website, website.host, website.path, website.owner, website.repo, source, source.host, source.path, source.owner, source.repo, license, description, dependencies.id, dependencies.title, lifecycle, deprecation.reason, deprecation.replacement, links.broken, links.website.status, links.website.broken, links.source.status, links.source.broken`,
+	} {
+		_, err := ParseQuery(map[string][]string{key: {"value"}})
+		assert.Error(t, err)
+		assert.Equal(t, message, err.Error())
+	}
+}
+
+func TestParseQuery_UnknownOperator(t *testing.T) {
+	_, err := ParseQuery(map[string][]string{"title[not]": {"value"}})
+	assert.Error(t, err)
+	assert.Equal(t, `unknown operator "not" for field "title", did you mean "ne"?`, err.Error())
+
+	_, err = ParseQuery(map[string][]string{"title[greater]": {"value"}})
+	assert.Error(t, err)
+	assert.Equal(t, `unknown operator "greater" for field "title", expected one of: eq, ne`, err.Error())
+}
+
+func TestParseQuery_MissingValue(t *testing.T) {
+	_, err := ParseQuery(map[string][]string{"title": {}})
+	assert.Error(t, err)
+	assert.Equal(t, `field "title" requires a value`, err.Error())
+}
+
+// endregion
@@ -2,6 +2,9 @@ package search
 
 import (
 	"APIServerExercise/core"
+	"APIServerExercise/tracing"
+	"APIServerExercise/util"
+	"context"
 	"fmt"
 	"github.com/google/uuid"
 	"reflect"
@@ -19,7 +22,7 @@ type Indexer interface {
 var _ Indexer = &Searcher{}
 
 type Filterer interface {
-	FilterMetadata(query map[string][]string, database *core.Database) ([]*core.Metadata, error)
+	FilterMetadata(ctx context.Context, query map[string][]string, database *core.Database) ([]*core.Metadata, error)
 }
 
 var _ Filterer = &Searcher{}
@@ -32,10 +35,16 @@ type Searcher struct {
 
 // Adding data to index
 // If data is a slice, will add children to index with data field name as prefix
+// Each key of a map is its own field, IE: labels.tier. Fields tagged index:"-" are not indexed.
+// Fields tagged index:"spdx" also index every license of their expression, IE: MIT for MIT OR Apache-2.0.
 func (s *Searcher) AddToIndex(data interface{}, id uuid.UUID, prefix string) {
 	// For each field in data
 	elements := reflect.ValueOf(data).Elem()
 	for i := 0; i < elements.NumField(); i++ {
+		indexTag := elements.Type().Field(i).Tag.Get("index")
+		if indexTag == "-" {
+			continue
+		}
 		fieldName := elements.Type().Field(i).Name
 		// Add prefix to field name if provided
 		if prefix != "" {
@@ -55,40 +64,73 @@ func (s *Searcher) AddToIndex(data interface{}, id uuid.UUID, prefix string) {
 			// skip adding the slice itself to the index
 			continue
 		}
+		if rv.Kind() == reflect.Ptr && rv.Type().Elem().Kind() == reflect.Struct {
+			// Add the fields of optional structs with field name as prefix, unset ones have no value
+			if !rv.IsNil() {
+				s.AddToIndex(fieldValueInterface, id, fieldName)
+			}
+			continue
+		}
+		if rv.Kind() == reflect.Map {
+			// Keys keep their case, label keys are case sensitive
+			iter := rv.MapRange()
+			for iter.Next() {
+				s.addValue(fmt.Sprintf("%s.%v", fieldName, iter.Key()), fmt.Sprintf("%v", iter.Value()), id)
+			}
+			continue
+		}
 
-		fieldValue := fmt.Sprintf("%v", fieldValueInterface)
-
-		// Initialize index as needed
-		if len(s.Index[fieldName]) == 0 {
-			s.Index[fieldName] = map[string]map[uuid.UUID]bool{}
+		s.addValue(fieldName, fmt.Sprintf("%v", fieldValueInterface), id)
+		if indexTag == urlIndexTag {
+			if u, ok := fieldValueInterface.(util.Yamlurl); ok {
+				for component, values := range core.URLComponents(u.URL) {
+					for _, value := range values {
+						s.addValue(fmt.Sprintf("%s.%s", fieldName, component), value, id)
+					}
+				}
+			}
 		}
-		if len(s.Index[fieldName][fieldValue]) == 0 {
-			s.Index[fieldName][fieldValue] = map[uuid.UUID]bool{}
+		if indexTag == spdxIndexTag {
+			if expression, err := core.ParseLicenseExpression(fmt.Sprintf("%v", fieldValueInterface)); err == nil {
+				for _, license := range expression.Licenses() {
+					s.addValue(fieldName, license, id)
+				}
+			}
 		}
+	}
+}
 
-		// Add entire value to index
-		s.Index[fieldName][fieldValue][id] = true
+func (s *Searcher) addValue(fieldName string, fieldValue string, id uuid.UUID) {
+	// Initialize index as needed
+	if len(s.Index[fieldName]) == 0 {
+		s.Index[fieldName] = map[string]map[uuid.UUID]bool{}
+	}
+	if len(s.Index[fieldName][fieldValue]) == 0 {
+		s.Index[fieldName][fieldValue] = map[uuid.UUID]bool{}
+	}
 
-		if !s.DisableIndexWords {
-			// Check to see if value has multiple words
-			// If so, add each word to index as well
-			fieldValueParts := strings.Fields(fieldValue)
-			if len(fieldValueParts) <= 1 {
+	// Add entire value to index
+	s.Index[fieldName][fieldValue][id] = true
+
+	if !s.DisableIndexWords {
+		// Check to see if value has multiple words
+		// If so, add each word to index as well
+		fieldValueParts := strings.Fields(fieldValue)
+		if len(fieldValueParts) <= 1 {
+			return
+		}
+
+		for _, part := range fieldValueParts {
+			part, include := cleanWord(part)
+			if !include {
 				continue
 			}
 
-			for _, part := range fieldValueParts {
-				part, include := cleanWord(part)
-				if !include {
-					continue
-				}
-
-				// initialize map if needed
-				if len(s.Index[fieldName][part]) == 0 {
-					s.Index[fieldName][part] = map[uuid.UUID]bool{}
-				}
-				s.Index[fieldName][part][id] = true
+			// initialize map if needed
+			if len(s.Index[fieldName][part]) == 0 {
+				s.Index[fieldName][part] = map[uuid.UUID]bool{}
 			}
+			s.Index[fieldName][part][id] = true
 		}
 	}
 }
@@ -108,42 +150,90 @@ func (s *Searcher) RemoveFromIndex(id uuid.UUID) {
 }
 
 // Filters stored metadata from database based on query
+// The query is validated against the metadata fields first, see ParseQuery. Draft and retired metadata are hidden
+// unless a condition is on the lifecycle.
 // Returns a list of filtered metadata
-func (s *Searcher) FilterMetadata(query map[string][]string, database *core.Database) ([]*core.Metadata, error) {
-	// Copy all metadatas
-	results := make([]*core.Metadata, 0, len(database.Metadatas))
-	for _, id := range database.Ordering { // keeping the default ordering
+func (s *Searcher) FilterMetadata(
+	ctx context.Context,
+	query map[string][]string,
+	database *core.Database) ([]*core.Metadata, error) {
+	ctx, span := tracing.Start(ctx, "search.FilterMetadata")
+	defer span.End()
+
+	conditions, err := ParseQuery(query)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	conditions = withDefaultLifecycle(conditions)
+
+	ids := s.filterIds(ctx, conditions, database.Ordering) // keeping the default ordering
+	results := make([]*core.Metadata, 0, len(ids))
+	for _, id := range ids {
 		results = append(results, database.Metadatas[id])
 	}
 
+	span.SetAttribute("search.results", len(results))
+	return results, nil
+}
+
+// Returns the ids matching every condition, in the order of ids
+func (s *Searcher) filterIds(ctx context.Context, conditions []Condition, ids []uuid.UUID) []uuid.UUID {
+	results := make([]uuid.UUID, len(ids))
+	copy(results, ids)
+
 	// Filter by query parameters
-	for queryKey, queryValues := range query {
+	for _, condition := range conditions {
 		// If no more results are left, stop filtering
 		if len(results) == 0 {
 			break
 		}
 
-		fieldNameIndex, ok := s.Index[queryKey]
-		if !ok {
-			return nil, fmt.Errorf("no such field name %s", queryKey)
-		}
+		_, stepSpan := tracing.Start(ctx, "search.filter")
+		stepSpan.SetAttribute("search.field", condition.Field)
+		stepSpan.SetAttribute("search.operator", condition.Operator)
 
-		// Get the list of matched ids for the query
-		// Only care about the first query value
-		matchedIds := fieldNameIndex[queryValues[0]]
-		newResult := make([]*core.Metadata, 0, len(matchedIds))
+		matchedIds := s.matchingIds(condition)
+		keep := !condition.Negated()
+		newResult := make([]uuid.UUID, 0, len(results))
 
 		// Craft new result list based on matching ids from index
-		// Gets intersect of results and matchedIds
-		for index, metadata := range results {
-			if _, ok := matchedIds[metadata.Id]; ok {
-				newResult = append(newResult, results[index])
+		// Gets intersect of results and matchedIds, or the difference for negated operators
+		for _, id := range results {
+			if _, ok := matchedIds[id]; ok == keep {
+				newResult = append(newResult, id)
 			}
 		}
 		results = newResult
+
+		stepSpan.SetAttribute("search.results", len(results))
+		stepSpan.End()
 	}
+	return results
+}
 
-	return results, nil
+// Returns the ids having the value of the condition, ignoring whether it is negated
+// Fields without any indexed value simply match nothing.
+func (s *Searcher) matchingIds(condition Condition) map[uuid.UUID]bool {
+	var values []string
+	switch condition.Operator {
+	case OperatorIn, OperatorNotIn:
+		values = condition.Values
+	case OperatorExists, OperatorNotExists:
+		for value := range s.Index[condition.Field] {
+			values = append(values, value)
+		}
+	default:
+		return s.Index[condition.Field][condition.Value]
+	}
+
+	matchedIds := map[uuid.UUID]bool{}
+	for _, value := range values {
+		for id := range s.Index[condition.Field][value] {
+			matchedIds[id] = true
+		}
+	}
+	return matchedIds
 }
 
 // Trims the string
@@ -158,3 +248,13 @@ func cleanWord(s string) (string, bool) {
 	}
 	return s, true
 }
+
+// Clears the index and indexes every metadata in the database again
+func (s *Searcher) Rebuild(database *core.Database) {
+	s.Index = map[string]map[string]map[uuid.UUID]bool{}
+	for _, id := range database.Ordering {
+		if metadata, ok := database.Metadatas[id]; ok {
+			s.AddToIndex(metadata, id, "")
+		}
+	}
+}
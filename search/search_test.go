@@ -2,8 +2,12 @@ package search
 
 import (
 	"APIServerExercise/core"
+	"APIServerExercise/lifecycle"
+	"APIServerExercise/util"
+	"context"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"net/url"
 	"testing"
 )
 
@@ -203,7 +207,7 @@ func TestSearcher_FilterMetadata(t *testing.T) {
 
 	searcher := Searcher{
 		Index: map[string]map[string]map[uuid.UUID]bool{
-			"key": {
+			"license": {
 				"value1": {id1: true, id4: true, id3: true},
 				"value2": {id2: true},
 			},
@@ -226,10 +230,10 @@ func TestSearcher_FilterMetadata(t *testing.T) {
 	}
 
 	query := map[string][]string{
-		"key": {"value1"},
+		"license": {"value1"},
 	}
 
-	results, err := searcher.FilterMetadata(query, database)
+	results, err := searcher.FilterMetadata(context.Background(), query, database)
 	assert.Nil(t, err)
 	assert.Len(t, results, 3)
 	assert.Equal(t, id1, results[0].Id)
@@ -237,12 +241,74 @@ func TestSearcher_FilterMetadata(t *testing.T) {
 	assert.Equal(t, id4, results[2].Id)
 }
 
+func TestSearcher_FilterMetadata_WithLicenseExpression(t *testing.T) {
+	searcher := Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}, DisableIndexWords: true}
+	database := &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}}
+	for _, license := range []string{"MIT", "(Apache-2.0 OR MIT) AND BSD-3-Clause", "GPL-2.0+ WITH Classpath-exception-2.0"} {
+		metadata := &core.Metadata{Id: uuid.New(), License: license}
+		database.Metadatas[metadata.Id] = metadata
+		database.Ordering = append(database.Ordering, metadata.Id)
+		searcher.AddToIndex(metadata, metadata.Id, "")
+	}
+
+	// Individual licenses match inside compound expressions, in any case
+	results, err := searcher.FilterMetadata(context.Background(), map[string][]string{"license": {"mit"}}, database)
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+
+	results, err = searcher.FilterMetadata(context.Background(), map[string][]string{"license": {"gpl-2.0"}}, database)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+
+	// Whole expressions match their canonical form, operands are not reordered
+	results, err = searcher.FilterMetadata(context.Background(),
+		map[string][]string{"license": {"bsd-3-clause and (mit or apache-2.0)"}}, database)
+	assert.Nil(t, err)
+	assert.Len(t, results, 0)
+
+	results, err = searcher.FilterMetadata(context.Background(),
+		map[string][]string{"license": {"(apache-2.0 or mit) and bsd-3-clause"}}, database)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+
+	results, err = searcher.FilterMetadata(context.Background(), map[string][]string{"license[ne]": {"MIT"}}, database)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+}
+
+func TestSearcher_FilterMetadata_WithURLComponents(t *testing.T) {
+	searcher := Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}, DisableIndexWords: true}
+	database := &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}}
+	for _, source := range []string{"https://github.com/upbound/repo", "https://github.com/crossplane/crossplane", "https://gitlab.com/upbound/tools/cli"} {
+		u, _ := url.Parse(source)
+		metadata := &core.Metadata{Id: uuid.New(), Source: util.Yamlurl{URL: u}}
+		database.Metadatas[metadata.Id] = metadata
+		database.Ordering = append(database.Ordering, metadata.Id)
+		searcher.AddToIndex(metadata, metadata.Id, "")
+	}
+
+	for query, expected := range map[string]int{
+		"source.host=github.com":     2,
+		"source.host=GitHub.com":     2,
+		"source.owner=upbound":       1,
+		"source.owner=upbound/tools": 1,
+		"source.repo=cli":            1,
+		"source.path=upbound":        2,
+		"source.host[ne]=github.com": 1,
+	} {
+		values, _ := url.ParseQuery(query)
+		results, err := searcher.FilterMetadata(context.Background(), values, database)
+		assert.Nil(t, err)
+		assert.Len(t, results, expected, query)
+	}
+}
+
 func TestSearcher_FilterMetadata_WithInvalidKey(t *testing.T) {
 	id1 := uuid.New()
 
 	searcher := Searcher{
 		Index: map[string]map[string]map[uuid.UUID]bool{
-			"key": {
+			"license": {
 				"value1": {id1: true},
 			},
 		},
@@ -258,13 +324,121 @@ func TestSearcher_FilterMetadata_WithInvalidKey(t *testing.T) {
 	}
 
 	query := map[string][]string{
-		"invalidkey": {"value1"},
+		"licence": {"value1"},
 	}
 
-	results, err := searcher.FilterMetadata(query, database)
+	results, err := searcher.FilterMetadata(context.Background(), query, database)
 	assert.Nil(t, results)
 	assert.Error(t, err)
-	assert.Equal(t, "no such field name invalidkey", err.Error())
+	assert.Equal(t, `unknown field "licence", did you mean "license"?`, err.Error())
+}
+
+func TestSearcher_FilterMetadata_WithNotEqual(t *testing.T) {
+	id1 := uuid.New()
+	id2 := uuid.New()
+
+	searcher := Searcher{
+		Index: map[string]map[string]map[uuid.UUID]bool{
+			"license": {
+				"value1": {id1: true},
+			},
+		},
+	}
+
+	database := &core.Database{
+		Metadatas: map[uuid.UUID]*core.Metadata{
+			id1: {Id: id1},
+			id2: {Id: id2},
+		},
+		Ordering: []uuid.UUID{id1, id2},
+	}
+
+	query := map[string][]string{
+		"license[ne]": {"value1"},
+	}
+
+	results, err := searcher.FilterMetadata(context.Background(), query, database)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, id2, results[0].Id)
+}
+
+func TestSearcher_FilterMetadata_WithLifecycle(t *testing.T) {
+	searcher := Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}}
+	database := &core.Database{Metadatas: map[uuid.UUID]*core.Metadata{}}
+	ids := map[string]uuid.UUID{}
+	for _, state := range []string{lifecycle.Draft, lifecycle.Published, lifecycle.Deprecated, lifecycle.Retired} {
+		metadata := &core.Metadata{Id: uuid.New(), Lifecycle: state}
+		ids[state] = metadata.Id
+		database.Metadatas[metadata.Id] = metadata
+		database.Ordering = append(database.Ordering, metadata.Id)
+		searcher.AddToIndex(metadata, metadata.Id, "")
+	}
+
+	for _, test := range []struct {
+		query    map[string][]string
+		expected []string
+	}{
+		{map[string][]string{}, []string{lifecycle.Published, lifecycle.Deprecated}},
+		{map[string][]string{"lifecycle": {lifecycle.Any}}, []string{lifecycle.Draft, lifecycle.Published, lifecycle.Deprecated, lifecycle.Retired}},
+		{map[string][]string{"lifecycle": {lifecycle.Retired}}, []string{lifecycle.Retired}},
+		{map[string][]string{"lifecycle[ne]": {lifecycle.Published}}, []string{lifecycle.Draft, lifecycle.Deprecated, lifecycle.Retired}},
+	} {
+		results, err := searcher.FilterMetadata(context.Background(), test.query, database)
+		assert.Nil(t, err)
+		var expected []*core.Metadata
+		for _, state := range test.expected {
+			expected = append(expected, database.Metadatas[ids[state]])
+		}
+		assert.Equal(t, expected, results, test.query)
+	}
+}
+
+// Valid fields do not depend on what has been indexed
+func TestSearcher_FilterMetadata_WithFieldNotIndexed(t *testing.T) {
+	id1 := uuid.New()
+
+	searcher := Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}}
+	database := &core.Database{
+		Metadatas: map[uuid.UUID]*core.Metadata{id1: {Id: id1}},
+		Ordering:  []uuid.UUID{id1},
+	}
+
+	results, err := searcher.FilterMetadata(context.Background(), map[string][]string{"website": {"https://website.com"}}, database)
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+}
+
+// endregion
+
+// region Rebuild
+
+func TestSearcher_Rebuild(t *testing.T) {
+	id1 := uuid.New()
+	staleId := uuid.New()
+
+	searcher := Searcher{
+		Index: map[string]map[string]map[uuid.UUID]bool{
+			"title": {
+				"stale": {staleId: true},
+			},
+		},
+		DisableIndexWords: true,
+	}
+
+	database := &core.Database{
+		Metadatas: map[uuid.UUID]*core.Metadata{
+			id1: {Id: id1, Title: "App 1"},
+		},
+		Ordering: []uuid.UUID{
+			id1,
+		},
+	}
+
+	searcher.Rebuild(database)
+
+	assert.Len(t, searcher.Index["title"], 1)
+	assert.True(t, searcher.Index["title"]["App 1"][id1])
 }
 
 // endregion
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"APIServerExercise/applications"
+	"APIServerExercise/config"
+	"APIServerExercise/core"
+	"APIServerExercise/dependencies"
+	"APIServerExercise/duplicates"
+	"APIServerExercise/graphqlserver"
+	"APIServerExercise/grpcserver"
+	"APIServerExercise/health"
+	"APIServerExercise/kinds"
+	"APIServerExercise/licensepolicy"
+	"APIServerExercise/linkcheck"
+	"APIServerExercise/metadatahandlers"
+	"APIServerExercise/metrics"
+	"APIServerExercise/openapi"
+	metadatav1 "APIServerExercise/proto/metadata/v1"
+	"APIServerExercise/search"
+	"APIServerExercise/storage"
+	"APIServerExercise/watch"
+	"context"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"net/http"
+	"strings"
+)
+
+// State shared by the handlers of every request
+type Server struct {
+	Config   *config.Config
+	Database *core.Database
+	Searcher *search.Searcher
+	Filterer search.Filterer
+	// Notified of every change made through the REST or the gRPC API
+	Events *watch.Broadcaster
+	// Resource kinds registered at runtime, served under /kinds
+	Kinds *kinds.Registry
+	// Checks the license of saved metadata
+	Policy *licensepolicy.Policy
+	// Unique constraints of saved metadata
+	Unique *storage.UniqueIndex
+	// Every read and change of the database and the index goes through the store
+	Store *storage.MetadataStore
+	// Candidates of the last duplicate detection
+	Duplicates *duplicates.Detector
+}
+
+// Creates a server with an empty database and index
+func New(cfg *config.Config) *Server {
+	searcher := &search.Searcher{
+		Index:             map[string]map[string]map[uuid.UUID]bool{},
+		DisableIndexWords: cfg.Index.DisableIndexWords,
+	}
+	database := &core.Database{
+		Metadatas: map[uuid.UUID]*core.Metadata{},
+		Ordering:  []uuid.UUID{},
+	}
+	events := watch.NewBroadcaster()
+	unique := storage.NewUniqueIndex(cfg.Validation.Unique)
+	store := &storage.MetadataStore{Database: database, Indexer: searcher, Events: events, Unique: unique}
+	return &Server{
+		Config:     cfg,
+		Database:   database,
+		Searcher:   searcher,
+		Filterer:   &metrics.InstrumentedFilterer{Filterer: searcher},
+		Events:     events,
+		Kinds:      kinds.NewRegistry(cfg.Index.DisableIndexWords),
+		Policy:     licensepolicy.New(cfg.Licenses),
+		Unique:     unique,
+		Store:      store,
+		Duplicates: &duplicates.Detector{Config: cfg.Duplicates, Store: store},
+	}
+}
+
+func (s *Server) newMetadataHandlerManager() metadatahandlers.MetadataHandlerManager {
+	return metadatahandlers.MetadataHandlerManager{
+		Store:           s.Store,
+		Filterer:        s.Filterer,
+		DefaultPageSize: s.Config.Paging.DefaultPageSize,
+		MaxPageSize:     s.Config.Paging.MaxPageSize,
+		Policy:          s.Policy,
+		SourceHosts:     s.Config.Validation.SourceHosts,
+	}
+}
+
+func (s *Server) handleMetadata(w http.ResponseWriter, req *http.Request) {
+	manager := s.newMetadataHandlerManager()
+
+	switch req.Method {
+	case http.MethodGet:
+		manager.HandleMetadataGet(w, req)
+	case http.MethodPut:
+		manager.HandleMetadataPut(w, req)
+	}
+}
+
+func (s *Server) handleMetadataWithId(w http.ResponseWriter, req *http.Request) {
+	manager := s.newMetadataHandlerManager()
+
+	switch req.Method {
+	case http.MethodGet:
+		manager.HandleMetadataGetWithId(w, req)
+	case http.MethodPut:
+		manager.HandleMetadataPutWithId(w, req)
+	case http.MethodDelete:
+		manager.HandleMetadataDeleteWithId(w, req)
+	}
+}
+
+func (s *Server) newKindsHandler() *kinds.Handler {
+	return &kinds.Handler{
+		Registry:        s.Kinds,
+		Auth:            &s.Config.Auth,
+		DefaultPageSize: s.Config.Paging.DefaultPageSize,
+		MaxPageSize:     s.Config.Paging.MaxPageSize,
+	}
+}
+
+func (s *Server) handleKind(w http.ResponseWriter, req *http.Request) {
+	handler := s.newKindsHandler()
+
+	switch req.Method {
+	case http.MethodGet:
+		handler.HandleKindGet(w, req)
+	case http.MethodPut:
+		handler.HandleKindPut(w, req)
+	case http.MethodDelete:
+		handler.HandleKindDelete(w, req)
+	}
+}
+
+func (s *Server) handleResources(w http.ResponseWriter, req *http.Request) {
+	handler := s.newKindsHandler()
+
+	switch req.Method {
+	case http.MethodGet:
+		handler.HandleResourcesGet(w, req)
+	case http.MethodPut:
+		handler.HandleResourcePut(w, req)
+	}
+}
+
+func (s *Server) handleResourceWithId(w http.ResponseWriter, req *http.Request) {
+	handler := s.newKindsHandler()
+
+	switch req.Method {
+	case http.MethodGet:
+		handler.HandleResourceGetWithId(w, req)
+	case http.MethodPut:
+		handler.HandleResourcePutWithId(w, req)
+	case http.MethodDelete:
+		handler.HandleResourceDeleteWithId(w, req)
+	}
+}
+
+// Background checker of the links of the database, run when the link check interval is set
+func (s *Server) NewLinkWorker() *linkcheck.Worker {
+	return &linkcheck.Worker{
+		Checker: linkcheck.New(s.Config.LinkCheck),
+		Store:   s.Store,
+	}
+}
+
+// Path of the GraphQL endpoint, its POST requests only read
+const GraphqlPath = "/graphql"
+
+func (s *Server) newGraphqlHandler() *graphqlserver.Handler {
+	return &graphqlserver.Handler{
+		Store:           s.Store,
+		Filterer:        s.Filterer,
+		DefaultPageSize: s.Config.Paging.DefaultPageSize,
+		MaxPageSize:     s.Config.Paging.MaxPageSize,
+		MaxDepth:        s.Config.Graphql.MaxDepth,
+		MaxComplexity:   s.Config.Graphql.MaxComplexity,
+	}
+}
+
+// Routes served behind the middlewares
+// Every route must restrict its methods and be documented in the openapi package.
+func (s *Server) NewApiRouter() *mux.Router {
+	r := mux.NewRouter()
+	r.MethodNotAllowedHandler = http.HandlerFunc(handleMethodNotAllowed)
+	r.HandleFunc("/metadata", s.handleMetadata).Methods(http.MethodGet, http.MethodPut)
+	r.HandleFunc("/metadata/{id}", s.handleMetadataWithId).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+	dependenciesHandler := &dependencies.Handler{Store: s.Store}
+	r.HandleFunc("/metadata/{id}/dependencies", dependenciesHandler.HandleDependenciesGet).Methods(http.MethodGet)
+	r.HandleFunc("/metadata/{id}/dependents", dependenciesHandler.HandleDependentsGet).Methods(http.MethodGet)
+	r.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+	r.HandleFunc("/config", s.Config.HandleConfig).Methods(http.MethodGet)
+	r.HandleFunc("/reports/licenses", (&licensepolicy.ReportHandler{Policy: s.Policy, Store: s.Store}).HandleReport).
+		Methods(http.MethodGet)
+	r.HandleFunc("/reports/links", (&linkcheck.ReportHandler{Store: s.Store}).HandleReport).Methods(http.MethodGet)
+	r.Handle(GraphqlPath, s.newGraphqlHandler()).Methods(http.MethodPost)
+	applicationsHandler := &applications.Handler{Store: s.Store}
+	r.HandleFunc("/applications", applicationsHandler.HandleApplicationsGet).Methods(http.MethodGet)
+	r.HandleFunc("/applications/{slug}", applicationsHandler.HandleApplicationGet).Methods(http.MethodGet)
+	r.HandleFunc("/applications/{slug}/versions", applicationsHandler.HandleVersionsGet).Methods(http.MethodGet)
+	r.HandleFunc("/applications/{slug}/versions/{version}", applicationsHandler.HandleVersionGet).Methods(http.MethodGet)
+	duplicatesHandler := &duplicates.Handler{Detector: s.Duplicates, Store: s.Store, Auth: &s.Config.Auth}
+	r.HandleFunc("/duplicates", duplicatesHandler.HandleDuplicatesGet).Methods(http.MethodGet)
+	r.HandleFunc("/duplicates/merge", duplicatesHandler.HandleMergePost).Methods(http.MethodPost)
+	r.HandleFunc("/kinds", s.newKindsHandler().HandleKindsGet).Methods(http.MethodGet)
+	r.HandleFunc("/kinds/{kind}", s.handleKind).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+	r.HandleFunc("/kinds/{kind}/resources", s.handleResources).Methods(http.MethodGet, http.MethodPut)
+	r.HandleFunc("/kinds/{kind}/resources/{id}", s.handleResourceWithId).
+		Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+	return r
+}
+
+// gRPC server of the metadata API and the gRPC health service
+// The health service reports NOT_SERVING until it is set to SERVING, IE: once the index is ready.
+func (s *Server) NewGrpcServer(opts ...grpc.ServerOption) (*grpc.Server, *grpchealth.Server) {
+	grpcServer := grpc.NewServer(opts...)
+	metadatav1.RegisterMetadataServiceServer(grpcServer, &grpcserver.MetadataServer{
+		Store:           s.Store,
+		Filterer:        s.Filterer,
+		DefaultPageSize: s.Config.Paging.DefaultPageSize,
+		MaxPageSize:     s.Config.Paging.MaxPageSize,
+		Policy:          s.Policy,
+		SourceHosts:     s.Config.Validation.SourceHosts,
+	})
+	healthServer := grpchealth.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthServer.SetServingStatus(metadatav1.MetadataService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	return grpcServer, healthServer
+}
+
+// Methods of the metadata service that only read, limited with the read budget
+var GrpcReadMethods = []string{
+	metadatav1.MetadataService_GetMetadata_FullMethodName,
+	metadatav1.MetadataService_ListMetadata_FullMethodName,
+	metadatav1.MetadataService_WatchMetadata_FullMethodName,
+}
+
+// Options applying the interceptors, in order, to the calls of the metadata service
+// The health service is left out so probes are never rate limited or gated, like the health endpoints.
+func MetadataInterceptors(unary []grpc.UnaryServerInterceptor, stream []grpc.StreamServerInterceptor) []grpc.ServerOption {
+	var unaryInterceptors []grpc.UnaryServerInterceptor
+	for _, interceptor := range unary {
+		interceptor := interceptor
+		unaryInterceptors = append(unaryInterceptors, func(
+			ctx context.Context,
+			req interface{},
+			info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler) (interface{}, error) {
+			if !isMetadataMethod(info.FullMethod) {
+				return handler(ctx, req)
+			}
+			return interceptor(ctx, req, info, handler)
+		})
+	}
+	var streamInterceptors []grpc.StreamServerInterceptor
+	for _, interceptor := range stream {
+		interceptor := interceptor
+		streamInterceptors = append(streamInterceptors, func(
+			srv interface{},
+			ss grpc.ServerStream,
+			info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler) error {
+			if !isMetadataMethod(info.FullMethod) {
+				return handler(srv, ss)
+			}
+			return interceptor(srv, ss, info, handler)
+		})
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	}
+}
+
+func isMetadataMethod(method string) bool {
+	return strings.HasPrefix(method, "/"+metadatav1.MetadataService_ServiceDesc.ServiceName+"/")
+}
+
+// Health endpoints, served without the middlewares so probes are never rate limited or gated
+func NewHealthRouter(checker *health.Checker) *mux.Router {
+	r := mux.NewRouter()
+	r.MethodNotAllowedHandler = http.HandlerFunc(handleMethodNotAllowed)
+	r.HandleFunc("/livez", checker.HandleLivez).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", checker.HandleReadyz).Methods(http.MethodGet)
+	r.HandleFunc("/healthz", checker.HandleHealthz).Methods(http.MethodGet)
+	return r
+}
+
+// Requests with a method a route does not support are answered with 404, as they always were
+func handleMethodNotAllowed(w http.ResponseWriter, req *http.Request) {
+	w.WriteHeader(http.StatusNotFound)
+}
+
+// Serves the OpenAPI document of every route under /openapi.yaml and /openapi.json
+func RegisterOpenApi(r *mux.Router, others ...*mux.Router) error {
+	spec := &openapi.Spec{Info: openapi.Info{
+		Title:       "Application Metadata API",
+		Description: "Persist and search application metadata",
+		Version:     "1.0.0",
+	}}
+	r.HandleFunc("/openapi.yaml", spec.HandleYaml).Methods(http.MethodGet)
+	r.HandleFunc("/openapi.json", spec.HandleJson).Methods(http.MethodGet)
+	return spec.Generate(append([]*mux.Router{r}, others...)...)
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"APIServerExercise/config"
+	"APIServerExercise/health"
+	"APIServerExercise/openapi"
+	metadatav1 "APIServerExercise/proto/metadata/v1"
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gopkg.in/yaml.v3"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newRouter() *mux.Router {
+	r := New(config.Defaults()).NewApiRouter()
+	if err := RegisterOpenApi(r, NewHealthRouter(&health.Checker{})); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Every route must be documented and every documented operation must be routed
+func TestRegisterOpenApi(t *testing.T) {
+	r := New(config.Defaults()).NewApiRouter()
+	err := RegisterOpenApi(r, NewHealthRouter(&health.Checker{}))
+	assert.Nil(t, err)
+}
+
+// The documented schema must describe what the handlers return
+func TestOpenApi_MatchesResponses(t *testing.T) {
+	r := newRouter()
+
+	responseRecorder := httptest.NewRecorder()
+	r.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var document openapi.Document
+	assert.Nil(t, yaml.Unmarshal(responseRecorder.Body.Bytes(), &document))
+
+	payload := `title: Valid App 1
+version: 0.0.1
+maintainers:
+- name: firstmaintainer app1
+  email: firstmaintainer@hotmail.com
+company: Random Inc.
+website: https://website.com
+source: https://github.com/random/repo
+license: Apache-2.0
+description: Some application content`
+	responseRecorder = httptest.NewRecorder()
+	r.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodPut, "/metadata", strings.NewReader(payload)))
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	assertMatchesSchema(t, document, "Metadata", responseRecorder.Body.Bytes())
+
+	responseRecorder = httptest.NewRecorder()
+	r.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/metadata", nil))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assertMatchesSchema(t, document, "ResultPage", responseRecorder.Body.Bytes())
+}
+
+// Checks that the response only has documented properties and has every required property
+func assertMatchesSchema(t *testing.T, document openapi.Document, name string, body []byte) {
+	var response map[string]interface{}
+	assert.Nil(t, yaml.Unmarshal(body, &response))
+	schema := document.Components.Schemas[name]
+	for key := range response {
+		assert.Contains(t, schema.Properties, key, "%s has undocumented property %s", name, key)
+	}
+	for _, key := range schema.Required {
+		assert.Contains(t, response, key, "%s is missing required property %s", name, key)
+	}
+}
+
+// Unsupported methods are answered with 404, not 405
+func TestNewApiRouter_WithUnsupportedMethod(t *testing.T) {
+	r := newRouter()
+
+	responseRecorder := httptest.NewRecorder()
+	r.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodPost, "/metadata", nil))
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+
+	responseRecorder = httptest.NewRecorder()
+	NewHealthRouter(&health.Checker{}).ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodPost, "/livez", nil))
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+}
+
+// The interceptors apply to the metadata service, never to the health service
+func TestMetadataInterceptors(t *testing.T) {
+	ready := health.NewFlag("not ready")
+	grpcServer, _ := New(config.Defaults()).NewGrpcServer(MetadataInterceptors(
+		[]grpc.UnaryServerInterceptor{ready.UnaryInterceptor},
+		[]grpc.StreamServerInterceptor{ready.StreamInterceptor})...)
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := metadatav1.NewMetadataServiceClient(conn)
+	request := &metadatav1.GetMetadataRequest{Id: "e9861b9b-9155-4857-a9e9-c651ad7abba9"}
+
+	_, err = client.GetMetadata(context.Background(), request)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+
+	ready.Set()
+	_, err = client.GetMetadata(context.Background(), request)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
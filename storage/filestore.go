@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/lifecycle"
+	"fmt"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Persists the database to a YAML file
+// The file contains the list of metadata in the default ordering.
+type FileStore struct {
+	Path string
+}
+
+// Load the metadata in the file into database
+// A missing file is not an error, the server is starting for the first time.
+func (f *FileStore) Load(database *core.Database) error {
+	content, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", f.Path, err)
+	}
+
+	var metadatas []*core.Metadata
+	if err := yaml.Unmarshal(content, &metadatas); err != nil {
+		return fmt.Errorf("failed to decode %s: %v", f.Path, err)
+	}
+
+	for _, metadata := range metadatas {
+		if metadata.Id == (uuid.UUID{}) {
+			return fmt.Errorf("metadata %q in %s has no id", metadata.Title, f.Path)
+		}
+		// Metadata saved before lifecycles existed are published
+		if metadata.Lifecycle == "" {
+			metadata.Lifecycle = lifecycle.Published
+		}
+		if _, ok := database.Metadatas[metadata.Id]; !ok {
+			database.Ordering = append(database.Ordering, metadata.Id)
+		}
+		database.Metadatas[metadata.Id] = metadata
+	}
+	return nil
+}
+
+// Write every metadata in database to the file
+// Writes to a temporary file first so a failed save never leaves a half written file behind.
+func (f *FileStore) Save(database *core.Database) error {
+	metadatas := make([]*core.Metadata, 0, len(database.Ordering))
+	for _, id := range database.Ordering {
+		if metadata, ok := database.Metadatas[id]; ok {
+			metadatas = append(metadatas, metadata)
+		}
+	}
+
+	content, err := yaml.Marshal(metadatas)
+	if err != nil {
+		return fmt.Errorf("failed to encode database: %v", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.Path), filepath.Base(f.Path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %v", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %v", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %v", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), f.Path); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", f.Path, err)
+	}
+	return nil
+}
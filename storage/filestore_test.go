@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func newTestMetadata(title string) *core.Metadata {
+	website, _ := url.Parse("https://website.com")
+	source, _ := url.Parse("https://github.com/random/repo")
+	return &core.Metadata{
+		Id:      uuid.New(),
+		Title:   title,
+		Version: "0.0.1",
+		Maintainers: []*core.Maintainer{
+			{
+				Name:  "firstmaintainer app1",
+				Email: "firstmaintainer@hotmail.com",
+			},
+		},
+		Company:     "Random Inc.",
+		Website:     util.Yamlurl{URL: website},
+		Source:      util.Yamlurl{URL: source},
+		License:     "Apache-2.0",
+		Description: "### Interesting Title\n Some application content, and description",
+	}
+}
+
+func newDatabase() *core.Database {
+	return &core.Database{
+		Metadatas: map[uuid.UUID]*core.Metadata{},
+		Ordering:  []uuid.UUID{},
+	}
+}
+
+func TestFileStore_SaveAndLoad(t *testing.T) {
+	store := FileStore{Path: filepath.Join(t.TempDir(), "data.yaml")}
+
+	metadata1 := newTestMetadata("App 1")
+	metadata1.Lifecycle = "deprecated"
+	metadata2 := newTestMetadata("App 2")
+	database := newDatabase()
+	database.Metadatas[metadata1.Id] = metadata1
+	database.Metadatas[metadata2.Id] = metadata2
+	database.Ordering = []uuid.UUID{metadata2.Id, metadata1.Id}
+
+	err := store.Save(database)
+	assert.Nil(t, err)
+
+	loaded := newDatabase()
+	err = store.Load(loaded)
+	assert.Nil(t, err)
+	assert.Equal(t, []uuid.UUID{metadata2.Id, metadata1.Id}, loaded.Ordering)
+	assert.Equal(t, metadata1, loaded.Metadatas[metadata1.Id])
+	// Metadata saved before lifecycles existed are published
+	metadata2.Lifecycle = "published"
+	assert.Equal(t, metadata2, loaded.Metadatas[metadata2.Id])
+}
+
+func TestFileStore_Load_WithMissingFile(t *testing.T) {
+	store := FileStore{Path: filepath.Join(t.TempDir(), "missing.yaml")}
+
+	database := newDatabase()
+	err := store.Load(database)
+	assert.Nil(t, err)
+	assert.Empty(t, database.Metadatas)
+}
+
+func TestFileStore_Load_WithoutId(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.yaml")
+	err := ioutil.WriteFile(path, []byte("- title: App 1\n"), 0644)
+	assert.Nil(t, err)
+
+	store := FileStore{Path: path}
+	err = store.Load(newDatabase())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "metadata \"App 1\" in")
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/dependencies"
+	"fmt"
+	"github.com/google/uuid"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The metadata to merge or to merge into does not exist
+type NotFoundError struct {
+	Id uuid.UUID
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("metadata %s not found", e.Id)
+}
+
+// Merges a metadata into another one and deletes it, returns the metadata it was merged into
+// The kept metadata keeps its values and gains the maintainers, labels and annotations it does not have. A record of the
+// merged metadata, and the records of the metadata merged into it, are added to its merge history.
+// The other metadata whose dependencies or replacement refer to the merged metadata by id refer to the kept one instead.
+// Returns a *NotFoundError when a metadata does not exist, a *ConflictError when the kept metadata would break a
+// unique constraint and a *dependencies.CycleError when the dependencies would form a cycle, nothing is changed then.
+func (s *MetadataStore) Merge(keepId uuid.UUID, mergeId uuid.UUID, mergedAt time.Time) (*core.Metadata, error) {
+	if keepId == mergeId {
+		return nil, fmt.Errorf("can not merge metadata %s into itself", keepId)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keep, ok := s.Database.Metadatas[keepId]
+	if !ok {
+		return nil, &NotFoundError{Id: keepId}
+	}
+	merge, ok := s.Database.Metadatas[mergeId]
+	if !ok {
+		return nil, &NotFoundError{Id: mergeId}
+	}
+
+	// The kept metadata is replaced by a copy so readers of the previous one are not affected
+	merged := *keep
+	merged.Maintainers = append([]*core.Maintainer{}, keep.Maintainers...)
+	for _, maintainer := range merge.Maintainers {
+		if !hasMaintainer(merged.Maintainers, maintainer) {
+			merged.Maintainers = append(merged.Maintainers, maintainer)
+		}
+	}
+	merged.Labels = mergeMaps(keep.Labels, merge.Labels)
+	merged.Annotations = mergeMaps(keep.Annotations, merge.Annotations)
+	merged.Merged = append(append([]*core.MergeRecord{}, keep.Merged...), merge.Merged...)
+	merged.Merged = append(merged.Merged, &core.MergeRecord{
+		Id:          merge.Id,
+		Title:       merge.Title,
+		Version:     merge.Version,
+		Maintainers: merge.Maintainers,
+		Company:     merge.Company,
+		Website:     merge.Website,
+		Source:      merge.Source,
+		License:     merge.License,
+		Description: merge.Description,
+		MergedAt:    mergedAt,
+	})
+	sort.SliceStable(merged.Merged, func(i, j int) bool { return merged.Merged[i].MergedAt.Before(merged.Merged[j].MergedAt) })
+	if redirected, ok := redirect(&merged, mergeId, keepId); ok {
+		merged = *redirected
+	}
+	var referring []*core.Metadata
+	for _, id := range s.Database.Ordering {
+		if id == keepId || id == mergeId {
+			continue
+		}
+		if redirected, ok := redirect(s.Database.Metadatas[id], mergeId, keepId); ok {
+			referring = append(referring, redirected)
+		}
+	}
+
+	// The merged metadata no longer holds its unique values
+	if s.Unique != nil {
+		s.Unique.remove(mergeId)
+		if err := s.checkUnique(&merged); err != nil {
+			s.Unique.add(merge)
+			return nil, err
+		}
+	}
+	if err := dependencies.CheckChange(s.Database, []uuid.UUID{mergeId}, append(referring, &merged)...); err != nil {
+		if s.Unique != nil {
+			s.Unique.add(merge)
+		}
+		return nil, err
+	}
+	s.remove(mergeId)
+	s.save(&merged, true)
+	for _, metadata := range referring {
+		s.save(metadata, true)
+	}
+	return &merged, nil
+}
+
+// Returns a copy of the metadata referring to the kept metadata by id where it referred to the merged one, false when
+// it does not refer to the merged one
+// The kept metadata drops its references to the merged one rather than referring to itself.
+func redirect(metadata *core.Metadata, mergeId uuid.UUID, keepId uuid.UUID) (*core.Metadata, bool) {
+	self := metadata.Id == keepId
+	redirected := *metadata
+	changed := false
+	redirected.Dependencies = nil
+	for _, dependency := range metadata.Dependencies {
+		if dependency.Id != mergeId.String() {
+			redirected.Dependencies = append(redirected.Dependencies, dependency)
+			continue
+		}
+		changed = true
+		if !self {
+			redirected.Dependencies = append(redirected.Dependencies, &core.Dependency{Id: keepId.String()})
+		}
+	}
+	if metadata.Deprecation != nil && metadata.Deprecation.Replacement == mergeId.String() {
+		changed = true
+		deprecation := *metadata.Deprecation
+		deprecation.Replacement = keepId.String()
+		if self {
+			deprecation.Replacement = ""
+		}
+		redirected.Deprecation = &deprecation
+	}
+	return &redirected, changed
+}
+
+// Maintainers are the same when they have the same email, in any case
+func hasMaintainer(maintainers []*core.Maintainer, maintainer *core.Maintainer) bool {
+	for _, existing := range maintainers {
+		if strings.EqualFold(existing.Email, maintainer.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the pairs of both maps, the values of keep win, nil when both are empty
+func mergeMaps(keep map[string]string, merge map[string]string) map[string]string {
+	if len(keep) == 0 && len(merge) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(keep)+len(merge))
+	for key, value := range merge {
+		merged[key] = value
+	}
+	for key, value := range keep {
+		merged[key] = value
+	}
+	return merged
+}
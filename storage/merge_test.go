@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/lifecycle"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestMetadataStore_Merge(t *testing.T) {
+	store := newUniqueStore()
+	keep := newTestMetadata("Valid App")
+	keep.Labels = map[string]string{"tier": "critical"}
+	merge := newTestMetadata("Valid-App")
+	merge.Source.URL, _ = url.Parse("https://github.com/random/other")
+	merge.Maintainers = append(merge.Maintainers, &core.Maintainer{Name: "second", Email: "second@hotmail.com"})
+	merge.Labels = map[string]string{"tier": "low", "team": "payments"}
+	store.Put(keep)
+	store.Put(merge)
+	mergedAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	merged, err := store.Merge(keep.Id, merge.Id, mergedAt)
+	assert.Nil(t, err)
+	assert.Equal(t, keep.Id, merged.Id)
+	assert.Equal(t, "Valid App", merged.Title)
+	assert.Len(t, merged.Maintainers, 2)
+	assert.Equal(t, map[string]string{"tier": "critical", "team": "payments"}, merged.Labels)
+	if assert.Len(t, merged.Merged, 1) {
+		assert.Equal(t, merge.Id, merged.Merged[0].Id)
+		assert.Equal(t, "Valid-App", merged.Merged[0].Title)
+		assert.Equal(t, mergedAt, merged.Merged[0].MergedAt)
+	}
+	assert.Equal(t, []uuid.UUID{keep.Id}, store.Database.Ordering)
+	_, ok := store.Get(merge.Id)
+	assert.False(t, ok)
+	// The previous metadata is not changed
+	assert.Len(t, keep.Maintainers, 1)
+
+	// The values of the merged metadata are free again
+	again := newTestMetadata("Valid-App")
+	again.Source.URL, _ = url.Parse("https://github.com/random/other")
+	_, err = store.Put(again)
+	assert.Nil(t, err)
+
+	// The history is kept when the metadata is saved again and when it is merged
+	update := *merged
+	update.Merged = nil
+	store.Put(&update)
+	merged, err = store.Merge(again.Id, keep.Id, mergedAt.Add(time.Hour))
+	assert.Nil(t, err)
+	if assert.Len(t, merged.Merged, 2) {
+		assert.Equal(t, merge.Id, merged.Merged[0].Id)
+		assert.Equal(t, keep.Id, merged.Merged[1].Id)
+	}
+}
+
+func TestMetadataStore_Merge_Errors(t *testing.T) {
+	store := newUniqueStore()
+	keep := newTestMetadata("App 1")
+	store.Put(keep)
+	unknown := uuid.New()
+
+	_, err := store.Merge(keep.Id, keep.Id, time.Now())
+	assert.EqualError(t, err, "can not merge metadata "+keep.Id.String()+" into itself")
+	_, err = store.Merge(keep.Id, unknown, time.Now())
+	assert.Equal(t, &NotFoundError{Id: unknown}, err)
+	_, err = store.Merge(unknown, keep.Id, time.Now())
+	assert.Equal(t, &NotFoundError{Id: unknown}, err)
+	assert.Len(t, store.Database.Metadatas, 1)
+}
+
+func TestMetadataStore_Merge_References(t *testing.T) {
+	store := newMetadataStore()
+	keep := newTestMetadata("App 1")
+	merge := newTestMetadata("App 2")
+	store.Put(keep)
+	store.Put(merge)
+	dependent := newTestMetadata("App 3")
+	dependent.Dependencies = []*core.Dependency{{Id: merge.Id.String()}}
+	dependent.Lifecycle = lifecycle.Deprecated
+	dependent.Deprecation = &core.Deprecation{Reason: "Moved", Replacement: merge.Id.String()}
+	_, err := store.Put(dependent)
+	assert.Nil(t, err)
+	self := *keep
+	self.Dependencies = []*core.Dependency{{Id: merge.Id.String()}}
+	_, err = store.Put(&self)
+	assert.Nil(t, err)
+
+	merged, err := store.Merge(keep.Id, merge.Id, time.Now())
+	assert.Nil(t, err)
+	// The kept metadata does not depend on itself
+	assert.Empty(t, merged.Dependencies)
+	redirected, _ := store.Get(dependent.Id)
+	assert.Equal(t, []*core.Dependency{{Id: keep.Id.String()}}, redirected.Dependencies)
+	assert.Equal(t, keep.Id.String(), redirected.Deprecation.Replacement)
+	// The previous metadata is not changed
+	assert.Equal(t, merge.Id.String(), dependent.Dependencies[0].Id)
+	assert.Equal(t, merge.Id.String(), dependent.Deprecation.Replacement)
+}
+
+func TestMetadataStore_Merge_WithCycle(t *testing.T) {
+	store := newUniqueStore()
+	merge := newTestMetadata("App 1")
+	merge.Source.URL, _ = url.Parse("https://github.com/random/merge")
+	store.Put(merge)
+	dependent := newTestMetadata("App 2")
+	dependent.Source.URL, _ = url.Parse("https://github.com/random/dependent")
+	dependent.Dependencies = []*core.Dependency{{Id: merge.Id.String()}}
+	store.Put(dependent)
+	keep := newTestMetadata("App 3")
+	keep.Dependencies = []*core.Dependency{{Id: dependent.Id.String()}}
+	store.Put(keep)
+
+	// The dependent would depend on the kept metadata, which depends on it
+	_, err := store.Merge(keep.Id, merge.Id, time.Now())
+	assert.EqualError(t, err, "dependencies form a cycle: App 2 0.0.1 -> App 3 0.0.1 -> App 2 0.0.1")
+	assert.Len(t, store.Database.Metadatas, 3)
+	stored, _ := store.Get(dependent.Id)
+	assert.Same(t, dependent, stored)
+
+	// The values of the merged metadata are still taken
+	again := newTestMetadata("App 1")
+	_, err = store.Put(again)
+	assert.IsType(t, &ConflictError{}, err)
+}
@@ -0,0 +1,269 @@
+package storage
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/dependencies"
+	"APIServerExercise/lifecycle"
+	"APIServerExercise/search"
+	"APIServerExercise/util"
+	"APIServerExercise/watch"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// Changes to the metadata shared by the REST and gRPC APIs
+// Keeps the database, the index and the watchers in sync. Every read and change of the database and the index goes
+// through the store so they are never read while they change. Saved metadata are never modified, they are replaced.
+type MetadataStore struct {
+	Database *core.Database
+	Indexer  search.Indexer
+	// Notified of every change, optional
+	Events *watch.Broadcaster
+	// Unique constraints checked when saving, optional
+	Unique *UniqueIndex
+	// Dates deprecations, uses time.Now when nil
+	Now func() time.Time
+
+	// Held for reading by the readers of the database and the index, and for writing by the changes
+	mutex sync.RWMutex
+}
+
+func (s *MetadataStore) Get(id uuid.UUID) (*core.Metadata, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	metadata, ok := s.Database.Metadatas[id]
+	return metadata, ok
+}
+
+// Calls read with the database while it can not change, IE: to filter it with the index
+// The database and the index must not be kept past read, the metadata can.
+func (s *MetadataStore) Read(read func(database *core.Database)) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	read(s.Database)
+}
+
+// Calls write with the database while nothing else reads or changes it, IE: to load it
+// write must keep the index and the unique constraints in sync with the database.
+func (s *MetadataStore) Write(write func(database *core.Database)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	write(s.Database)
+}
+
+// Returns every metadata in the default ordering
+func (s *MetadataStore) Snapshot() []*core.Metadata {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	metadatas := make([]*core.Metadata, 0, len(s.Database.Ordering))
+	for _, id := range s.Database.Ordering {
+		if metadata, ok := s.Database.Metadatas[id]; ok {
+			metadatas = append(metadatas, metadata)
+		}
+	}
+	return metadatas
+}
+
+// Saves the metadata, replacing the metadata with the same id, returns whether the metadata was created
+// The metadata is normalized, completed from the metadata it replaces and validated, it is not saved when invalid.
+func (s *MetadataStore) Put(metadata *core.Metadata) (bool, error) {
+	normalize(metadata)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Check if there is an existing metadata with the same Id
+	existing, exists := s.Database.Metadatas[metadata.Id]
+	inherit(metadata, existing)
+	if err := s.validate(metadata, existing); err != nil {
+		return false, err
+	}
+	s.save(metadata, exists)
+	return !exists, nil
+}
+
+// Generates an id when the metadata has none and puts its license, URLs and dependency ids in their canonical form
+// IE: apache-2.0 -> Apache-2.0
+func normalize(metadata *core.Metadata) {
+	if metadata.Id == (uuid.UUID{}) {
+		metadata.Id = uuid.New()
+	}
+	metadata.License = core.NormalizeLicense(metadata.License)
+	metadata.Website.URL = core.NormalizeURL(metadata.Website.URL)
+	metadata.Source.URL = core.NormalizeURL(metadata.Source.URL)
+	for _, dependency := range metadata.Dependencies {
+		if id, err := uuid.Parse(dependency.Id); err == nil {
+			dependency.Id = id.String()
+		}
+	}
+}
+
+// Sets the fields payloads do not set from the metadata replaced, existing is nil for a new metadata
+// Without an application, the metadata keeps the application of the metadata it replaces or takes the slug of its title.
+// The link status is set by the link checker only, it is kept while the website and source do not change.
+// The merge history is set by Merge only and is always kept.
+func inherit(metadata *core.Metadata, existing *core.Metadata) {
+	if metadata.Application == "" && existing != nil {
+		metadata.Application = existing.Application
+	}
+	if metadata.Application == "" {
+		metadata.Application = core.Slug(metadata.Title)
+	}
+	metadata.Links = nil
+	if existing != nil && sameLinks(existing, metadata) {
+		metadata.Links = existing.Links
+	}
+	metadata.Merged = nil
+	if existing != nil {
+		metadata.Merged = existing.Merged
+	}
+}
+
+// Applies the lifecycle change of the metadata and checks it could replace existing, nil for a new metadata
+// Returns a *lifecycle.DeprecationError or a *lifecycle.TransitionError when the lifecycle change is not allowed, a
+// *ConflictError when another metadata has the same values for the fields of a unique constraint, and a
+// *dependencies.UnresolvedError or a *dependencies.CycleError when a dependency does not resolve or leads back to the
+// metadata, or when a dependent would then form a cycle.
+func (s *MetadataStore) validate(metadata *core.Metadata, existing *core.Metadata) error {
+	if err := lifecycle.Apply(metadata, existing, s.exists, s.now()); err != nil {
+		return err
+	}
+	if err := s.checkUnique(metadata); err != nil {
+		return err
+	}
+	if err := dependencies.Check(s.Database, metadata); err != nil {
+		return err
+	}
+	return dependencies.CheckChange(s.Database, nil, metadata)
+}
+
+func (s *MetadataStore) exists(id uuid.UUID) bool {
+	_, ok := s.Database.Metadatas[id]
+	return ok
+}
+
+func (s *MetadataStore) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s *MetadataStore) checkUnique(metadata *core.Metadata) error {
+	if s.Unique == nil {
+		return nil
+	}
+	if fields, owner, conflict := s.Unique.conflict(metadata); conflict {
+		return &ConflictError{Fields: fields, Existing: s.Database.Metadatas[owner]}
+	}
+	return nil
+}
+
+// Saves the metadata in the database and the indexes, replacing the existing metadata with the same id
+func (s *MetadataStore) save(metadata *core.Metadata, exists bool) {
+	// If there is an existing metadata, remove old metadata Id from indexes
+	if exists {
+		s.Indexer.RemoveFromIndex(metadata.Id)
+	} else {
+		// this is a new metadata, add to ordering
+		s.Database.Ordering = append(s.Database.Ordering, metadata.Id)
+	}
+
+	// Save metadata in database and add to index
+	s.Database.Metadatas[metadata.Id] = metadata
+	s.Indexer.AddToIndex(metadata, metadata.Id, "")
+	if s.Unique != nil {
+		s.Unique.add(metadata)
+	}
+
+	eventType := watch.Added
+	if exists {
+		eventType = watch.Modified
+	}
+	s.Events.Publish(watch.Event{Type: eventType, Metadata: metadata})
+}
+
+// Records the result of checking the links of the metadata, returns false if it does not exist
+// Also returns false, and keeps the previous status, when the website or source of the metadata is not the one that
+// was checked, IE: they changed during the check.
+// The metadata is replaced by a copy so readers of the previous one are not affected.
+func (s *MetadataStore) SetLinks(id uuid.UUID, links *core.LinkStatus) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing, ok := s.Database.Metadatas[id]
+	if !ok || !checkedLinks(existing, links) {
+		return false
+	}
+	metadata := *existing
+	metadata.Links = links
+
+	s.Indexer.RemoveFromIndex(id)
+	s.Database.Metadatas[id] = &metadata
+	s.Indexer.AddToIndex(&metadata, id, "")
+	s.Events.Publish(watch.Event{Type: watch.Modified, Metadata: &metadata})
+	return true
+}
+
+// Whether both metadata have the same website and source
+func sameLinks(a *core.Metadata, b *core.Metadata) bool {
+	return urlString(a.Website) == urlString(b.Website) && urlString(a.Source) == urlString(b.Source)
+}
+
+// Whether the status was checked against the current website and source of the metadata
+func checkedLinks(metadata *core.Metadata, links *core.LinkStatus) bool {
+	return checkedURL(metadata.Website, links.Website) && checkedURL(metadata.Source, links.Source)
+}
+
+func checkedURL(u util.Yamlurl, check *core.LinkCheck) bool {
+	if u.URL == nil || check == nil {
+		return u.URL == nil && check == nil
+	}
+	return u.String() == check.URL
+}
+
+func urlString(u util.Yamlurl) string {
+	if u.URL == nil {
+		return ""
+	}
+	return u.String()
+}
+
+// Removes the metadata, returns false if it does not exist
+// Returns a *dependencies.CycleError, and does not remove the metadata, when a dependency by title of another metadata
+// would then resolve to a metadata leading back to it.
+func (s *MetadataStore) Delete(id uuid.UUID) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.exists(id) {
+		return false, nil
+	}
+	if err := dependencies.CheckChange(s.Database, []uuid.UUID{id}); err != nil {
+		return false, err
+	}
+	return s.remove(id), nil
+}
+
+func (s *MetadataStore) remove(id uuid.UUID) bool {
+	metadata, ok := s.Database.Metadatas[id]
+	if !ok {
+		return false
+	}
+
+	if s.Unique != nil {
+		s.Unique.remove(id)
+	}
+	s.Indexer.RemoveFromIndex(id)
+	delete(s.Database.Metadatas, id)
+	for index, existingId := range s.Database.Ordering {
+		if id == existingId {
+			// remove id from Ordering
+			s.Database.Ordering = append(s.Database.Ordering[:index], s.Database.Ordering[index+1:]...)
+			break
+		}
+	}
+
+	s.Events.Publish(watch.Event{Type: watch.Deleted, Metadata: metadata})
+	return true
+}
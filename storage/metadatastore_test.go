@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/dependencies"
+	"APIServerExercise/lifecycle"
+	"APIServerExercise/search"
+	"APIServerExercise/watch"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newMetadataStore() *MetadataStore {
+	return &MetadataStore{
+		Database: newDatabase(),
+		Indexer:  &search.Searcher{Index: map[string]map[string]map[uuid.UUID]bool{}},
+		Events:   watch.NewBroadcaster(),
+	}
+}
+
+func TestMetadataStore(t *testing.T) {
+	store := newMetadataStore()
+	searcher := store.Indexer.(*search.Searcher)
+	events, unsubscribe := store.Events.Subscribe()
+	defer unsubscribe()
+
+	metadata := newTestMetadata("App")
+	metadata.Id = uuid.UUID{}
+	created, err := store.Put(metadata)
+	assert.Nil(t, err)
+	assert.True(t, created)
+	assert.NotEqual(t, uuid.UUID{}, metadata.Id)
+	assert.Equal(t, []uuid.UUID{metadata.Id}, store.Database.Ordering)
+	assert.True(t, searcher.Index["title"]["App"][metadata.Id])
+	assert.Equal(t, watch.Event{Type: watch.Added, Metadata: metadata}, <-events)
+
+	updated := newTestMetadata("Renamed")
+	updated.Id = metadata.Id
+	created, err = store.Put(updated)
+	assert.Nil(t, err)
+	assert.False(t, created)
+	assert.Len(t, store.Database.Ordering, 1)
+	assert.Empty(t, searcher.Index["title"]["App"])
+	assert.Equal(t, watch.Modified, (<-events).Type)
+
+	got, ok := store.Get(metadata.Id)
+	assert.True(t, ok)
+	assert.Equal(t, "Renamed", got.Title)
+
+	deleted, err := store.Delete(metadata.Id)
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+	deleted, err = store.Delete(metadata.Id)
+	assert.Nil(t, err)
+	assert.False(t, deleted)
+	assert.Empty(t, store.Database.Metadatas)
+	assert.Empty(t, store.Database.Ordering)
+	assert.Equal(t, watch.Event{Type: watch.Deleted, Metadata: updated}, <-events)
+}
+
+// Run with -race, readers and the link checker must not see the database or the index while they change
+func TestMetadataStore_Concurrent(t *testing.T) {
+	store := newMetadataStore()
+	store.Unique = NewUniqueIndex([][]string{{"title", "version"}})
+	searcher := store.Indexer.(*search.Searcher)
+	existing := newTestMetadata("Existing")
+	store.Put(existing)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				metadata := newTestMetadata(fmt.Sprintf("App %d %d", i, j))
+				store.Put(metadata)
+				store.SetLinks(existing.Id, &core.LinkStatus{Broken: j%2 == 0,
+					Website: &core.LinkCheck{URL: existing.Website.String()}, Source: &core.LinkCheck{URL: existing.Source.String()}})
+				store.Get(metadata.Id)
+				store.Read(func(database *core.Database) {
+					searcher.FilterMetadata(context.Background(), map[string][]string{"links.broken": {"true"}}, database)
+				})
+				store.Snapshot()
+				store.Delete(metadata.Id)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, store.Snapshot(), 1)
+}
+
+func TestMetadataStore_NormalizesLicense(t *testing.T) {
+	store := newMetadataStore()
+
+	metadata := newTestMetadata("App")
+	metadata.License = "apache-2.0 or mit"
+	store.Put(metadata)
+	assert.Equal(t, "Apache-2.0 OR MIT", metadata.License)
+}
+
+func TestMetadataStore_NormalizesURLs(t *testing.T) {
+	store := newMetadataStore()
+
+	metadata := newTestMetadata("App")
+	metadata.Website.URL, _ = url.Parse("HTTPS://Website.com:443/")
+	metadata.Source.URL, _ = url.Parse("https://GitHub.com/random/repo/")
+	store.Put(metadata)
+	assert.Equal(t, "https://website.com", metadata.Website.String())
+	assert.Equal(t, "https://github.com/random/repo", metadata.Source.String())
+}
+
+func TestMetadataStore_Dependencies(t *testing.T) {
+	store := newMetadataStore()
+	library := newTestMetadata("Library")
+	store.Put(library)
+
+	app := newTestMetadata("App")
+	app.Dependencies = []*core.Dependency{{Id: strings.ToUpper(library.Id.String())}}
+	_, err := store.Put(app)
+	assert.Nil(t, err)
+	assert.Equal(t, library.Id.String(), app.Dependencies[0].Id)
+
+	cyclic := *library
+	cyclic.Dependencies = []*core.Dependency{{Title: "App"}}
+	created, err := store.Put(&cyclic)
+	assert.False(t, created)
+	assert.IsType(t, &dependencies.CycleError{}, err)
+	stored, _ := store.Get(library.Id)
+	assert.Same(t, library, stored)
+
+	unresolved := newTestMetadata("Other")
+	unresolved.Dependencies = []*core.Dependency{{Title: "Unknown"}}
+	_, err = store.Put(unresolved)
+	assert.Equal(t, &dependencies.UnresolvedError{Index: 0, Dependency: &core.Dependency{Title: "Unknown"}}, err)
+	assert.Len(t, store.Database.Metadatas, 2)
+}
+
+// A dependency by title resolves to another metadata once the newest one is deleted, renamed or given an older version
+func TestMetadataStore_Dependencies_Dependents(t *testing.T) {
+	store := newMetadataStore()
+	newest := newTestMetadata("Library")
+	newest.Version = "2.0.0"
+	_, err := store.Put(newest)
+	assert.Nil(t, err)
+	app := newTestMetadata("App")
+	app.Dependencies = []*core.Dependency{{Title: "Library"}}
+	_, err = store.Put(app)
+	assert.Nil(t, err)
+	older := newTestMetadata("Library")
+	older.Version = "1.0.0"
+	older.Dependencies = []*core.Dependency{{Title: "App"}}
+	_, err = store.Put(older)
+	assert.Nil(t, err)
+
+	deleted, err := store.Delete(newest.Id)
+	assert.False(t, deleted)
+	assert.IsType(t, &dependencies.CycleError{}, err)
+	assert.Len(t, store.Database.Metadatas, 3)
+
+	downgraded := *newest
+	downgraded.Version = "0.1.0"
+	_, err = store.Put(&downgraded)
+	assert.IsType(t, &dependencies.CycleError{}, err)
+	renamed := *newest
+	renamed.Title = "Renamed"
+	_, err = store.Put(&renamed)
+	assert.IsType(t, &dependencies.CycleError{}, err)
+	stored, _ := store.Get(newest.Id)
+	assert.Same(t, newest, stored)
+
+	// Without the dependency of the older version nothing leads back
+	independent := *older
+	independent.Dependencies = nil
+	_, err = store.Put(&independent)
+	assert.Nil(t, err)
+	deleted, err = store.Delete(newest.Id)
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+}
+
+func TestMetadataStore_SetLinks(t *testing.T) {
+	store := newMetadataStore()
+	searcher := store.Indexer.(*search.Searcher)
+	metadata := newTestMetadata("App")
+	store.Put(metadata)
+	events, unsubscribe := store.Events.Subscribe()
+	defer unsubscribe()
+
+	links := &core.LinkStatus{Broken: true,
+		Website: &core.LinkCheck{URL: metadata.Website.String(), Status: 200},
+		Source:  &core.LinkCheck{URL: metadata.Source.String(), Status: 404, Broken: true}}
+	assert.True(t, store.SetLinks(metadata.Id, links))
+	assert.False(t, store.SetLinks(uuid.New(), links))
+	assert.Nil(t, metadata.Links, "readers of the previous metadata are not affected")
+	got, _ := store.Get(metadata.Id)
+	assert.Equal(t, links, got.Links)
+	assert.True(t, searcher.Index["links.broken"]["true"][metadata.Id])
+	assert.Equal(t, watch.Modified, (<-events).Type)
+
+	// Links sent by clients are ignored, the status is kept while the links do not change
+	updated := newTestMetadata("App")
+	updated.Id = metadata.Id
+	updated.Links = &core.LinkStatus{}
+	store.Put(updated)
+	assert.Equal(t, links, updated.Links)
+
+	moved := newTestMetadata("App")
+	moved.Id = metadata.Id
+	moved.Source.URL, _ = url.Parse("https://github.com/random/moved")
+	store.Put(moved)
+	assert.Nil(t, moved.Links)
+	assert.Empty(t, searcher.Index["links.broken"]["true"])
+
+	// A status checked before the source moved is not recorded
+	assert.False(t, store.SetLinks(metadata.Id, links))
+	got, _ = store.Get(metadata.Id)
+	assert.Nil(t, got.Links)
+}
+
+func TestCheckedLinks(t *testing.T) {
+	metadata := newTestMetadata("App")
+	links := &core.LinkStatus{
+		Website: &core.LinkCheck{URL: "https://website.com"},
+		Source:  &core.LinkCheck{URL: "https://github.com/random/repo"},
+	}
+	assert.True(t, checkedLinks(metadata, links))
+
+	// The source changed while it was checked
+	metadata.Source.URL, _ = url.Parse("https://github.com/random/moved")
+	assert.False(t, checkedLinks(metadata, links))
+
+	// A link removed while it was checked
+	metadata.Source.URL = nil
+	assert.False(t, checkedLinks(metadata, links))
+	links.Source = nil
+	assert.True(t, checkedLinks(metadata, links))
+}
+
+func TestMetadataStore_DefaultsApplication(t *testing.T) {
+	store := newMetadataStore()
+
+	metadata := newTestMetadata("Valid App 1")
+	store.Put(metadata)
+	assert.Equal(t, "valid-app-1", metadata.Application)
+
+	// The application is kept when a replacement has none
+	renamed := newTestMetadata("Renamed")
+	renamed.Id = metadata.Id
+	renamed.Application = "custom"
+	store.Put(renamed)
+	replaced := newTestMetadata("Renamed again")
+	replaced.Id = metadata.Id
+	store.Put(replaced)
+	assert.Equal(t, "custom", replaced.Application)
+}
+
+func TestMetadataStore_Lifecycle(t *testing.T) {
+	store := newMetadataStore()
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.Now = func() time.Time { return now }
+
+	replacement := newTestMetadata("App 2")
+	store.Put(replacement)
+	metadata := newTestMetadata("App")
+	store.Put(metadata)
+	assert.Equal(t, lifecycle.Published, metadata.Lifecycle)
+
+	deprecated := newTestMetadata("App")
+	deprecated.Id = metadata.Id
+	deprecated.Lifecycle = lifecycle.Deprecated
+	deprecated.Deprecation = &core.Deprecation{Reason: "Replaced by App 2", Replacement: strings.ToUpper(replacement.Id.String())}
+	_, err := store.Put(deprecated)
+	assert.Nil(t, err)
+	assert.Equal(t, replacement.Id.String(), deprecated.Deprecation.Replacement)
+	assert.Equal(t, now, deprecated.Deprecation.DeprecatedAt)
+
+	draft := newTestMetadata("App")
+	draft.Id = metadata.Id
+	draft.Lifecycle = lifecycle.Draft
+	created, err := store.Put(draft)
+	assert.False(t, created)
+	assert.Equal(t, &lifecycle.TransitionError{From: lifecycle.Deprecated, To: lifecycle.Draft}, err)
+	stored, _ := store.Get(metadata.Id)
+	assert.Same(t, deprecated, stored)
+}
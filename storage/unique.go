@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"APIServerExercise/core"
+	"APIServerExercise/search"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// Saving a metadata would give it the same values as an existing metadata for the fields of a unique constraint
+type ConflictError struct {
+	// Fields of the unique constraint, IE: title and version
+	Fields []string
+	// The metadata already having the values
+	Existing *core.Metadata
+}
+
+func (e *ConflictError) Error() string {
+	values := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		values[i] = fmt.Sprintf("%s %q", field, strings.Join(search.FieldValues(e.Existing, field), ", "))
+	}
+	return fmt.Sprintf("%s must be unique, metadata %s already has %s",
+		strings.Join(e.Fields, " and "), e.Existing.Id, strings.Join(values, " and "))
+}
+
+// Secondary index of the values of the fields of every unique constraint
+// Values are compared case insensitively, after the normalization of the metadata, IE: Valid App 1 and valid app 1
+// are the same title. A metadata missing a value of a constraint is not constrained by it.
+// The index is changed by the store under its lock, the check and the save of a metadata are then atomic.
+type UniqueIndex struct {
+	// Fields of each constraint, IE: [[title version] [source]]
+	Constraints [][]string
+
+	// Values of a constraint -> id of the metadata having them
+	owners map[uniqueKey]uuid.UUID
+	// Id -> keys of the metadata
+	keys map[uuid.UUID][]uniqueKey
+}
+
+// The values of the fields of a constraint
+type uniqueKey struct {
+	// Index of the constraint
+	constraint int
+	values     string
+}
+
+func NewUniqueIndex(constraints [][]string) *UniqueIndex {
+	return &UniqueIndex{Constraints: constraints, owners: map[uniqueKey]uuid.UUID{}, keys: map[uuid.UUID][]uniqueKey{}}
+}
+
+// Checks that every field of every constraint can be constrained, a metadata field with a single value
+func ValidateConstraints(constraints [][]string) error {
+	fields := search.SingleValuedFields()
+	for _, constraint := range constraints {
+		if len(constraint) == 0 {
+			return fmt.Errorf("a unique constraint must have at least one field")
+		}
+		for _, field := range constraint {
+			found := false
+			for _, candidate := range fields {
+				found = found || candidate == field
+			}
+			if !found {
+				return fmt.Errorf("unknown field %q in unique constraint, must be one of %s", field, strings.Join(fields, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the key of the values of each constraint of the metadata
+func (u *UniqueIndex) keysOf(metadata *core.Metadata) []uniqueKey {
+	var keys []uniqueKey
+	for i, constraint := range u.Constraints {
+		values := make([]string, 0, len(constraint))
+		for _, field := range constraint {
+			value := strings.ToLower(strings.Join(search.FieldValues(metadata, field), ""))
+			if value == "" {
+				break
+			}
+			values = append(values, value)
+		}
+		if len(values) == len(constraint) {
+			keys = append(keys, uniqueKey{constraint: i, values: strings.Join(values, "\x00")})
+		}
+	}
+	return keys
+}
+
+// Returns the fields of the first constraint another metadata has the same values for, and the id of that metadata
+func (u *UniqueIndex) conflict(metadata *core.Metadata) ([]string, uuid.UUID, bool) {
+	for _, key := range u.keysOf(metadata) {
+		if owner, ok := u.owners[key]; ok && owner != metadata.Id {
+			return u.Constraints[key.constraint], owner, true
+		}
+	}
+	return nil, uuid.UUID{}, false
+}
+
+func (u *UniqueIndex) add(metadata *core.Metadata) {
+	u.remove(metadata.Id)
+	keys := u.keysOf(metadata)
+	for _, key := range keys {
+		u.owners[key] = metadata.Id
+	}
+	u.keys[metadata.Id] = keys
+}
+
+func (u *UniqueIndex) remove(id uuid.UUID) {
+	for _, key := range u.keys[id] {
+		if u.owners[key] == id {
+			delete(u.owners, key)
+		}
+	}
+	delete(u.keys, id)
+}
+
+// Indexes every metadata of the database, IE: once it is loaded
+// Returns the conflicts of the metadata having the same values as a metadata before them in the default ordering,
+// keyed by their id. They are not indexed and can only be saved once their values are unique.
+// The index must not be in use by a store, IE: call it from MetadataStore.Write.
+func (u *UniqueIndex) Rebuild(database *core.Database) map[uuid.UUID]*ConflictError {
+	u.owners = map[uniqueKey]uuid.UUID{}
+	u.keys = map[uuid.UUID][]uniqueKey{}
+	conflicts := map[uuid.UUID]*ConflictError{}
+	for _, id := range database.Ordering {
+		metadata, ok := database.Metadatas[id]
+		if !ok {
+			continue
+		}
+		if fields, owner, ok := u.conflict(metadata); ok {
+			conflicts[id] = &ConflictError{Fields: fields, Existing: database.Metadatas[owner]}
+			continue
+		}
+		u.add(metadata)
+	}
+	return conflicts
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func newUniqueStore() *MetadataStore {
+	store := newMetadataStore()
+	store.Unique = NewUniqueIndex([][]string{{"title", "version"}, {"source"}})
+	return store
+}
+
+func TestValidateConstraints(t *testing.T) {
+	assert.Nil(t, ValidateConstraints([][]string{{"title", "version"}, {"source"}}))
+	assert.Nil(t, ValidateConstraints(nil))
+
+	assert.Equal(t, "a unique constraint must have at least one field", ValidateConstraints([][]string{{}}).Error())
+	err := ValidateConstraints([][]string{{"title", "maintainers.email"}})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `unknown field "maintainers.email" in unique constraint, must be one of id, title, version`)
+	}
+}
+
+func TestMetadataStore_Put_Conflict(t *testing.T) {
+	store := newUniqueStore()
+	existing := newTestMetadata("Valid App 1")
+	_, err := store.Put(existing)
+	assert.Nil(t, err)
+
+	duplicate := newTestMetadata("valid app 1")
+	duplicate.Source.URL, _ = url.Parse("https://github.com/random/other")
+	created, err := store.Put(duplicate)
+	assert.False(t, created)
+	assert.Equal(t, &ConflictError{Fields: []string{"title", "version"}, Existing: existing}, err)
+	assert.Equal(t, fmt.Sprintf(`title and version must be unique, metadata %s already has title "Valid App 1" and version "0.0.1"`,
+		existing.Id), err.Error())
+	assert.Len(t, store.Database.Metadatas, 1)
+	assert.Len(t, store.Database.Ordering, 1)
+
+	duplicate.Version = "0.0.2"
+	duplicate.Source.URL, _ = url.Parse("https://GitHub.com/random/repo/")
+	_, err = store.Put(duplicate)
+	assert.Equal(t, `source must be unique, metadata `+existing.Id.String()+` already has source "https://github.com/random/repo"`, err.Error())
+
+	// Saving the metadata again or changing its values frees them
+	_, err = store.Put(existing)
+	assert.Nil(t, err)
+	moved := newTestMetadata("Valid App 1")
+	moved.Id = existing.Id
+	moved.Source.URL, _ = url.Parse("https://github.com/random/moved")
+	_, err = store.Put(moved)
+	assert.Nil(t, err)
+	_, err = store.Put(duplicate)
+	assert.Nil(t, err)
+
+	deleted, err := store.Delete(moved.Id)
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+	recreated := newTestMetadata("Valid App 1")
+	recreated.Source.URL, _ = url.Parse("https://github.com/random/recreated")
+	_, err = store.Put(recreated)
+	assert.Nil(t, err)
+}
+
+func TestMetadataStore_Put_ConcurrentConflict(t *testing.T) {
+	store := newUniqueStore()
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	conflicts := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Put(newTestMetadata("Valid App 1")); err != nil {
+				mutex.Lock()
+				conflicts++
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 9, conflicts)
+	assert.Len(t, store.Database.Metadatas, 1)
+}
+
+func TestUniqueIndex_Rebuild(t *testing.T) {
+	store := newMetadataStore()
+	first := newTestMetadata("Valid App 1")
+	duplicate := newTestMetadata("Valid App 1")
+	other := newTestMetadata("Valid App 2")
+	other.Source.URL, _ = url.Parse("https://github.com/random/other")
+	store.Put(first)
+	store.Put(duplicate)
+	store.Put(other)
+
+	index := NewUniqueIndex([][]string{{"title", "version"}})
+	conflicts := index.Rebuild(store.Database)
+	assert.Equal(t, map[uuid.UUID]*ConflictError{
+		duplicate.Id: {Fields: []string{"title", "version"}, Existing: first},
+	}, conflicts)
+
+	store.Unique = index
+	_, err := store.Put(newTestMetadata("Valid App 2"))
+	assert.IsType(t, &ConflictError{}, err)
+}
@@ -0,0 +1,134 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"net/url"
+	"os"
+	"sync"
+)
+
+const (
+	NoneExporter   = "none"
+	StdoutExporter = "stdout"
+	FileExporter   = "file"
+	OtlpExporter   = "otlp"
+)
+
+// Provider set by Setup, flushed by Shutdown
+var provider *sdktrace.TracerProvider
+
+// Makes the spans exported by the exporter, nil exports nothing
+// Spans have trace ids without an exporter too, so responses always identify their trace.
+func Setup(exporter sdktrace.SpanExporter, serviceName string) {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	}
+	if exporter != nil {
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+	setProvider(sdktrace.NewTracerProvider(options...))
+}
+
+func setProvider(tracerProvider *sdktrace.TracerProvider) {
+	provider = tracerProvider
+	otel.SetTracerProvider(tracerProvider)
+}
+
+// Flushes the spans not exported yet and stops the exporter
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+// Creates the exporter selected by name, returns nil for none
+// The file exporter appends spans to the file at path in the OTLP JSON format, one export request per line, and the
+// stdout exporter writes them to stdout, one JSON object per span. The otlp exporter sends spans over OTLP/HTTP to the endpoint, IE: http://localhost:4318, or
+// to OTEL_EXPORTER_OTLP_ENDPOINT when the endpoint is empty.
+func NewExporter(name string, path string, endpoint string) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "", NoneExporter:
+		return nil, nil
+	case StdoutExporter:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case FileExporter:
+		if path == "" {
+			return nil, fmt.Errorf("the %s exporter requires a path", FileExporter)
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %v", err)
+		}
+		return otlptrace.New(context.Background(), &fileClient{file: file})
+	case OtlpExporter:
+		options, err := otlpOptions(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return otlptracehttp.New(context.Background(), options...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %s", name)
+	}
+}
+
+// OTLP client appending every batch of spans to a file as a JSON ExportTraceServiceRequest, one per line
+// The format of the file exporter of the OpenTelemetry Collector, read back by its otlpjsonfile receiver.
+type fileClient struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+var _ otlptrace.Client = &fileClient{}
+
+func (c *fileClient) Start(ctx context.Context) error {
+	return nil
+}
+
+// Closes the file once the spans are written
+func (c *fileClient) Stop(ctx context.Context) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.file.Close()
+}
+
+func (c *fileClient) UploadTraces(ctx context.Context, spans []*tracepb.ResourceSpans) error {
+	line, err := protojson.Marshal(&coltracepb.ExportTraceServiceRequest{ResourceSpans: spans})
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, err = c.file.Write(append(line, '\n'))
+	return err
+}
+
+// Options sending to the endpoint, none when it is empty so the OTEL_EXPORTER_OTLP environment variables apply
+func otlpOptions(endpoint string) ([]otlptracehttp.Option, error) {
+	if endpoint == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("the trace endpoint must be an http or https URL, IE: http://localhost:4318")
+	}
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	if u.Scheme == "http" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	if u.Path != "" && u.Path != "/" {
+		options = append(options, otlptracehttp.WithURLPath(u.Path))
+	}
+	return options, nil
+}
@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"APIServerExercise/util"
+	"context"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// Codes meaning the server failed, like the 5xx HTTP status codes, other codes are the caller's fault
+var serverErrorCodes = map[grpccodes.Code]bool{
+	grpccodes.Unknown:          true,
+	grpccodes.DeadlineExceeded: true,
+	grpccodes.Unimplemented:    true,
+	grpccodes.Internal:         true,
+	grpccodes.Unavailable:      true,
+	grpccodes.DataLoss:         true,
+}
+
+// Creates a server span for every unary call, the gRPC counterpart of Middleware
+// The trace is continued when the call has a valid traceparent metadata.
+func UnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, span := startCall(ctx, info.FullMethod)
+	defer span.End()
+	response, err := handler(ctx, req)
+	endCall(span, err)
+	return response, err
+}
+
+// The span of a stream lasts until the stream ends
+func StreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	ctx, span := startCall(ss.Context(), info.FullMethod)
+	defer span.End()
+	err := handler(srv, &util.ServerStream{ServerStream: ss, Ctx: ctx})
+	endCall(span, err)
+	return err
+}
+
+// Starts the span of the call, named after the method, IE: metadata.v1.MetadataService/GetMetadata
+func startCall(ctx context.Context, method string) (context.Context, *Span) {
+	md, _ := grpcmetadata.FromIncomingContext(ctx)
+	ctx = propagator.Extract(ctx, metadataCarrier(md))
+	ctx, span := StartServer(ctx, strings.TrimPrefix(method, "/"))
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", method)
+
+	// Let the caller know which trace the call ended up in
+	header := grpcmetadata.MD{}
+	propagator.Inject(ctx, metadataCarrier(header))
+	grpc.SetHeader(ctx, header)
+	return ctx, span
+}
+
+func endCall(span *Span, err error) {
+	code := status.Code(err)
+	span.SetAttribute("rpc.grpc.status_code", int(code))
+	if serverErrorCodes[code] {
+		span.SetError(err)
+	}
+}
+
+// Reads and writes the traceparent in gRPC metadata
+type metadataCarrier grpcmetadata.MD
+
+var _ propagation.TextMapCarrier = metadataCarrier{}
+
+func (c metadataCarrier) Get(key string) string {
+	if values := grpcmetadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	grpcmetadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestUnaryInterceptor(t *testing.T) {
+	recorder := setupTest()
+
+	ctx := grpcmetadata.NewIncomingContext(context.Background(),
+		grpcmetadata.Pairs(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+	_, err := UnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/metadata.v1.MetadataService/GetMetadata"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			_, span := Start(ctx, "inner")
+			span.End()
+			return nil, status.Error(grpccodes.Internal, "failed")
+		})
+	assert.Equal(t, grpccodes.Internal, status.Code(err))
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	inner, server := spans[0], spans[1]
+	assert.Equal(t, "metadata.v1.MetadataService/GetMetadata", server.Name())
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, server.SpanContext().SpanID(), inner.Parent().SpanID())
+	assert.Equal(t, codes.Error, server.Status().Code)
+	assert.Contains(t, server.Attributes(), attribute.Int("rpc.grpc.status_code", int(grpccodes.Internal)))
+}
+
+// Errors caused by the caller do not mark the span as failed
+func TestUnaryInterceptor_WithClientError(t *testing.T) {
+	recorder := setupTest()
+
+	UnaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/metadata.v1.MetadataService/GetMetadata"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(grpccodes.NotFound, "not found")
+		})
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent().IsValid())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"APIServerExercise/util"
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+const (
+	TraceparentHeader = "traceparent"
+	// Name of the instrumentation scope reported with every span
+	scopeName = "APIServerExercise"
+)
+
+// Continues and propagates traces with the W3C traceparent header
+var propagator = propagation.TraceContext{}
+
+// A span of the global tracer provider
+type Span struct {
+	trace.Span
+}
+
+// Starts a span as a child of the span in ctx
+// Returns a context containing the new span, End must be called on the span.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	ctx, span := otel.Tracer(scopeName).Start(ctx, name)
+	return ctx, &Span{Span: span}
+}
+
+// Starts a server span continuing the trace of the caller, if any
+func StartServer(ctx context.Context, name string) (context.Context, *Span) {
+	ctx, span := otel.Tracer(scopeName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer))
+	return ctx, &Span{Span: span}
+}
+
+// Returns the current span, or a span that records nothing if there is none
+func SpanFromContext(ctx context.Context) *Span {
+	return &Span{Span: trace.SpanFromContext(ctx)}
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.SetAttributes(toAttribute(key, value))
+}
+
+// Marks the span as failed
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+	s.RecordError(err)
+	s.SetStatus(codes.Error, err.Error())
+}
+
+func toAttribute(key string, value interface{}) attribute.KeyValue {
+	switch typed := value.(type) {
+	case string:
+		return attribute.String(key, typed)
+	case bool:
+		return attribute.Bool(key, typed)
+	case int:
+		return attribute.Int(key, typed)
+	case int64:
+		return attribute.Int64(key, typed)
+	case float64:
+		return attribute.Float64(key, typed)
+	default:
+		return attribute.String(key, fmt.Sprintf("%v", typed))
+	}
+}
+
+// Creates a server span for every request routed by mux
+// The trace is continued when the request has a valid W3C traceparent header.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := Extract(req.Context(), req.Header)
+		route := util.RouteTemplate(req)
+		ctx, span := StartServer(ctx, fmt.Sprintf("%s %s", req.Method, route))
+		defer span.End()
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.target", req.URL.RequestURI())
+
+		// Let the caller know which trace the request ended up in
+		Inject(ctx, w.Header())
+
+		sw := util.NewStatusWriter(w)
+		next.ServeHTTP(sw, req.WithContext(ctx))
+
+		span.SetAttribute("http.status_code", sw.Status)
+		if sw.Status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.Status))
+		}
+	})
+}
+
+// Returns the context of the trace of the traceparent header, ctx when it has none
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Adds the traceparent header for the span in ctx, used when calling other services
+func Inject(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,194 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func setupTest() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	setProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func TestStart(t *testing.T) {
+	recorder := setupTest()
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	child.SetAttribute("key", "value")
+	child.SetAttribute("count", 3)
+	child.SetError(fmt.Errorf("validation failed"))
+	child.End()
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].Name())
+	assert.Equal(t, parent.SpanContext().TraceID(), spans[0].SpanContext().TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.False(t, spans[1].Parent().IsValid())
+	assert.Equal(t, []attribute.KeyValue{attribute.String("key", "value"), attribute.Int("count", 3)}, spans[0].Attributes())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "validation failed", spans[0].Status().Description)
+}
+
+func TestSpanFromContext_WithoutSpan(t *testing.T) {
+	span := SpanFromContext(context.Background())
+	span.SetAttribute("key", "value")
+	span.End()
+	assert.False(t, span.SpanContext().IsValid())
+}
+
+// region Middleware
+
+func TestMiddleware(t *testing.T) {
+	recorder := setupTest()
+
+	r := mux.NewRouter()
+	r.Use(Middleware)
+	r.HandleFunc("/metadata/{id}", func(w http.ResponseWriter, req *http.Request) {
+		_, span := Start(req.Context(), "inner")
+		span.End()
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/metadata/1", nil)
+	request.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	responseRecorder := httptest.NewRecorder()
+	r.ServeHTTP(responseRecorder, request)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	inner, server := spans[0], spans[1]
+	assert.Equal(t, "GET /metadata/{id}", server.Name())
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, server.SpanContext().SpanID(), inner.Parent().SpanID())
+	assert.Equal(t, codes.Error, server.Status().Code)
+	assert.Equal(t, "Internal Server Error", server.Status().Description)
+	assert.Contains(t, server.Attributes(), attribute.Int("http.status_code", http.StatusInternalServerError))
+	assert.Equal(t,
+		fmt.Sprintf("00-4bf92f3577b34da6a3ce929d0e0e4736-%s-01", server.SpanContext().SpanID()),
+		responseRecorder.Header().Get(TraceparentHeader))
+}
+
+func TestMiddleware_WithoutTraceparent(t *testing.T) {
+	recorder := setupTest()
+
+	r := mux.NewRouter()
+	r.Use(Middleware)
+	r.HandleFunc("/metadata", func(w http.ResponseWriter, req *http.Request) {})
+
+	responseRecorder := httptest.NewRecorder()
+	r.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/metadata", nil))
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent().IsValid())
+	assert.Contains(t, responseRecorder.Header().Get(TraceparentHeader), spans[0].SpanContext().TraceID().String())
+}
+
+func TestMiddleware_NotSampled(t *testing.T) {
+	recorder := setupTest()
+
+	r := mux.NewRouter()
+	r.Use(Middleware)
+	r.HandleFunc("/metadata", func(w http.ResponseWriter, req *http.Request) {})
+
+	request := httptest.NewRequest(http.MethodGet, "/metadata", nil)
+	request.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	r.ServeHTTP(httptest.NewRecorder(), request)
+
+	assert.Empty(t, recorder.Ended())
+}
+
+// endregion
+
+// region Exporters
+
+func TestNewExporter_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	exporter, err := NewExporter(FileExporter, path, "")
+	assert.Nil(t, err)
+	Setup(exporter, "test-service")
+
+	_, span := Start(context.Background(), "span")
+	span.End()
+	assert.Nil(t, Shutdown(context.Background()))
+
+	// One OTLP JSON export request per line, as read by the otlpjsonfile receiver of the collector
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	assert.Len(t, lines, 1)
+	var request coltracepb.ExportTraceServiceRequest
+	assert.Nil(t, protojson.Unmarshal([]byte(lines[0]), &request))
+	assert.Len(t, request.ResourceSpans, 1)
+	resourceSpans := request.ResourceSpans[0]
+	attributes := map[string]string{}
+	for _, attribute := range resourceSpans.Resource.Attributes {
+		attributes[attribute.Key] = attribute.Value.GetStringValue()
+	}
+	assert.Equal(t, "test-service", attributes["service.name"])
+	assert.Equal(t, "span", resourceSpans.ScopeSpans[0].Spans[0].Name)
+	assert.Equal(t, span.SpanContext().TraceID().String(), hex.EncodeToString(resourceSpans.ScopeSpans[0].Spans[0].TraceId))
+}
+
+func TestNewExporter_Otlp(t *testing.T) {
+	var mutex sync.Mutex
+	var paths []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		paths = append(paths, req.URL.Path)
+	}))
+	defer collector.Close()
+
+	exporter, err := NewExporter(OtlpExporter, "", collector.URL)
+	assert.Nil(t, err)
+	Setup(exporter, "test-service")
+
+	_, span := Start(context.Background(), "span")
+	span.End()
+	assert.Nil(t, Shutdown(context.Background()))
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []string{"/v1/traces"}, paths)
+}
+
+func TestNewExporter_Invalid(t *testing.T) {
+	exporter, err := NewExporter(NoneExporter, "", "")
+	assert.Nil(t, err)
+	assert.Nil(t, exporter)
+
+	_, err = NewExporter(FileExporter, "", "")
+	assert.EqualError(t, err, "the file exporter requires a path")
+
+	_, err = NewExporter(OtlpExporter, "", "localhost:4318")
+	assert.True(t, strings.HasPrefix(err.Error(), "the trace endpoint must be an http or https URL"))
+
+	_, err = NewExporter("zipkin", "", "")
+	assert.EqualError(t, err, "unknown trace exporter zipkin")
+}
+
+// endregion
@@ -0,0 +1,20 @@
+package util
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// Returns the path template of the route matched by mux, IE: /metadata/{id}
+// Returns an empty string when the request was not routed by mux
+func RouteTemplate(req *http.Request) string {
+	current := mux.CurrentRoute(req)
+	if current == nil {
+		return ""
+	}
+	template, err := current.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return template
+}
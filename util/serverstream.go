@@ -0,0 +1,17 @@
+package util
+
+import (
+	"context"
+	"google.golang.org/grpc"
+)
+
+// grpc.ServerStream does not allow replacing its context
+// Wrapper that returns Ctx so stream interceptors can pass values down like unary interceptors do
+type ServerStream struct {
+	grpc.ServerStream
+	Ctx context.Context
+}
+
+func (s *ServerStream) Context() context.Context {
+	return s.Ctx
+}
@@ -21,5 +21,9 @@ func (j *Yamlurl) UnmarshalYAML(unmarshal func(interface{}) error) error {
 }
 
 func (j Yamlurl) MarshalYAML() (interface{}, error) {
+	// Unset urls are marshalled as an empty string instead of dereferencing nil
+	if j.URL == nil {
+		return "", nil
+	}
 	return j.String(), nil
 }
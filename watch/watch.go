@@ -0,0 +1,69 @@
+package watch
+
+import (
+	"APIServerExercise/core"
+	"sync"
+)
+
+type EventType string
+
+const (
+	Added    EventType = "ADDED"
+	Modified EventType = "MODIFIED"
+	Deleted  EventType = "DELETED"
+)
+
+// Number of events a subscriber can fall behind before it is dropped
+const subscriberBuffer = 64
+
+// A change of a single metadata
+// Metadata must not be modified, it is shared by every subscriber.
+type Event struct {
+	Type     EventType
+	Metadata *core.Metadata
+}
+
+// Sends every published event to every subscriber
+type Broadcaster struct {
+	mutex       sync.Mutex
+	subscribers map[chan Event]bool
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{subscribers: map[chan Event]bool{}}
+}
+
+// Returns a channel receiving every event published from now on and a function to unsubscribe
+// Publishing never blocks, the channel of a subscriber that falls too far behind is closed.
+func (b *Broadcaster) Subscribe() (<-chan Event, func()) {
+	events := make(chan Event, subscriberBuffer)
+	b.mutex.Lock()
+	b.subscribers[events] = true
+	b.mutex.Unlock()
+
+	return events, func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		if b.subscribers[events] {
+			delete(b.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// Sends the event to every subscriber, does nothing on a nil broadcaster
+func (b *Broadcaster) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for events := range b.subscribers {
+		select {
+		case events <- event:
+		default:
+			delete(b.subscribers, events)
+			close(events)
+		}
+	}
+}
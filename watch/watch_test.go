@@ -0,0 +1,48 @@
+package watch
+
+import (
+	"APIServerExercise/core"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBroadcaster(t *testing.T) {
+	b := NewBroadcaster()
+	first, unsubscribeFirst := b.Subscribe()
+	second, unsubscribeSecond := b.Subscribe()
+	defer unsubscribeSecond()
+
+	metadata := &core.Metadata{Title: "App"}
+	b.Publish(Event{Type: Added, Metadata: metadata})
+	assert.Equal(t, Event{Type: Added, Metadata: metadata}, <-first)
+	assert.Equal(t, Event{Type: Added, Metadata: metadata}, <-second)
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	_, ok := <-first
+	assert.False(t, ok)
+
+	b.Publish(Event{Type: Deleted, Metadata: metadata})
+	assert.Equal(t, Deleted, (<-second).Type)
+}
+
+func TestBroadcaster_SlowSubscriber(t *testing.T) {
+	b := NewBroadcaster()
+	events, unsubscribe := b.Subscribe()
+	defer unsubscribe()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		b.Publish(Event{Type: Modified})
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received)
+}
+
+func TestBroadcaster_Nil(t *testing.T) {
+	var b *Broadcaster
+	b.Publish(Event{Type: Added})
+}